@@ -0,0 +1,50 @@
+// Command membership checks that folder membership is consistent: that
+// every folder an upload is filed under exists, holds items by membership
+// and is one the upload's owner has a role on, directly or through a
+// folder above it, and that nothing is recorded only in
+// the post_ids folders used to carry. Run with -repair to fix what it finds.
+//
+//	go run ./cmd/membership [-repair] [-user <id>]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	DB "lyked-backend/internal/database/mongodb"
+	"lyked-backend/internal/library"
+	"os"
+	"time"
+)
+
+func main() {
+	repair := flag.Bool("repair", false, "fix the problems found")
+	userID := flag.String("user", "", "check only this user's library")
+	flag.Parse()
+
+	if _, err := DB.ConnectMongo("lyked-app"); err != nil {
+		log.Fatal("Failed to connect to MongoDB:", err)
+	}
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		log.Fatal(err)
+	}
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	report, err := library.CheckMembership(ctx, uploads, folders, *userID, *repair)
+	if err != nil {
+		log.Fatal("Membership check failed:", err)
+	}
+	out := json.NewEncoder(os.Stdout)
+	out.SetIndent("", "  ")
+	out.Encode(report)
+	if !report.Consistent() && !*repair {
+		os.Exit(1)
+	}
+}
@@ -1,9 +1,16 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"lyked-backend/internal/archive"
 	DB "lyked-backend/internal/database/mongodb"
 	PDB "lyked-backend/internal/database/postgresql"
+	"lyked-backend/internal/library"
+	"lyked-backend/internal/linkcheck"
+	"lyked-backend/internal/search"
+	"lyked-backend/internal/trash"
+	"strconv"
 
 	"lyked-backend/internal/utils"
 	"lyked-backend/routes"
@@ -21,8 +28,8 @@ func InitServer() error {
 	r.Use(gin.Logger())
 	r.Use(cors.New(cors.Config{
 		AllowAllOrigins:  true, // Allow all origins for development
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Share-Password"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: false, // Must be false when AllowAllOrigins is true
 		MaxAge:           12 * time.Hour,
@@ -41,6 +48,45 @@ func InitServer() error {
 	if err := routes.InitProtectedUserRoutes(r); err != nil {
 		return fmt.Errorf("failed to initialize protected user routes: %w", err)
 	}
+	if err := routes.InitProtectedSearchRoutes(r); err != nil {
+		return fmt.Errorf("failed to initialize protected search routes: %w", err)
+	}
+	if err := routes.InitProtectedFolderRoutes(r); err != nil {
+		return fmt.Errorf("failed to initialize protected folder routes: %w", err)
+	}
+	if err := routes.InitProtectedImportRoutes(r); err != nil {
+		return fmt.Errorf("failed to initialize protected import routes: %w", err)
+	}
+	if err := routes.InitProtectedTrashRoutes(r); err != nil {
+		return fmt.Errorf("failed to initialize protected trash routes: %w", err)
+	}
+	if err := routes.InitProtectedLinkRoutes(r); err != nil {
+		return fmt.Errorf("failed to initialize protected link routes: %w", err)
+	}
+	if err := routes.InitProtectedArchiveRoutes(r); err != nil {
+		return fmt.Errorf("failed to initialize protected archive routes: %w", err)
+	}
+	if err := routes.InitProtectedSnapshotRoutes(r); err != nil {
+		return fmt.Errorf("failed to initialize protected snapshot routes: %w", err)
+	}
+	if err := routes.InitProtectedResurfaceRoutes(r); err != nil {
+		return fmt.Errorf("failed to initialize protected resurface routes: %w", err)
+	}
+	if err := routes.InitProtectedReviewRoutes(r); err != nil {
+		return fmt.Errorf("failed to initialize protected review routes: %w", err)
+	}
+	if err := routes.InitProtectedTagRoutes(r); err != nil {
+		return fmt.Errorf("failed to initialize protected tag routes: %w", err)
+	}
+	if err := routes.InitProtectedRuleRoutes(r); err != nil {
+		return fmt.Errorf("failed to initialize protected rule routes: %w", err)
+	}
+	if err := routes.InitProtectedShareLinkRoutes(r); err != nil {
+		return fmt.Errorf("failed to initialize protected share link routes: %w", err)
+	}
+	if err := routes.InitPublicShareRoutes(r); err != nil {
+		return fmt.Errorf("failed to initialize public share routes: %w", err)
+	}
 	// Connect to MongoDB
 	if _, err := DB.ConnectMongo("lyked-app"); err != nil {
 		return fmt.Errorf("failed to connect to MongoDB: %w", err)
@@ -52,6 +98,39 @@ func InitServer() error {
 	if _, err := DB.GetCollection("uploads"); err != nil {
 		return fmt.Errorf("failed to get 'uploads' collection: %w", err)
 	}
+	if err := DB.EnsureIndexes(); err != nil {
+		return fmt.Errorf("failed to ensure MongoDB indexes: %w", err)
+	}
+	if err := migrateUploadIDs(); err != nil {
+		return err
+	}
+	if err := migrateSortFields(); err != nil {
+		return err
+	}
+	if err := migrateWatchStatus(); err != nil {
+		return err
+	}
+	if err := migrateTags(); err != nil {
+		return err
+	}
+	if err := search.Init(context.Background(), utils.GetEnv("SEARCH_BACKEND", "memory")); err != nil {
+		return fmt.Errorf("failed to initialize search: %w", err)
+	}
+	quotaMB, _ := strconv.ParseInt(utils.GetEnv("ARCHIVE_QUOTA_MB", "2048"), 10, 64)
+	if err := archive.Init(utils.GetEnv("ARCHIVE_DIR", "data/archive"), quotaMB<<20); err != nil {
+		return fmt.Errorf("failed to initialize archive storage: %w", err)
+	}
+	if err := recoverArchives(); err != nil {
+		return err
+	}
+	retention := trash.DefaultRetention
+	if days, err := strconv.Atoi(utils.GetEnv("TRASH_RETENTION_DAYS", "")); err == nil && days > 0 {
+		retention = time.Duration(days) * 24 * time.Hour
+	}
+	trash.StartPurger(retention, time.Hour)
+	if utils.GetEnv("LINK_CHECK_ENABLED", "true") == "true" {
+		linkcheck.Start(time.Hour, linkcheck.DefaultRecheckAfter)
+	}
 	_, err := PDB.ConnectPostgres()
 	if err != nil {
 		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
@@ -72,3 +151,75 @@ func InitServer() error {
 	}
 	return nil
 }
+
+func migrateWatchStatus() error {
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return library.MigrateWatchStatus(ctx, uploads)
+}
+
+func migrateSortFields() error {
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	migrated, err := library.MigrateSortFields(ctx, uploads)
+	if err != nil {
+		return err
+	}
+	if migrated > 0 {
+		fmt.Printf("Filled in saved_at and platform on %d uploads\n", migrated)
+	}
+	return nil
+}
+
+func migrateUploadIDs() error {
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	migrated, err := library.MigrateUploadIDs(ctx, uploads, "")
+	if err != nil {
+		return fmt.Errorf("failed to migrate upload ids: %w", err)
+	}
+	if migrated > 0 {
+		fmt.Printf("Migrated %d upload ids to ObjectIDs\n", migrated)
+	}
+	return nil
+}
+
+func migrateTags() error {
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	migrated, err := library.MigrateTags(ctx, uploads)
+	if err != nil {
+		return err
+	}
+	if migrated > 0 {
+		fmt.Printf("Normalized the tags of %d uploads\n", migrated)
+	}
+	return nil
+}
+
+// recoverArchives fails the archive jobs a restart cut short, which would
+// otherwise stay queued and hold on to quota.
+func recoverArchives() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := archive.Recover(ctx); err != nil {
+		return fmt.Errorf("failed to recover archive jobs: %w", err)
+	}
+	return nil
+}
@@ -11,7 +11,7 @@ require (
 	gorm.io/gorm v1.30.1
 )
 
-require github.com/golang-jwt/jwt/v5 v5.3.0 // indirect
+require github.com/golang-jwt/jwt/v5 v5.3.0
 
 require (
 	// github.com/golang-jwt/jwt/v5 v5.3.0 // indirect
@@ -49,10 +49,9 @@ require (
 	github.com/xdg-go/scram v1.1.2 // indirect
 	github.com/xdg-go/stringprep v1.0.4 // indirect
 	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78 // indirect
-	go.mongodb.org/mongo-driver v1.17.4
 	golang.org/x/arch v0.19.0 // indirect
 	golang.org/x/crypto v0.40.0 // direct
-	golang.org/x/net v0.42.0 // indirect
+	golang.org/x/net v0.42.0
 	golang.org/x/sync v0.16.0 // indirect
 	golang.org/x/sys v0.34.0 // indirect
 	golang.org/x/text v0.27.0 // indirect
@@ -0,0 +1,453 @@
+// Package archive keeps offline copies of saved items: the media itself
+// when it can be found, otherwise the page HTML.
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	DB "lyked-backend/internal/database/mongodb"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/utils"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DefaultMaxSize = 500 << 20 // per archived item
+	maxPageBytes   = 5 << 20   // HTML read while looking for media
+	jobTimeout     = 30 * time.Minute
+	maxConcurrent  = 2
+	userAgent      = "Mozilla/5.0 (compatible; LykedArchiver/1.0)"
+)
+
+var (
+	ErrNotConfigured = errors.New("archiving is not configured")
+	ErrQuotaExceeded = errors.New("archive quota exceeded")
+	errTooLarge      = errors.New("file is larger than the archive limit")
+)
+
+var (
+	// Store holds the archived bytes; nil until Init is called.
+	Store BlobStore
+	// Quota caps each user's archived bytes; 0 means no limit.
+	Quota   int64
+	MaxSize int64 = DefaultMaxSize
+
+	// client refuses private addresses, also for redirects and the media
+	// a page points at.
+	client = &http.Client{Transport: utils.PublicTransport(false), Timeout: jobTimeout}
+	slots  = make(chan struct{}, maxConcurrent)
+)
+
+// Init sets up the disk-backed store used in production.
+func Init(dir string, quota int64) error {
+	store, err := NewFileStore(dir)
+	if err != nil {
+		return err
+	}
+	Store = store
+	Quota = quota
+	return nil
+}
+
+// Start queues an archive of the upload. An archive that is done or in
+// progress is returned as is; a failed one is retried. started reports
+// whether a new job was queued.
+func Start(ctx context.Context, upload model.LykedUploads) (archive *model.Archive, started bool, err error) {
+	if Store == nil {
+		return nil, false, ErrNotConfigured
+	}
+	archives, err := DB.GetCollection("archives")
+	if err != nil {
+		return nil, false, err
+	}
+
+	var existing model.Archive
+	err = archives.FindOne(ctx, bson.M{"user_id": upload.UserID, "upload_id": upload.ID.Hex()}).Decode(&existing)
+	found := err == nil
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, false, fmt.Errorf("failed to fetch archive: %w", err)
+	}
+	if found && existing.Status != model.ArchiveStatusFailed {
+		return &existing, false, nil
+	}
+
+	held, err := reserve(ctx, upload.UserID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	archive = &model.Archive{
+		ID:        bson.NewObjectID(),
+		UserID:    upload.UserID,
+		UploadID:  upload.ID.Hex(),
+		Status:    model.ArchiveStatusQueued,
+		CreatedAt: time.Now().UTC(),
+	}
+	if found {
+		archive.ID = existing.ID
+	}
+	if _, err := archives.ReplaceOne(ctx, bson.M{"_id": archive.ID}, archive, options.Replace().SetUpsert(true)); err != nil {
+		held.settle(ctx, 0, 0)
+		return nil, false, fmt.Errorf("failed to create archive: %w", err)
+	}
+
+	go run(*archive, upload.VideoLink, held)
+	return archive, true, nil
+}
+
+func run(archive model.Archive, link string, held *reservation) {
+	slots <- struct{}{}
+	defer func() { <-slots }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+	defer cancel()
+
+	archive.Status = model.ArchiveStatusRunning
+	save(ctx, &archive)
+
+	if err := archiveLink(ctx, &archive, link, held.limit); err != nil {
+		archive.Status = model.ArchiveStatusFailed
+		archive.Message = err.Error()
+		save(ctx, &archive)
+		held.settle(ctx, 0, 0)
+		return
+	}
+	now := time.Now().UTC()
+	archive.Status = model.ArchiveStatusCompleted
+	archive.CompletedAt = &now
+	save(ctx, &archive)
+	held.settle(ctx, archive.Size, 1)
+}
+
+// archiveLink downloads the link, following it to the media when the page
+// names some, and stores the result.
+func archiveLink(ctx context.Context, archive *model.Archive, link string, limit int64) error {
+	resp, err := get(ctx, link)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	archive.Kind = model.ArchiveKindMedia
+	archive.SourceURL = resp.Request.URL.String()
+	archive.ContentType = resp.Header.Get("Content-Type")
+
+	if !isMedia(archive.ContentType) {
+		page, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+		if err != nil {
+			return fmt.Errorf("failed to read page: %w", err)
+		}
+		if mediaURL := findMediaURL(resp.Request.URL, page); mediaURL != "" {
+			if media, err := get(ctx, mediaURL); err == nil {
+				defer media.Body.Close()
+				if isMedia(media.Header.Get("Content-Type")) {
+					return store(ctx, archive, media.Body, media.Request.URL.String(), media.Header.Get("Content-Type"), limit)
+				}
+			}
+		}
+		archive.Kind = model.ArchiveKindPage
+		return store(ctx, archive, bytes.NewReader(page), archive.SourceURL, archive.ContentType, limit)
+	}
+	return store(ctx, archive, resp.Body, archive.SourceURL, archive.ContentType, limit)
+}
+
+// store spools the content to a temporary file while hashing it, then
+// hands it to the blob store unless an identical blob is already there.
+func store(ctx context.Context, archive *model.Archive, r io.Reader, sourceURL, contentType string, limit int64) error {
+	tmp, err := os.CreateTemp("", "lyked-archive-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hasher := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(r, limit+1))
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	if n > limit {
+		if limit < MaxSize {
+			return ErrQuotaExceeded
+		}
+		return errTooLarge
+	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+
+	exists, err := Store.Exists(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := Store.Put(ctx, hash, tmp); err != nil {
+			return err
+		}
+	}
+
+	archive.Hash = hash
+	archive.Size = n
+	archive.SourceURL = sourceURL
+	archive.ContentType = contentType
+	if archive.ContentType == "" {
+		archive.ContentType = "application/octet-stream"
+	}
+	return nil
+}
+
+func get(ctx context.Context, link string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", link, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching %s returned HTTP %d", link, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// Inline reports whether an archived file can be shown in the browser on
+// the API's origin: audio, video and raster images. Pages and SVG images
+// could run script there, so they are only served as downloads.
+func Inline(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return isMedia(contentType) && mediaType != "image/svg+xml"
+}
+
+func isMedia(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "video/") || strings.HasPrefix(mediaType, "image/") || strings.HasPrefix(mediaType, "audio/")
+}
+
+func save(ctx context.Context, archive *model.Archive) {
+	archives, err := DB.GetCollection("archives")
+	if err == nil {
+		_, err = archives.ReplaceOne(ctx, bson.M{"_id": archive.ID}, archive)
+	}
+	if err != nil {
+		fmt.Printf("Failed to save archive %s: %v\n", archive.ID.Hex(), err)
+	}
+}
+
+// Remove deletes an archive and, when no other archive shares its
+// content, the blob as well.
+func Remove(ctx context.Context, archive *model.Archive) error {
+	archives, err := DB.GetCollection("archives")
+	if err != nil {
+		return err
+	}
+	if _, err := archives.DeleteOne(ctx, bson.M{"_id": archive.ID}); err != nil {
+		return fmt.Errorf("failed to delete archive: %w", err)
+	}
+	if archive.Status == model.ArchiveStatusCompleted {
+		if err := addUsage(ctx, archive.UserID, -archive.Size, -1); err != nil {
+			return err
+		}
+	}
+	return releaseBlob(ctx, archive.Hash)
+}
+
+// releaseBlob deletes a blob once no archive or snapshot refers to it.
+func releaseBlob(ctx context.Context, hash string) error {
+	if hash == "" || Store == nil {
+		return nil
+	}
+	for _, name := range []string{"archives", "snapshots"} {
+		collection, err := DB.GetCollection(name)
+		if err != nil {
+			return err
+		}
+		shared, err := collection.CountDocuments(ctx, bson.M{"hash": hash})
+		if err != nil {
+			return fmt.Errorf("failed to check blob references: %w", err)
+		}
+		if shared > 0 {
+			return nil
+		}
+	}
+	return Store.Delete(ctx, hash)
+}
+
+// RemoveForUploads deletes the archives and snapshots of uploads that are
+// gone for good.
+func RemoveForUploads(ctx context.Context, uploadIDs []string) error {
+	archives, err := DB.GetCollection("archives")
+	if err != nil {
+		return err
+	}
+	cursor, err := archives.Find(ctx, bson.M{"upload_id": bson.M{"$in": uploadIDs}})
+	if err != nil {
+		return fmt.Errorf("failed to find archives: %w", err)
+	}
+	var found []model.Archive
+	if err := cursor.All(ctx, &found); err != nil {
+		return fmt.Errorf("failed to parse archives: %w", err)
+	}
+	for i := range found {
+		if err := Remove(ctx, &found[i]); err != nil {
+			return err
+		}
+	}
+
+	snapshots, err := DB.GetCollection("snapshots")
+	if err != nil {
+		return err
+	}
+	cursor, err = snapshots.Find(ctx, bson.M{"upload_id": bson.M{"$in": uploadIDs}})
+	if err != nil {
+		return fmt.Errorf("failed to find snapshots: %w", err)
+	}
+	var captured []model.Snapshot
+	if err := cursor.All(ctx, &captured); err != nil {
+		return fmt.Errorf("failed to parse snapshots: %w", err)
+	}
+	for i := range captured {
+		if err := RemoveSnapshot(ctx, &captured[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Usage returns the user's archived totals.
+func Usage(ctx context.Context, userID string) (model.ArchiveUsage, error) {
+	usage := model.ArchiveUsage{UserID: userID}
+	collection, err := DB.GetCollection("archive_usage")
+	if err != nil {
+		return usage, err
+	}
+	err = collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&usage)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return usage, fmt.Errorf("failed to fetch archive usage: %w", err)
+	}
+	return usage, nil
+}
+
+// reservation is quota set aside for one archive or snapshot while it
+// runs, so jobs started together can't each spend the same free bytes.
+type reservation struct {
+	userID string
+	limit  int64 // bytes the job may store
+	held   int64 // bytes reserved on the user's usage; 0 without a quota
+}
+
+// reserve sets aside up to MaxSize of the user's free quota, failing with
+// ErrQuotaExceeded when none is left.
+func reserve(ctx context.Context, userID string) (*reservation, error) {
+	if Quota <= 0 {
+		return &reservation{userID: userID, limit: MaxSize}, nil
+	}
+	collection, err := DB.GetCollection("archive_usage")
+	if err != nil {
+		return nil, err
+	}
+	for {
+		usage, err := Usage(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		limit := min(MaxSize, Quota-usage.Bytes-usage.Reserved)
+		if limit <= 0 {
+			return nil, ErrQuotaExceeded
+		}
+		// Only take the bytes if nobody changed the usage since it was
+		// read; otherwise read it again.
+		filter := bson.M{"_id": userID, "bytes": orUnset(usage.Bytes), "reserved": orUnset(usage.Reserved)}
+		res, err := collection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"reserved": limit}}, options.UpdateOne().SetUpsert(true))
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to reserve archive quota: %w", err)
+		}
+		if res.ModifiedCount+res.UpsertedCount == 1 {
+			return &reservation{userID: userID, limit: limit, held: limit}, nil
+		}
+	}
+}
+
+// orUnset matches the value, or a missing field when it is zero.
+func orUnset(n int64) any {
+	if n == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return n
+}
+
+// settle counts what the job stored, if anything, and gives back the
+// reservation.
+func (r *reservation) settle(ctx context.Context, size int64, count int) {
+	collection, err := DB.GetCollection("archive_usage")
+	if err == nil {
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": r.userID},
+			bson.M{"$inc": bson.M{"bytes": size, "count": count, "reserved": -r.held}},
+			options.UpdateOne().SetUpsert(true))
+	}
+	if err != nil {
+		fmt.Printf("Failed to update archive usage for %s: %v\n", r.userID, err)
+	}
+}
+
+// Recover fails the archives and snapshots a restart interrupted and
+// releases their reservations. Jobs live in this process, so it runs at
+// startup before any can be queued.
+func Recover(ctx context.Context) error {
+	for _, name := range []string{"archives", "snapshots"} {
+		collection, err := DB.GetCollection(name)
+		if err != nil {
+			return err
+		}
+		_, err = collection.UpdateMany(ctx,
+			bson.M{"status": bson.M{"$in": bson.A{model.ArchiveStatusQueued, model.ArchiveStatusRunning}}},
+			bson.M{"$set": bson.M{"status": model.ArchiveStatusFailed, "message": "interrupted by a server restart"}})
+		if err != nil {
+			return fmt.Errorf("failed to recover %s: %w", name, err)
+		}
+	}
+	usage, err := DB.GetCollection("archive_usage")
+	if err != nil {
+		return err
+	}
+	if _, err := usage.UpdateMany(ctx, bson.M{"reserved": bson.M{"$exists": true}}, bson.M{"$unset": bson.M{"reserved": ""}}); err != nil {
+		return fmt.Errorf("failed to release archive reservations: %w", err)
+	}
+	return nil
+}
+
+func addUsage(ctx context.Context, userID string, size int64, count int) error {
+	collection, err := DB.GetCollection("archive_usage")
+	if err != nil {
+		return err
+	}
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": userID},
+		bson.M{"$inc": bson.M{"bytes": size, "count": count}},
+		options.UpdateOne().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to update archive usage: %w", err)
+	}
+	return nil
+}
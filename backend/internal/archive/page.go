@@ -0,0 +1,75 @@
+package archive
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// videoProperties and imageProperties are the meta tags that point at a
+// page's media, best first.
+var (
+	videoProperties = []string{"og:video:secure_url", "og:video:url", "og:video", "twitter:player:stream"}
+	imageProperties = []string{"og:image:secure_url", "og:image"}
+)
+
+// findMediaURL looks through the page's meta tags for a direct link to its
+// video or image and resolves it against the page URL.
+func findMediaURL(base *url.URL, page []byte) string {
+	found := map[string]string{}
+	z := html.NewTokenizer(bytes.NewReader(page))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		name, hasAttr := z.TagName()
+		if string(name) == "body" {
+			break // meta tags live in the head
+		}
+		if string(name) != "meta" {
+			continue
+		}
+		var property, content string
+		for hasAttr {
+			var k, v []byte
+			k, v, hasAttr = z.TagAttr()
+			switch string(k) {
+			case "property", "name":
+				property = strings.ToLower(string(v))
+			case "content":
+				content = strings.TrimSpace(string(v))
+			}
+		}
+		if property != "" && content != "" {
+			if _, ok := found[property]; !ok {
+				found[property] = content
+			}
+		}
+	}
+
+	properties := videoProperties
+	// The image of a video page is only its thumbnail; the page is worth more.
+	if !strings.HasPrefix(found["og:type"], "video") {
+		properties = append(properties, imageProperties...)
+	}
+	for _, property := range properties {
+		raw, ok := found[property]
+		if !ok {
+			continue
+		}
+		ref, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		if resolved := base.ResolveReference(ref); resolved.Scheme == "http" || resolved.Scheme == "https" {
+			return resolved.String()
+		}
+	}
+	return ""
+}
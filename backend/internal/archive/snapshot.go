@@ -0,0 +1,181 @@
+package archive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	DB "lyked-backend/internal/database/mongodb"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/warc"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Capturer takes snapshots. It refuses private addresses; one built with
+// warc.NewCapturer(true) can capture a local fixture server instead, as
+// the warc tests do.
+var Capturer = warc.NewCapturer(false)
+
+// StartSnapshot queues a WARC capture of the upload's page. While one is
+// queued or running for the upload it is returned instead of a new one.
+func StartSnapshot(ctx context.Context, upload model.LykedUploads) (snapshot *model.Snapshot, started bool, err error) {
+	if Store == nil {
+		return nil, false, ErrNotConfigured
+	}
+	snapshots, err := DB.GetCollection("snapshots")
+	if err != nil {
+		return nil, false, err
+	}
+
+	var pending model.Snapshot
+	err = snapshots.FindOne(ctx, bson.M{
+		"user_id":   upload.UserID,
+		"upload_id": upload.ID.Hex(),
+		"status":    bson.M{"$in": []string{model.ArchiveStatusQueued, model.ArchiveStatusRunning}},
+	}).Decode(&pending)
+	if err == nil {
+		return &pending, false, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, false, fmt.Errorf("failed to fetch snapshots: %w", err)
+	}
+
+	held, err := reserve(ctx, upload.UserID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	snapshot = &model.Snapshot{
+		ID:        bson.NewObjectID(),
+		UserID:    upload.UserID,
+		UploadID:  upload.ID.Hex(),
+		Status:    model.ArchiveStatusQueued,
+		URL:       upload.VideoLink,
+		CreatedAt: time.Now().UTC(),
+	}
+	if _, err := snapshots.InsertOne(ctx, snapshot); err != nil {
+		held.settle(ctx, 0, 0)
+		return nil, false, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	go runSnapshot(*snapshot, upload.VideoLink, held)
+	return snapshot, true, nil
+}
+
+func runSnapshot(snapshot model.Snapshot, link string, held *reservation) {
+	slots <- struct{}{}
+	defer func() { <-slots }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+	defer cancel()
+
+	snapshot.Status = model.ArchiveStatusRunning
+	saveSnapshot(ctx, &snapshot)
+
+	if err := capture(ctx, &snapshot, link, held.limit); err != nil {
+		snapshot.Status = model.ArchiveStatusFailed
+		snapshot.Message = err.Error()
+		saveSnapshot(ctx, &snapshot)
+		held.settle(ctx, 0, 0)
+		return
+	}
+	now := time.Now().UTC()
+	snapshot.Status = model.ArchiveStatusCompleted
+	snapshot.CompletedAt = &now
+	saveSnapshot(ctx, &snapshot)
+	held.settle(ctx, snapshot.Size, 1)
+}
+
+// capture writes the WARC to a temporary file while hashing it, then
+// stores it like any other archived blob.
+func capture(ctx context.Context, snapshot *model.Snapshot, link string, limit int64) error {
+	tmp, err := os.CreateTemp("", "lyked-snapshot-*.warc.gz")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	capturer := *Capturer
+	capturer.MaxBytes = min(capturer.MaxBytes, limit)
+	hasher := sha256.New()
+	result, err := capturer.Capture(ctx, link, io.MultiWriter(tmp, hasher))
+	if err != nil {
+		return err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if size > limit {
+		return ErrQuotaExceeded
+	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+
+	exists, err := Store.Exists(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := Store.Put(ctx, hash, tmp); err != nil {
+			return err
+		}
+	}
+
+	snapshot.URL = result.URL
+	snapshot.Hash = hash
+	snapshot.Size = size
+	snapshot.Resources = result.Resources
+	snapshot.Failed = result.Failed
+	return nil
+}
+
+func saveSnapshot(ctx context.Context, snapshot *model.Snapshot) {
+	snapshots, err := DB.GetCollection("snapshots")
+	if err == nil {
+		_, err = snapshots.ReplaceOne(ctx, bson.M{"_id": snapshot.ID}, snapshot)
+	}
+	if err != nil {
+		fmt.Printf("Failed to save snapshot %s: %v\n", snapshot.ID.Hex(), err)
+	}
+}
+
+// OpenSnapshot loads a completed snapshot's WARC file for replay.
+func OpenSnapshot(ctx context.Context, snapshot *model.Snapshot) (*warc.Archive, error) {
+	if Store == nil {
+		return nil, ErrNotConfigured
+	}
+	blob, err := Store.Open(ctx, snapshot.Hash)
+	if err != nil {
+		return nil, err
+	}
+	defer blob.Close()
+	return warc.Load(blob)
+}
+
+// RemoveSnapshot deletes a snapshot and its WARC file unless the file is
+// shared.
+func RemoveSnapshot(ctx context.Context, snapshot *model.Snapshot) error {
+	snapshots, err := DB.GetCollection("snapshots")
+	if err != nil {
+		return err
+	}
+	if _, err := snapshots.DeleteOne(ctx, bson.M{"_id": snapshot.ID}); err != nil {
+		return fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+	if snapshot.Status == model.ArchiveStatusCompleted {
+		if err := addUsage(ctx, snapshot.UserID, -snapshot.Size, -1); err != nil {
+			return err
+		}
+	}
+	return releaseBlob(ctx, snapshot.Hash)
+}
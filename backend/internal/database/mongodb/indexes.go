@@ -0,0 +1,228 @@
+package MDB
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// collectionIndexes lists the indexes each collection needs. Every listing
+// query is scoped to a user, so user_id always leads.
+var collectionIndexes = map[string][]mongo.IndexModel{
+	"uploads": {
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "saved_at", Value: -1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetName("user_saved_at"),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "title", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetName("user_title"),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "platform", Value: 1}, {Key: "saved_at", Value: -1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetName("user_platform_saved_at"),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "folders", Value: 1}, {Key: "saved_at", Value: -1}},
+			Options: options.Index().SetName("user_folders_saved_at"),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "tags", Value: 1}, {Key: "saved_at", Value: -1}},
+			Options: options.Index().SetName("user_tags_saved_at"),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "deleted_at", Value: -1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetName("user_deleted_at").
+				SetPartialFilterExpression(bson.M{"deleted_at": bson.M{"$exists": true}}),
+		},
+		{
+			Keys: bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetName("deleted_at").
+				SetPartialFilterExpression(bson.M{"deleted_at": bson.M{"$exists": true}}),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "link_status", Value: 1}, {Key: "saved_at", Value: -1}},
+			Options: options.Index().SetName("user_link_status_saved_at"),
+		},
+		{
+			Keys:    bson.D{{Key: "link_checked_at", Value: 1}},
+			Options: options.Index().SetName("link_checked_at"),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}, {Key: "saved_at", Value: -1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetName("user_status_saved_at"),
+		},
+		{
+			// Favorites are a small slice of the library, so they get their
+			// own index, e.g. for "favorite unwatched recipes".
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}, {Key: "tags", Value: 1}, {Key: "saved_at", Value: -1}},
+			Options: options.Index().SetName("user_favorite_status_tags_saved_at").
+				SetPartialFilterExpression(bson.M{"favorite": true}),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "rating", Value: -1}, {Key: "saved_at", Value: -1}},
+			Options: options.Index().SetName("user_rating_saved_at"),
+		},
+	},
+	"folders": {
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "kind", Value: 1}},
+			Options: options.Index().SetName("user_kind"),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "path", Value: 1}},
+			Options: options.Index().SetName("user_path"),
+		},
+		{
+			Keys:    bson.D{{Key: "members.user_id", Value: 1}},
+			Options: options.Index().SetName("members_user_id"),
+		},
+		{
+			Keys: bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetName("deleted_at").
+				SetPartialFilterExpression(bson.M{"deleted_at": bson.M{"$exists": true}}),
+		},
+	},
+	"folder_invites": {
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}},
+			Options: options.Index().SetName("user_status"),
+		},
+		{
+			Keys:    bson.D{{Key: "folder_id", Value: 1}, {Key: "status", Value: 1}},
+			Options: options.Index().SetName("folder_status"),
+		},
+	},
+	"tag_meta": {
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "tag", Value: 1}},
+			Options: options.Index().SetName("user_tag").SetUnique(true),
+		},
+	},
+	"tag_aliases": {
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "alias", Value: 1}},
+			Options: options.Index().SetName("user_alias").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "tag", Value: 1}},
+			Options: options.Index().SetName("user_tag"),
+		},
+	},
+	"tag_feedback": {
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "context", Value: 1}, {Key: "tag", Value: 1}},
+			Options: options.Index().SetName("user_context_tag").SetUnique(true),
+		},
+	},
+	"rules": {
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("user_created_at"),
+		},
+	},
+	"share_links": {
+		{
+			Keys:    bson.D{{Key: "token", Value: 1}},
+			Options: options.Index().SetName("token").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("user_created_at"),
+		},
+		{
+			Keys:    bson.D{{Key: "kind", Value: 1}, {Key: "target_id", Value: 1}},
+			Options: options.Index().SetName("kind_target"),
+		},
+	},
+	"archives": {
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "upload_id", Value: 1}},
+			Options: options.Index().SetName("user_upload").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "upload_id", Value: 1}},
+			Options: options.Index().SetName("upload_id"),
+		},
+		{
+			Keys:    bson.D{{Key: "hash", Value: 1}},
+			Options: options.Index().SetName("hash"),
+		},
+	},
+	"snapshots": {
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "upload_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("user_upload_created_at"),
+		},
+		{
+			Keys:    bson.D{{Key: "upload_id", Value: 1}},
+			Options: options.Index().SetName("upload_id"),
+		},
+		{
+			Keys:    bson.D{{Key: "hash", Value: 1}},
+			Options: options.Index().SetName("hash"),
+		},
+	},
+	"resurface_feedback": {
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "upload_id", Value: 1}},
+			Options: options.Index().SetName("user_upload").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "upload_id", Value: 1}},
+			Options: options.Index().SetName("upload_id"),
+		},
+	},
+	"review_cards": {
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "upload_id", Value: 1}},
+			Options: options.Index().SetName("user_upload").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "due_at", Value: 1}},
+			Options: options.Index().SetName("user_due_at"),
+		},
+		{
+			Keys:    bson.D{{Key: "upload_id", Value: 1}},
+			Options: options.Index().SetName("upload_id"),
+		},
+	},
+	"review_log": {
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "reviewed_at", Value: -1}},
+			Options: options.Index().SetName("user_reviewed_at"),
+		},
+		{
+			Keys:    bson.D{{Key: "upload_id", Value: 1}},
+			Options: options.Index().SetName("upload_id"),
+		},
+	},
+	"import_jobs": {
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("user_created_at"),
+		},
+	},
+}
+
+// EnsureIndexes creates any missing indexes. CreateMany is a no-op for
+// indexes that already exist with the same definition.
+func EnsureIndexes() error {
+	if MongoDB == nil {
+		return fmt.Errorf("MongoDB client is not connected")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for name, models := range collectionIndexes {
+		if _, err := MongoDB.Collection(name).Indexes().CreateMany(ctx, models); err != nil {
+			return fmt.Errorf("failed to create indexes on '%s': %w", name, err)
+		}
+	}
+	fmt.Println("✅ MongoDB indexes ensured")
+	return nil
+}
@@ -0,0 +1,58 @@
+package MDB
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var (
+	transactionsOnce      sync.Once
+	transactionsSupported bool
+)
+
+// WithTransaction runs fn in a multi-document transaction; every operation
+// fn makes with the ctx it is given is part of it, and so is any nested
+// WithTransaction. fn may be retried on transient errors, so it must not
+// have side effects outside the database.
+//
+// Transactions need a replica set or sharded cluster. Against a standalone
+// server fn runs without one, and `go run ./cmd/membership -repair` puts
+// right anything a failure halfway through leaves behind.
+func WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongoClient == nil {
+		return fmt.Errorf("MongoDB client is not connected")
+	}
+	if mongo.SessionFromContext(ctx) != nil {
+		// Already inside a transaction, which fn joins.
+		return fn(ctx)
+	}
+	transactionsOnce.Do(func() {
+		var hello bson.M
+		err := mongoClient.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+		if err != nil {
+			return
+		}
+		_, replicaSet := hello["setName"]
+		transactionsSupported = replicaSet || hello["msg"] == "isdbgrid"
+		if !transactionsSupported {
+			fmt.Println("MongoDB is a standalone server; multi-document updates run without transactions")
+		}
+	})
+	if !transactionsSupported {
+		return fn(ctx)
+	}
+
+	session, err := mongoClient.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(ctx context.Context) (any, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
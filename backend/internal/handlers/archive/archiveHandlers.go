@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lyked-backend/internal/archive"
+	DB "lyked-backend/internal/database/mongodb"
+	model "lyked-backend/internal/models/mongodb"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// StartArchiveHandler queues an offline copy of an upload. Archiving is
+// opt-in per item; nothing is downloaded until this is called.
+func StartArchiveHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid upload ID"})
+		return
+	}
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var upload model.LykedUploads
+	err = uploads.FindOne(ctx, bson.M{"_id": id, "user_id": userID, "deleted_at": nil}).Decode(&upload)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(404, gin.H{"error": "Upload not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch upload"})
+		return
+	}
+
+	record, started, err := archive.Start(ctx, upload)
+	switch {
+	case errors.Is(err, archive.ErrNotConfigured):
+		c.JSON(503, gin.H{"error": "Archiving is not available"})
+		return
+	case errors.Is(err, archive.ErrQuotaExceeded):
+		c.JSON(403, gin.H{"error": "Archive quota exceeded"})
+		return
+	case err != nil:
+		c.JSON(500, gin.H{"error": "Failed to start archiving"})
+		return
+	}
+	if started {
+		c.JSON(202, gin.H{"message": "Archiving started", "archive": record})
+		return
+	}
+	c.JSON(200, gin.H{"archive": record})
+}
+
+// GetArchiveHandler returns the archive's status and metadata.
+func GetArchiveHandler(c *gin.Context) {
+	record, ok := findArchive(c)
+	if !ok {
+		return
+	}
+	c.JSON(200, gin.H{"archive": record})
+}
+
+// StreamArchiveHandler serves the archived file. http.ServeContent takes
+// care of Range requests, so video players can seek. Only media is served
+// inline; pages come back as downloads.
+func StreamArchiveHandler(c *gin.Context) {
+	record, ok := findArchive(c)
+	if !ok {
+		return
+	}
+	if record.Status != model.ArchiveStatusCompleted {
+		c.JSON(409, gin.H{"error": "Archive is not ready", "status": record.Status})
+		return
+	}
+	if archive.Store == nil {
+		c.JSON(503, gin.H{"error": "Archiving is not available"})
+		return
+	}
+
+	blob, err := archive.Store.Open(c.Request.Context(), record.Hash)
+	if errors.Is(err, archive.ErrBlobNotFound) {
+		c.JSON(404, gin.H{"error": "Archived file is missing"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to open archive"})
+		return
+	}
+	defer blob.Close()
+
+	modified := record.CreatedAt
+	if record.CompletedAt != nil {
+		modified = *record.CompletedAt
+	}
+	// Archived pages are someone else's HTML: never let them run on our
+	// origin.
+	c.Header("Content-Type", record.ContentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Security-Policy", "sandbox")
+	if !archive.Inline(record.ContentType) {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="archive-%s"`, record.ID.Hex()))
+	}
+	c.Header("ETag", `"`+record.Hash+`"`)
+	c.Header("Cache-Control", "private, max-age=31536000, immutable")
+	http.ServeContent(c.Writer, c.Request, "", modified, blob)
+}
+
+// DeleteArchiveHandler removes the offline copy; the upload is untouched.
+func DeleteArchiveHandler(c *gin.Context) {
+	record, ok := findArchive(c)
+	if !ok {
+		return
+	}
+	if record.Status == model.ArchiveStatusQueued || record.Status == model.ArchiveStatusRunning {
+		c.JSON(409, gin.H{"error": "Archive is still in progress"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := archive.Remove(ctx, record); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to delete archive"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Archive deleted"})
+}
+
+// ArchiveUsageHandler returns how much the user has archived, and the quota.
+func ArchiveUsageHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	usage, err := archive.Usage(ctx, userID.(string))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch archive usage"})
+		return
+	}
+	c.JSON(200, gin.H{"usage": usage, "quota_bytes": archive.Quota})
+}
+
+// findArchive loads the caller's archive of the upload in the :id param,
+// writing the error response itself when there is none.
+func findArchive(c *gin.Context) (*model.Archive, bool) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return nil, false
+	}
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid upload ID"})
+		return nil, false
+	}
+	archives, err := DB.GetCollection("archives")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return nil, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var record model.Archive
+	err = archives.FindOne(ctx, bson.M{"user_id": userID, "upload_id": id.Hex()}).Decode(&record)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(404, gin.H{"error": "Archive not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch archive"})
+		return nil, false
+	}
+	return &record, true
+}
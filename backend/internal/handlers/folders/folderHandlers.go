@@ -0,0 +1,923 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	DB "lyked-backend/internal/database/mongodb"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/search"
+	"lyked-backend/internal/search/query"
+	"lyked-backend/internal/trash"
+	"lyked-backend/internal/utils"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const maxCovers = 4
+
+type folderSummary struct {
+	model.Folder
+	ItemCount  int64           `json:"item_count"`
+	TotalCount int64           `json:"total_count"`    // items in the folder and all of its subfolders
+	Covers     []string        `json:"covers"`         // thumbnails of the most recent items
+	Role       string          `json:"role,omitempty"` // the caller's role, on folders shared with them
+	Children   []folderSummary `json:"children,omitempty"`
+}
+
+type folderRequest struct {
+	Name     string `json:"name"`
+	Pinned   bool   `json:"pinned"`
+	ParentID string `json:"parent_id"`
+}
+
+type folderItemsRequest struct {
+	UploadIDs []string `json:"upload_ids"`
+}
+
+// ListFoldersHandler returns every folder, static and smart, with item
+// counts and up to four cover thumbnails. Pinned folders come first.
+// parent=root or parent=<id> limits the list to one level; the parent may
+// be a folder shared with the user.
+func ListFoldersHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	parent := c.Query("parent")
+	if parent == "root" {
+		parent = ""
+	} else if _, err := bson.ObjectIDFromHex(parent); parent != "" && err != nil {
+		c.JSON(400, gin.H{"error": "parent must be root or a folder ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if parent != "" {
+		summaries, ok := subfolderSummaries(c, ctx, userID.(string), parent)
+		if ok {
+			c.JSON(200, gin.H{"folders": summaries})
+		}
+		return
+	}
+	summaries, ok := folderSummaries(c, ctx, userID.(string))
+	if !ok {
+		return
+	}
+	if c.Query("parent") != "" {
+		level := []folderSummary{}
+		for _, f := range summaries {
+			if f.ParentID == parent {
+				level = append(level, f)
+			}
+		}
+		summaries = level
+	}
+	c.JSON(200, gin.H{"folders": summaries})
+}
+
+// FolderTreeHandler returns the folders nested under their parents, each
+// level sorted like GET /folders.
+func FolderTreeHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	summaries, ok := folderSummaries(c, ctx, userID.(string))
+	if !ok {
+		return
+	}
+	children := map[string][]folderSummary{}
+	for _, f := range summaries {
+		children[f.ParentID] = append(children[f.ParentID], f)
+	}
+	var build func(parentID string) []folderSummary
+	build = func(parentID string) []folderSummary {
+		level := children[parentID]
+		for i := range level {
+			level[i].Children = build(level[i].ID.Hex())
+		}
+		return level
+	}
+	tree := build("")
+	if tree == nil {
+		tree = []folderSummary{}
+	}
+	c.JSON(200, gin.H{"folders": tree})
+}
+
+// folderSummaries loads the user's live folders with their counts and
+// covers, sorted. It writes the error response itself.
+func folderSummaries(c *gin.Context, ctx context.Context, userID string) ([]folderSummary, bool) {
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return nil, false
+	}
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return nil, false
+	}
+
+	cursor, err := folders.Find(ctx, bson.M{"user_id": userID, "deleted_at": nil})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch folders"})
+		return nil, false
+	}
+	defer cursor.Close(ctx)
+
+	var all []model.Folder
+	if err := cursor.All(ctx, &all); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to parse folders"})
+		return nil, false
+	}
+	return summarize(c, ctx, uploads, all)
+}
+
+// subfolderSummaries lists the direct subfolders of a folder the user can
+// see, with the caller's role when someone else owns them. It writes the
+// error response itself.
+func subfolderSummaries(c *gin.Context, ctx context.Context, userID, parentID string) ([]folderSummary, bool) {
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return nil, false
+	}
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return nil, false
+	}
+	parent, _, err := library.AccessFolder(ctx, folders, userID, parentID, model.RoleViewer)
+	if err != nil {
+		folderError(c, err, "Failed to fetch folder")
+		return nil, false
+	}
+	descendants, err := library.Descendants(ctx, folders, parent)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch folders"})
+		return nil, false
+	}
+	summaries, ok := summarize(c, ctx, uploads, descendants)
+	if !ok {
+		return nil, false
+	}
+	level := []folderSummary{}
+	for _, f := range summaries {
+		if f.ParentID == parentID {
+			if f.UserID != userID {
+				// Members may have a stronger role on the subfolder itself.
+				if f.Role, err = library.FolderRole(ctx, folders, &f.Folder, userID); err != nil {
+					c.JSON(500, gin.H{"error": "Failed to fetch folders"})
+					return nil, false
+				}
+			}
+			level = append(level, f)
+		}
+	}
+	return level, true
+}
+
+// summarize counts the items of each folder and its subfolders among all,
+// and sorts them. Items filed by other members of a shared folder count
+// too. Static folders are counted together in one aggregation; smart
+// folders, whose filters differ, are counted one by one. It writes the
+// error response itself.
+func summarize(c *gin.Context, ctx context.Context, uploads *mongo.Collection, all []model.Folder) ([]folderSummary, bool) {
+	now := time.Now()
+	stats, err := staticFolderStats(ctx, uploads, all)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to count folder items"})
+		return nil, false
+	}
+	summaries := make([]folderSummary, 0, len(all))
+	for _, f := range all {
+		summary := folderSummary{Folder: f, Covers: []string{}}
+		if f.IsSmart() {
+			filter, err := folderItemsFilter(&f, now)
+			if err != nil {
+				// A smart folder whose filter no longer compiles still gets listed.
+				summaries = append(summaries, summary)
+				continue
+			}
+			filter["deleted_at"] = nil
+			if summary.ItemCount, err = uploads.CountDocuments(ctx, filter); err != nil {
+				c.JSON(500, gin.H{"error": "Failed to count folder items"})
+				return nil, false
+			}
+			if summary.Covers, err = folderCovers(ctx, uploads, filter); err != nil {
+				c.JSON(500, gin.H{"error": "Failed to fetch folder covers"})
+				return nil, false
+			}
+		} else {
+			summary.ItemCount = stats.count(map[string]bool{f.ID.Hex(): true})
+			summary.Covers = coverThumbnails(stats.covers[f.ID.Hex()])
+		}
+		summary.TotalCount = summary.ItemCount
+
+		subtree := []model.Folder{f}
+		ids := map[string]bool{f.ID.Hex(): true}
+		static := !f.IsSmart()
+		for _, d := range all {
+			if strings.HasPrefix(d.AncestorPath(), f.ChildPath()) {
+				subtree = append(subtree, d)
+				ids[d.ID.Hex()] = true
+				static = static && !d.IsSmart()
+			}
+		}
+		if len(subtree) > 1 {
+			if static {
+				summary.TotalCount = stats.count(ids)
+			} else {
+				// Items filed in several folders of the subtree count once.
+				filter, err := library.TreeFilter(subtree, now)
+				if err == nil {
+					summary.TotalCount, err = uploads.CountDocuments(ctx, bson.M{"$and": bson.A{filter, bson.M{"deleted_at": nil}}})
+				}
+				if err != nil {
+					c.JSON(500, gin.H{"error": "Failed to count folder items"})
+					return nil, false
+				}
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if summaries[i].Pinned != summaries[j].Pinned {
+			return summaries[i].Pinned
+		}
+		return strings.ToLower(summaries[i].Name) < strings.ToLower(summaries[j].Name)
+	})
+	return summaries, true
+}
+
+// folderStats is what one aggregation over the live items filed in static
+// folders found.
+type folderStats struct {
+	// combos are the distinct sets of folders items are filed in, with
+	// how many items share each set.
+	combos []folderCombo
+	// covers holds the links of each folder's most recent items.
+	covers map[string][]string
+}
+
+type folderCombo struct {
+	Folders []string `bson:"_id"`
+	Count   int64    `bson:"count"`
+}
+
+// count returns how many items are filed in any of the folders, each item
+// counted once.
+func (s *folderStats) count(folders map[string]bool) int64 {
+	var n int64
+	for _, combo := range s.combos {
+		if slices.ContainsFunc(combo.Folders, func(id string) bool { return folders[id] }) {
+			n += combo.Count
+		}
+	}
+	return n
+}
+
+// staticFolderStats counts and finds covers for every static folder among
+// all in a single aggregation.
+func staticFolderStats(ctx context.Context, uploads *mongo.Collection, all []model.Folder) (*folderStats, error) {
+	stats := &folderStats{covers: map[string][]string{}}
+	ids := bson.A{}
+	for _, f := range all {
+		if !f.IsSmart() {
+			ids = append(ids, f.ID.Hex())
+		}
+	}
+	if len(ids) == 0 {
+		return stats, nil
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"folders": bson.M{"$in": ids}, "deleted_at": nil}}},
+		{{Key: "$sort", Value: bson.D{{Key: "saved_at", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$project", Value: bson.M{"video_link": 1, "folders": bson.M{"$setIntersection": bson.A{"$folders", ids}}}}},
+		{{Key: "$facet", Value: bson.M{
+			"combos": bson.A{
+				bson.M{"$group": bson.M{"_id": "$folders", "count": bson.M{"$sum": 1}}},
+			},
+			"covers": bson.A{
+				bson.M{"$unwind": "$folders"},
+				// $firstN keeps each group bounded, where $push would hold
+				// every link of the folder.
+				bson.M{"$group": bson.M{"_id": "$folders", "links": bson.M{"$firstN": bson.M{"input": "$video_link", "n": 5 * maxCovers}}}},
+			},
+		}}},
+	}
+	cursor, err := uploads.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+	var out []struct {
+		Combos []folderCombo `bson:"combos"`
+		Covers []struct {
+			ID    string   `bson:"_id"`
+			Links []string `bson:"links"`
+		} `bson:"covers"`
+	}
+	if err := cursor.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return stats, nil
+	}
+	stats.combos = out[0].Combos
+	for _, c := range out[0].Covers {
+		stats.covers[c.ID] = c.Links
+	}
+	return stats, nil
+}
+
+// GetFolderItemsHandler returns a folder with a page of its items. Smart
+// folders are evaluated on every request. Accepts the same params as
+// GET /upload/all; include_subfolders=true adds the items of subfolders.
+// Static folders default to their manual order (sort=position).
+func GetFolderItemsHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	folder, role, err := library.AccessFolder(ctx, folders, userID.(string), c.Param("id"), model.RoleViewer)
+	if err != nil {
+		folderError(c, err, "Failed to fetch folder")
+		return
+	}
+
+	// A static folder on its own is listed by membership, in its manual
+	// order unless asked otherwise.
+	params := c.Request.URL.Query()
+	byMembership := !folder.IsSmart() && params.Get("include_subfolders") != "true"
+	if byMembership {
+		params.Set("folder", folder.ID.Hex())
+		if params.Get("sort") == "" {
+			params.Set("sort", library.SortPosition)
+		}
+	}
+	opts, err := library.ParseListParams(params)
+	var syntaxErr *query.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		c.JSON(400, gin.H{"error": "Invalid search query", "details": syntaxErr.Msg, "position": syntaxErr.Pos})
+		return
+	}
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	opts.UserID = userID.(string)
+	opts.AnyOwner = true
+
+	crumbs, err := library.Breadcrumbs(ctx, folders, folder, opts.UserID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch parent folders"})
+		return
+	}
+	if !byMembership {
+		var filter bson.M
+		if opts.Subfolders {
+			filter, err = library.SubtreeFilter(ctx, folders, folder, time.Now())
+		} else {
+			filter, err = folderItemsFilter(folder, time.Now())
+		}
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Smart folder filter is invalid", "details": err.Error()})
+			return
+		}
+		if opts.Query != nil {
+			filter = bson.M{"$and": bson.A{filter, opts.Query}}
+		}
+		opts.Query = filter
+	}
+
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	page, err := library.List(ctx, uploads, opts)
+	if errors.Is(err, library.ErrInvalidCursor) {
+		c.JSON(400, gin.H{"error": "Invalid cursor"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch folder items"})
+		return
+	}
+
+	c.JSON(200, gin.H{"folder": folder, "role": role, "breadcrumbs": crumbs, "uploads": page.Uploads, "next_cursor": page.NextCursor, "has_more": page.HasMore, "total": page.Total})
+}
+
+// PinFolderHandler pins or unpins a folder of either kind. Needs the
+// editor role on shared folders.
+func PinFolderHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var body struct {
+		Pinned *bool `json:"pinned"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Pinned == nil {
+		c.JSON(400, gin.H{"error": "pinned (true or false) is required"})
+		return
+	}
+
+	collection, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	folder, _, err := library.AccessFolder(ctx, collection, userID.(string), c.Param("id"), model.RoleEditor)
+	if err != nil {
+		folderError(c, err, "Failed to fetch folder")
+		return
+	}
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": folder.ID}, bson.M{"$set": bson.M{"pinned": *body.Pinned}}); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to update folder"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Folder updated", "pinned": *body.Pinned})
+}
+
+// CreateFolderHandler creates a static folder, at the top level or under
+// parent_id. Names are unique among the static folders sharing a parent,
+// ignoring case, as imports match folders by name. Editors of a shared
+// folder can create subfolders in it, which belong to its owner.
+func CreateFolderHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+
+	var req folderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid folder data"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(400, gin.H{"error": "Folder name is required"})
+		return
+	}
+
+	collection, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	owner, ok := folderOwner(c, ctx, collection, userID.(string), req.ParentID)
+	if !ok {
+		return
+	}
+	folder := model.Folder{
+		ID:        bson.NewObjectID(),
+		UserID:    owner,
+		Name:      req.Name,
+		Kind:      model.FolderKindStatic,
+		Pinned:    req.Pinned,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := library.CreateFolder(ctx, collection, &folder, req.ParentID); err != nil {
+		folderError(c, err, "Failed to create folder")
+		return
+	}
+	c.JSON(201, gin.H{"message": "Folder created", "folder": folder})
+}
+
+// RenameFolderHandler renames a folder of either kind. Needs the editor
+// role on shared folders.
+func RenameFolderHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var req folderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid folder data"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(400, gin.H{"error": "Folder name is required"})
+		return
+	}
+
+	collection, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	folder, _, err := library.AccessFolder(ctx, collection, userID.(string), c.Param("id"), model.RoleEditor)
+	if err != nil {
+		folderError(c, err, "Failed to fetch folder")
+		return
+	}
+	if !folder.IsSmart() {
+		taken, err := library.FolderNameTaken(ctx, collection, folder.UserID, folder.ParentID, req.Name, folder.ID)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to fetch folders"})
+			return
+		}
+		if taken {
+			c.JSON(409, gin.H{"error": "A folder with this name already exists"})
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": folder.ID, "user_id": folder.UserID}, bson.M{"$set": bson.M{"name": req.Name, "updated_at": now}}); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to update folder"})
+		return
+	}
+	folder.Name, folder.UpdatedAt = req.Name, &now
+	c.JSON(200, gin.H{"message": "Folder renamed", "folder": folder})
+}
+
+// MoveFolderHandler moves a folder, with its subfolders, under parent_id
+// or to the top level when parent_id is empty. Needs the owner role on
+// the folder and the editor role on the new parent, which has to belong
+// to the same owner.
+func MoveFolderHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var body struct {
+		ParentID *string `json:"parent_id"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.ParentID == nil {
+		c.JSON(400, gin.H{"error": "parent_id is required; use an empty string for the top level"})
+		return
+	}
+
+	collection, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	folder, _, err := library.AccessFolder(ctx, collection, userID.(string), c.Param("id"), model.RoleOwner)
+	if err != nil {
+		folderError(c, err, "Failed to fetch folder")
+		return
+	}
+	owner, ok := folderOwner(c, ctx, collection, userID.(string), *body.ParentID)
+	if !ok {
+		return
+	}
+	if *body.ParentID != "" && owner != folder.UserID {
+		folderError(c, library.ErrMoveAcrossOwners, "Failed to move folder")
+		return
+	}
+	folder, err = library.MoveFolder(ctx, collection, folder.UserID, folder.ID.Hex(), *body.ParentID, time.Now().UTC())
+	if err != nil {
+		folderError(c, err, "Failed to move folder")
+		return
+	}
+	c.JSON(200, gin.H{"message": "Folder moved", "folder": folder})
+}
+
+// BreadcrumbsHandler returns the path from the top level down to a folder.
+func BreadcrumbsHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	collection, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	folder, _, err := library.AccessFolder(ctx, collection, userID.(string), c.Param("id"), model.RoleViewer)
+	if err != nil {
+		folderError(c, err, "Failed to fetch folder")
+		return
+	}
+	crumbs, err := library.Breadcrumbs(ctx, collection, folder, userID.(string))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch parent folders"})
+		return
+	}
+	c.JSON(200, gin.H{"breadcrumbs": crumbs})
+}
+
+// DeleteFolderHandler moves a folder and its subfolders to the trash. With
+// contents=trash the items filed in a static folder or its subfolders are
+// trashed along with them; by default (contents=keep) they stay in the
+// library. Items filed by other members of a shared folder are never
+// trashed. Needs the owner role.
+func DeleteFolderHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	contents := c.DefaultQuery("contents", "keep")
+	if contents != "keep" && contents != "trash" {
+		c.JSON(400, gin.H{"error": "contents must be keep or trash"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	folder, _, err := library.AccessFolder(ctx, folders, userID.(string), c.Param("id"), model.RoleOwner)
+	if err != nil {
+		folderError(c, err, "Failed to fetch folder")
+		return
+	}
+	if contents == "trash" && folder.IsSmart() {
+		c.JSON(400, gin.H{"error": "A smart folder's contents can't be trashed with it"})
+		return
+	}
+
+	now := time.Now().UTC()
+	var trashed []bson.ObjectID
+	if contents == "trash" {
+		trashed, err = trash.TrashFolderWithItems(ctx, folder.UserID, folder.ID, now)
+	} else {
+		_, err = trash.TrashFolder(ctx, folder.UserID, folder.ID, now)
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to delete folder"})
+		return
+	}
+	for _, id := range trashed {
+		if err := search.Default.Remove(ctx, folder.UserID, id.Hex()); err != nil {
+			fmt.Printf("Failed to remove upload %s from search: %v\n", id.Hex(), err)
+		}
+	}
+	c.JSON(200, gin.H{"message": "Folder moved to trash", "trashed_items": len(trashed)})
+}
+
+// AddFolderItemsHandler files uploads into a static folder.
+func AddFolderItemsHandler(c *gin.Context) {
+	changeFolderItems(c, library.OpAddToFolder)
+}
+
+// RemoveFolderItemsHandler takes uploads out of a static folder. The
+// uploads themselves are kept.
+func RemoveFolderItemsHandler(c *gin.Context) {
+	changeFolderItems(c, library.OpRemoveFromFolder)
+}
+
+// changeFolderItems runs a folder membership change as a batch operation,
+// so it reports per-item results the way POST /upload/batch does. On a
+// shared folder contributors file and remove their own items; editors can
+// remove anyone's.
+func changeFolderItems(c *gin.Context, op string) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var body folderItemsRequest
+	if err := c.ShouldBindJSON(&body); err != nil || len(body.UploadIDs) == 0 {
+		c.JSON(400, gin.H{"error": "upload_ids is required"})
+		return
+	}
+	req := library.BatchRequest{Operation: op, IDs: body.UploadIDs, FolderID: c.Param("id")}
+	if err := req.Validate(); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := library.ApplyBatch(ctx, uploads, folders, userID.(string), req, time.Now().UTC())
+	switch {
+	case errors.Is(err, library.ErrFolderNotFound):
+		c.JSON(404, gin.H{"error": "Folder not found"})
+		return
+	case errors.Is(err, library.ErrSmartFolder):
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, library.ErrForbidden):
+		c.JSON(403, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(500, gin.H{"error": "Failed to update folder items"})
+		return
+	}
+
+	var changed []bson.ObjectID
+	for _, r := range result.Results {
+		if id, err := bson.ObjectIDFromHex(r.ID); err == nil && r.Status == library.BatchUpdated {
+			changed = append(changed, id)
+		}
+	}
+	// Editors may have changed other members' items.
+	if err := search.Reindex(ctx, "", changed); err != nil {
+		fmt.Printf("Failed to reindex folder items: %v\n", err)
+	}
+	c.JSON(200, result)
+}
+
+// ReorderFolderItemHandler moves one item of a static folder to a new spot
+// in its manual order, between after_id and before_id. Needs the editor
+// role on shared folders.
+func ReorderFolderItemHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var req library.MoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid reorder data"})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rank, err := library.MoveInFolder(ctx, uploads, folders, userID.(string), c.Param("id"), req)
+	switch {
+	case errors.Is(err, library.ErrFolderNotFound):
+		c.JSON(404, gin.H{"error": "Folder not found"})
+		return
+	case errors.Is(err, library.ErrNotInFolder):
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, library.ErrSmartFolder):
+		c.JSON(400, gin.H{"error": "Smart folders can't be reordered"})
+		return
+	case errors.Is(err, library.ErrBadNeighbors):
+		c.JSON(409, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, library.ErrForbidden):
+		c.JSON(403, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(500, gin.H{"error": "Failed to reorder folder"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Item moved", "upload_id": req.UploadID, "position": rank})
+}
+
+// folderError maps the library's folder errors to responses, falling back
+// to a 500 with the given message.
+func folderError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, library.ErrFolderNotFound):
+		c.JSON(404, gin.H{"error": "Folder not found"})
+	case errors.Is(err, library.ErrForbidden):
+		c.JSON(403, gin.H{"error": err.Error()})
+	case errors.Is(err, library.ErrInviteNotFound), errors.Is(err, library.ErrMemberNotFound):
+		c.JSON(404, gin.H{"error": err.Error()})
+	case errors.Is(err, library.ErrFolderNameTaken), errors.Is(err, library.ErrAlreadyMember):
+		c.JSON(409, gin.H{"error": err.Error()})
+	case errors.Is(err, library.ErrFolderCycle), errors.Is(err, library.ErrFolderTooDeep), errors.Is(err, library.ErrSmartParent),
+		errors.Is(err, library.ErrInvalidRole), errors.Is(err, library.ErrInviteYourself), errors.Is(err, library.ErrShareSmart),
+		errors.Is(err, library.ErrMoveAcrossOwners):
+		c.JSON(400, gin.H{"error": err.Error()})
+	default:
+		c.JSON(500, gin.H{"error": fallback})
+	}
+}
+
+// folderOwner returns who a folder created or moved under parentID
+// belongs to: the caller at the top level, otherwise the parent's owner,
+// provided the caller is an editor there. It writes the error response
+// itself.
+func folderOwner(c *gin.Context, ctx context.Context, folders *mongo.Collection, userID, parentID string) (string, bool) {
+	if parentID == "" {
+		return userID, true
+	}
+	parent, _, err := library.AccessFolder(ctx, folders, userID, parentID, model.RoleEditor)
+	if err != nil {
+		folderError(c, err, "Failed to fetch folder")
+		return "", false
+	}
+	return parent.UserID, true
+}
+
+// folderCovers returns thumbnails of a folder's most recent items, for
+// the ones whose links have one.
+func folderCovers(ctx context.Context, uploads *mongo.Collection, filter bson.M) ([]string, error) {
+	cursor, err := uploads.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "saved_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetProjection(bson.M{"video_link": 1}).
+		SetLimit(5*maxCovers))
+	if err != nil {
+		return nil, err
+	}
+	var docs []struct {
+		VideoLink string `bson:"video_link"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	links := make([]string, len(docs))
+	for i, d := range docs {
+		links[i] = d.VideoLink
+	}
+	return coverThumbnails(links), nil
+}
+
+// coverThumbnails picks up to maxCovers thumbnails from links, newest
+// first.
+func coverThumbnails(links []string) []string {
+	covers := []string{}
+	for _, link := range links {
+		if thumb := utils.ThumbnailURL(link); thumb != "" {
+			covers = append(covers, thumb)
+			if len(covers) == maxCovers {
+				break
+			}
+		}
+	}
+	return covers
+}
+
+// folderItemsFilter selects a folder's items: by membership for static
+// folders, whoever filed them, and by the stored filter over the owner's
+// library for smart ones.
+func folderItemsFilter(f *model.Folder, now time.Time) (bson.M, error) {
+	if f.IsSmart() {
+		filter, err := library.SmartFolderFilter(f.Filter, now)
+		if err != nil {
+			return nil, err
+		}
+		filter["user_id"] = f.UserID
+		return filter, nil
+	}
+	return bson.M{"folders": f.ID.Hex()}, nil
+}
@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	DB "lyked-backend/internal/database/mongodb"
+	PDB "lyked-backend/internal/database/postgresql"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	modelPG "lyked-backend/internal/models/postgresql"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"gorm.io/gorm"
+)
+
+type inviteRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+}
+
+// InviteHandler invites a user, by username or email, to a folder with a
+// role. Only owners can invite.
+func InviteHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var req inviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid invite data"})
+		return
+	}
+	login := strings.TrimSpace(req.Username)
+	if login == "" {
+		login = strings.TrimSpace(req.Email)
+	}
+	if login == "" {
+		c.JSON(400, gin.H{"error": "username or email is required"})
+		return
+	}
+
+	if PDB.PostgresDB == nil {
+		c.JSON(500, gin.H{"error": "Database connection not available"})
+		return
+	}
+	var invitee modelPG.User
+	err := PDB.PostgresDB.Where("username = ? OR email = ?", login, login).First(&invitee).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(404, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
+	invites, folders, ok := sharingCollections(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	invite, err := library.Invite(ctx, invites, folders, userID.(string), c.Param("id"),
+		invitee.ID.String(), invitee.Username, req.Role, time.Now().UTC())
+	if err != nil {
+		folderError(c, err, "Failed to invite user")
+		return
+	}
+	c.JSON(201, gin.H{"message": "Invite sent", "invite": invite})
+}
+
+// ListMembersHandler returns who a folder is shared with. Owners also see
+// the pending invites.
+func ListMembersHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	invites, folders, ok := sharingCollections(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	folder, role, err := library.AccessFolder(ctx, folders, userID.(string), c.Param("id"), model.RoleViewer)
+	if err != nil {
+		folderError(c, err, "Failed to fetch folder")
+		return
+	}
+	members := folder.Members
+	if members == nil {
+		members = []model.FolderMember{}
+	}
+	resp := gin.H{"owner_id": folder.UserID, "role": role, "members": members}
+	if role == model.RoleOwner {
+		pending, err := library.FolderInvites(ctx, invites, folder.ID)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to fetch invites"})
+			return
+		}
+		resp["invites"] = pending
+	}
+	c.JSON(200, resp)
+}
+
+// UpdateMemberHandler changes a member's role. Only owners can.
+func UpdateMemberHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var body struct {
+		Role string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid member data"})
+		return
+	}
+	_, folders, ok := sharingCollections(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := library.SetMemberRole(ctx, folders, userID.(string), c.Param("id"), c.Param("user_id"), body.Role); err != nil {
+		folderError(c, err, "Failed to update member")
+		return
+	}
+	c.JSON(200, gin.H{"message": "Member updated"})
+}
+
+// RemoveMemberHandler takes someone off a folder. Owners can remove
+// anyone; members can remove themselves to leave.
+func RemoveMemberHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	_, folders, ok := sharingCollections(c)
+	if !ok {
+		return
+	}
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := library.RemoveMember(ctx, uploads, folders, userID.(string), c.Param("id"), c.Param("user_id")); err != nil {
+		folderError(c, err, "Failed to remove member")
+		return
+	}
+	c.JSON(200, gin.H{"message": "Member removed"})
+}
+
+// RevokeInviteHandler withdraws a pending invite. Only owners can.
+func RevokeInviteHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	invites, folders, ok := sharingCollections(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := library.RevokeInvite(ctx, invites, folders, userID.(string), c.Param("id"), c.Param("invite_id"), time.Now().UTC())
+	if err != nil {
+		folderError(c, err, "Failed to revoke invite")
+		return
+	}
+	c.JSON(200, gin.H{"message": "Invite revoked"})
+}
+
+// MyInvitesHandler returns the invites waiting for the user's answer.
+func MyInvitesHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	invites, _, ok := sharingCollections(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pending, err := library.PendingInvites(ctx, invites, userID.(string))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch invites"})
+		return
+	}
+	c.JSON(200, gin.H{"invites": pending})
+}
+
+// AcceptInviteHandler accepts an invite, adding the user to the folder.
+func AcceptInviteHandler(c *gin.Context) {
+	respondToInvite(c, true)
+}
+
+// DeclineInviteHandler declines an invite.
+func DeclineInviteHandler(c *gin.Context) {
+	respondToInvite(c, false)
+}
+
+func respondToInvite(c *gin.Context, accept bool) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	invites, folders, ok := sharingCollections(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	invite, err := library.RespondToInvite(ctx, invites, folders, userID.(string), c.Param("invite_id"), accept, time.Now().UTC())
+	if err != nil {
+		folderError(c, err, "Failed to answer invite")
+		return
+	}
+	message := "Invite declined"
+	if accept {
+		message = "Invite accepted"
+	}
+	c.JSON(200, gin.H{"message": message, "invite": invite})
+}
+
+// SharedWithMeHandler returns the folders other users have shared with the
+// user, with counts, covers and the user's role on each.
+func SharedWithMeHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	_, folders, ok := sharingCollections(c)
+	if !ok {
+		return
+	}
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	shared, err := library.SharedWithMe(ctx, folders, userID.(string))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch shared folders"})
+		return
+	}
+	// Subfolders are needed for the totals, but listed through their parent
+	// when that is shared too.
+	sharedIDs := map[string]bool{}
+	for _, f := range shared {
+		sharedIDs[f.ID.Hex()] = true
+	}
+	var all []model.Folder
+	roles := map[string]string{}
+	for _, f := range shared {
+		if insideShared(&f, sharedIDs) {
+			continue
+		}
+		descendants, err := library.Descendants(ctx, folders, &f)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to fetch shared folders"})
+			return
+		}
+		if roles[f.ID.Hex()], err = library.FolderRole(ctx, folders, &f, userID.(string)); err != nil {
+			c.JSON(500, gin.H{"error": "Failed to fetch shared folders"})
+			return
+		}
+		all = append(append(all, f), descendants...)
+	}
+
+	summaries, ok := summarize(c, ctx, uploads, all)
+	if !ok {
+		return
+	}
+	roots := []folderSummary{}
+	for _, s := range summaries {
+		if role, ok := roles[s.ID.Hex()]; ok {
+			s.Role = role
+			roots = append(roots, s)
+		}
+	}
+	c.JSON(200, gin.H{"folders": roots})
+}
+
+// insideShared reports whether one of the folder's ancestors is shared with
+// the user as well.
+func insideShared(f *model.Folder, sharedIDs map[string]bool) bool {
+	for _, id := range strings.Split(strings.Trim(f.AncestorPath(), "/"), "/") {
+		if sharedIDs[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// sharingCollections returns the folder_invites and folders collections.
+// It writes the error response itself.
+func sharingCollections(c *gin.Context) (*mongo.Collection, *mongo.Collection, bool) {
+	invites, err := DB.GetCollection("folder_invites")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return nil, nil, false
+	}
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return nil, nil, false
+	}
+	return invites, folders, true
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	DB "lyked-backend/internal/database/mongodb"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/search/query"
+	"lyked-backend/internal/trash"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type smartFolderRequest struct {
+	Name     string             `json:"name"`
+	Filter   *model.SmartFilter `json:"filter"`
+	Pinned   bool               `json:"pinned"`
+	ParentID string             `json:"parent_id"`
+}
+
+// CreateSmartFolderHandler creates a smart folder, at the top level or
+// under parent_id. Under a shared folder it belongs to, and matches items
+// of, the folder's owner.
+func CreateSmartFolderHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+
+	var req smartFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid folder data"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(400, gin.H{"error": "Folder name is required"})
+		return
+	}
+	if !validSmartFilter(c, req.Filter) {
+		return
+	}
+
+	collection, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	owner, ok := folderOwner(c, ctx, collection, userID.(string), req.ParentID)
+	if !ok || !resolveFilterTags(c, ctx, req.Filter, owner) {
+		return
+	}
+	folder := model.Folder{
+		ID:        bson.NewObjectID(),
+		UserID:    owner,
+		Name:      req.Name,
+		Kind:      model.FolderKindSmart,
+		Filter:    req.Filter,
+		Pinned:    req.Pinned,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := library.CreateFolder(ctx, collection, &folder, req.ParentID); err != nil {
+		folderError(c, err, "Failed to create folder")
+		return
+	}
+	c.JSON(201, gin.H{"message": "Smart folder created", "folder": folder})
+}
+
+// UpdateSmartFolderHandler renames a smart folder and/or replaces its filter.
+func UpdateSmartFolderHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+
+	var req smartFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid folder data"})
+		return
+	}
+	set := bson.M{}
+	if name := strings.TrimSpace(req.Name); name != "" {
+		set["name"] = name
+	}
+	if req.Filter != nil {
+		if !validSmartFilter(c, req.Filter) {
+			return
+		}
+		set["filter"] = req.Filter
+	}
+	if len(set) == 0 {
+		c.JSON(400, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	collection, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	folder, ok := smartFolder(c, ctx, collection, userID.(string), model.RoleEditor)
+	if !ok {
+		return
+	}
+	if req.Filter != nil && !resolveFilterTags(c, ctx, req.Filter, folder.UserID) {
+		return
+	}
+	res, err := collection.UpdateOne(ctx,
+		bson.M{"_id": folder.ID, "kind": model.FolderKindSmart, "deleted_at": nil},
+		bson.M{"$set": set})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to update folder"})
+		return
+	}
+	if res.MatchedCount == 0 {
+		c.JSON(404, gin.H{"error": "Smart folder not found"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Smart folder updated"})
+}
+
+// DeleteSmartFolderHandler moves the folder to the trash; items are untouched.
+func DeleteSmartFolderHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	collection, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	folder, ok := smartFolder(c, ctx, collection, userID.(string), model.RoleOwner)
+	if !ok {
+		return
+	}
+	if _, err := trash.TrashFolder(ctx, folder.UserID, folder.ID, time.Now().UTC()); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to delete folder"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Smart folder moved to trash"})
+}
+
+// smartFolder finds the smart folder in the :id param that the user holds
+// at least the min role on. It writes the error response itself.
+func smartFolder(c *gin.Context, ctx context.Context, folders *mongo.Collection, userID, min string) (*model.Folder, bool) {
+	folder, _, err := library.AccessFolder(ctx, folders, userID, c.Param("id"), min)
+	if errors.Is(err, library.ErrFolderNotFound) || (err == nil && !folder.IsSmart()) {
+		c.JSON(404, gin.H{"error": "Smart folder not found"})
+		return nil, false
+	}
+	if err != nil {
+		folderError(c, err, "Failed to fetch folder")
+		return nil, false
+	}
+	return folder, true
+}
+
+// resolveFilterTags resolves the filter's tags through the aliases of the
+// folder's owner, whose items it matches. It writes the 500 response itself.
+func resolveFilterTags(c *gin.Context, ctx context.Context, f *model.SmartFilter, owner string) bool {
+	aliases, err := library.LoadTagAliases(ctx, owner)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to resolve tag aliases"})
+		return false
+	}
+	f.Tags = aliases.ResolveAll(f.Tags)
+	f.ExcludeTags = aliases.ResolveAll(f.ExcludeTags)
+	return true
+}
+
+// validSmartFilter compiles the filter once so bad definitions are rejected
+// up front. It writes the 400 response itself.
+func validSmartFilter(c *gin.Context, f *model.SmartFilter) bool {
+	if f == nil {
+		c.JSON(400, gin.H{"error": "Smart folder filter is required"})
+		return false
+	}
+	_, err := library.SmartFolderFilter(f, time.Now())
+	var syntaxErr *query.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		c.JSON(400, gin.H{"error": "Invalid search query", "details": syntaxErr.Msg, "position": syntaxErr.Pos})
+		return false
+	}
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid smart folder filter", "details": err.Error()})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	DB "lyked-backend/internal/database/mongodb"
+	"lyked-backend/internal/importer"
+	model "lyked-backend/internal/models/mongodb"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const maxImportFileSize = 50 << 20 // 50 MB
+
+// StartImportHandler accepts a multipart upload with the export "file" and
+// its "source" format. The file is parsed up front so format errors come
+// back immediately; saving the items runs as a background job. With
+// dry_run=true nothing is saved and a preview of the outcome is returned.
+func StartImportHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize)
+	source := c.PostForm("source")
+	if source == "" {
+		c.JSON(400, gin.H{"error": "source is required", "sources": importer.Sources()})
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(400, gin.H{"error": "An export file is required (max 50 MB)"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Failed to read export file"})
+		return
+	}
+	defer file.Close()
+
+	items, rowErrors, err := importer.Parse(source, file)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Failed to parse export file", "details": err.Error(), "sources": importer.Sources()})
+		return
+	}
+	// An explicit folder name files everything from this export, e.g. a
+	// YouTube playlist whose name only appears in the file name.
+	if folder := strings.TrimSpace(c.PostForm("folder")); folder != "" {
+		for i := range items {
+			items[i].Folder, items[i].Parents = folder, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if dryRun, _ := strconv.ParseBool(c.PostForm("dry_run")); dryRun {
+		preview, err := importer.Preview(ctx, userID.(string), items, rowErrors)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to preview import"})
+			return
+		}
+		c.JSON(200, gin.H{"source": source, "preview": preview})
+		return
+	}
+
+	job := &model.ImportJob{UserID: userID.(string), Source: source, FileName: fileHeader.Filename}
+	if err := importer.Start(ctx, job, items, rowErrors); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to start import"})
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Import started", "job": job})
+}
+
+// ListImportsHandler returns the user's import jobs, newest first, without
+// their error reports.
+func ListImportsHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	collection, err := DB.GetCollection("import_jobs")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID}, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetProjection(bson.M{"errors": 0}).
+		SetLimit(50))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch imports"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	jobs := []model.ImportJob{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to parse imports"})
+		return
+	}
+	c.JSON(200, gin.H{"imports": jobs})
+}
+
+// GetImportHandler returns a job's progress and its per-row error report.
+func GetImportHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid import ID"})
+		return
+	}
+	collection, err := DB.GetCollection("import_jobs")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var job model.ImportJob
+	err = collection.FindOne(ctx, bson.M{"_id": id, "user_id": userID}).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(404, gin.H{"error": "Import not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch import"})
+		return
+	}
+
+	progress := 0.0
+	if job.Total > 0 {
+		progress = float64(job.Processed) / float64(job.Total)
+	}
+	c.JSON(200, gin.H{"import": job, "progress": progress})
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	DB "lyked-backend/internal/database/mongodb"
+	"lyked-backend/internal/library"
+	"lyked-backend/internal/linkcheck"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/search/query"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type brokenCount struct {
+	Status   string `bson:"status" json:"status"`
+	Platform string `bson:"platform" json:"platform"`
+	Count    int    `bson:"count" json:"count"`
+}
+
+// BrokenLinksHandler reports the user's dead links: counts per status and
+// platform, plus a page of the affected uploads. Accepts the GET
+// /upload/all params; link_status narrows the report to some statuses.
+func BrokenLinksHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+
+	params := c.Request.URL.Query()
+	if len(params["link_status"]) == 0 {
+		params.Set("link_status", library.LinkStatusBroken)
+	}
+	opts, err := library.ParseListParams(params)
+	var syntaxErr *query.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		c.JSON(400, gin.H{"error": "Invalid search query", "details": syntaxErr.Msg, "position": syntaxErr.Pos})
+		return
+	}
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	for _, s := range opts.LinkStatuses {
+		if !linkcheck.IsBroken(s) {
+			c.JSON(400, gin.H{"error": "link_status must be removed, private, region_blocked or broken"})
+			return
+		}
+	}
+	opts.UserID = userID.(string)
+
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := uploads.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: opts.Filter()}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"status": "$link_status", "platform": "$platform"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$project", Value: bson.M{"_id": 0, "status": "$_id.status", "platform": "$_id.platform", "count": 1}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}}}},
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to count broken links"})
+		return
+	}
+	counts := []brokenCount{}
+	if err := cursor.All(ctx, &counts); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to parse broken link counts"})
+		return
+	}
+	total := 0
+	for _, n := range counts {
+		total += n.Count
+	}
+
+	page, err := library.List(ctx, uploads, opts)
+	if errors.Is(err, library.ErrInvalidCursor) {
+		c.JSON(400, gin.H{"error": "Invalid cursor"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch broken links"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"total":       total,
+		"counts":      counts,
+		"uploads":     page.Uploads,
+		"next_cursor": page.NextCursor,
+		"has_more":    page.HasMore,
+	})
+}
+
+// CheckLinkHandler probes one upload's link right away instead of waiting
+// for the background sweep.
+func CheckLinkHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid upload ID"})
+		return
+	}
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var upload model.LykedUploads
+	err = uploads.FindOne(ctx, bson.M{"_id": id, "user_id": userID, "deleted_at": nil}).Decode(&upload)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(404, gin.H{"error": "Upload not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch upload"})
+		return
+	}
+
+	result := linkcheck.Default.Check(ctx, upload.VideoLink)
+	if err := linkcheck.Record(ctx, upload.ID, result); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to save link status"})
+		return
+	}
+	c.JSON(200, gin.H{"upload_id": upload.ID.Hex(), "link": result})
+}
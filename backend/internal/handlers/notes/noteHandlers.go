@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	DB "lyked-backend/internal/database/mongodb"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/notes"
+	"lyked-backend/internal/search"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type noteRequest struct {
+	Body string `json:"body"`
+}
+
+type annotationRequest struct {
+	At   *notes.Timestamp `json:"at"`
+	Text *string          `json:"text"`
+}
+
+// ListNotesHandler returns an upload's notes and annotations.
+func ListNotesHandler(c *gin.Context) {
+	userID, uploadID, ok := noteParams(c)
+	if !ok {
+		return
+	}
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var upload model.LykedUploads
+	err = uploads.FindOne(ctx, bson.M{"_id": uploadID, "user_id": userID, "deleted_at": nil}).Decode(&upload)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(404, gin.H{"error": "Upload not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch upload"})
+		return
+	}
+	if upload.Notes == nil {
+		upload.Notes = []model.Note{}
+	}
+	if upload.Annotations == nil {
+		upload.Annotations = []model.Annotation{}
+	}
+	c.JSON(200, gin.H{"notes": upload.Notes, "annotations": upload.Annotations})
+}
+
+func CreateNoteHandler(c *gin.Context) {
+	userID, uploadID, ok := noteParams(c)
+	if !ok {
+		return
+	}
+	var req noteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body"})
+		return
+	}
+	uploads, ctx, cancel, ok := uploadsCollection(c)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	upload, note, err := notes.AddNote(ctx, uploads, userID, uploadID, req.Body, time.Now().UTC())
+	if respondError(c, err) {
+		return
+	}
+	reindex(ctx, upload)
+	c.JSON(201, gin.H{"note": note})
+}
+
+func UpdateNoteHandler(c *gin.Context) {
+	userID, uploadID, ok := noteParams(c)
+	if !ok {
+		return
+	}
+	var req noteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body"})
+		return
+	}
+	uploads, ctx, cancel, ok := uploadsCollection(c)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	upload, note, err := notes.UpdateNote(ctx, uploads, userID, uploadID, c.Param("noteId"), req.Body, time.Now().UTC())
+	if respondError(c, err) {
+		return
+	}
+	reindex(ctx, upload)
+	c.JSON(200, gin.H{"note": note})
+}
+
+func DeleteNoteHandler(c *gin.Context) {
+	userID, uploadID, ok := noteParams(c)
+	if !ok {
+		return
+	}
+	uploads, ctx, cancel, ok := uploadsCollection(c)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	upload, err := notes.DeleteNote(ctx, uploads, userID, uploadID, c.Param("noteId"))
+	if respondError(c, err) {
+		return
+	}
+	reindex(ctx, upload)
+	c.JSON(200, gin.H{"message": "Note deleted"})
+}
+
+// CreateAnnotationHandler pins a note to a moment in the video; "at" is
+// seconds or a clock string such as "2:31".
+func CreateAnnotationHandler(c *gin.Context) {
+	userID, uploadID, ok := noteParams(c)
+	if !ok {
+		return
+	}
+	var req annotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if req.At == nil || req.Text == nil {
+		c.JSON(400, gin.H{"error": "Both at and text are required"})
+		return
+	}
+	uploads, ctx, cancel, ok := uploadsCollection(c)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	upload, annotation, err := notes.AddAnnotation(ctx, uploads, userID, uploadID, float64(*req.At), *req.Text, time.Now().UTC())
+	if respondError(c, err) {
+		return
+	}
+	reindex(ctx, upload)
+	c.JSON(201, gin.H{"annotation": annotation})
+}
+
+func UpdateAnnotationHandler(c *gin.Context) {
+	userID, uploadID, ok := noteParams(c)
+	if !ok {
+		return
+	}
+	var req annotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if req.At == nil && req.Text == nil {
+		c.JSON(400, gin.H{"error": "Nothing to update"})
+		return
+	}
+	var at *float64
+	if req.At != nil {
+		seconds := float64(*req.At)
+		at = &seconds
+	}
+	uploads, ctx, cancel, ok := uploadsCollection(c)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	upload, annotation, err := notes.UpdateAnnotation(ctx, uploads, userID, uploadID, c.Param("annotationId"), at, req.Text, time.Now().UTC())
+	if respondError(c, err) {
+		return
+	}
+	reindex(ctx, upload)
+	c.JSON(200, gin.H{"annotation": annotation})
+}
+
+func DeleteAnnotationHandler(c *gin.Context) {
+	userID, uploadID, ok := noteParams(c)
+	if !ok {
+		return
+	}
+	uploads, ctx, cancel, ok := uploadsCollection(c)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	upload, err := notes.DeleteAnnotation(ctx, uploads, userID, uploadID, c.Param("annotationId"))
+	if respondError(c, err) {
+		return
+	}
+	reindex(ctx, upload)
+	c.JSON(200, gin.H{"message": "Annotation deleted"})
+}
+
+func noteParams(c *gin.Context) (string, bson.ObjectID, bool) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return "", bson.NilObjectID, false
+	}
+	uploadID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid upload ID"})
+		return "", bson.NilObjectID, false
+	}
+	return userID.(string), uploadID, true
+}
+
+func uploadsCollection(c *gin.Context) (*mongo.Collection, context.Context, context.CancelFunc, bool) {
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return nil, nil, nil, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	return uploads, ctx, cancel, true
+}
+
+// respondError writes the response for a notes error and reports whether
+// there was one.
+func respondError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, notes.ErrUploadNotFound):
+		c.JSON(404, gin.H{"error": "Upload not found"})
+	case errors.Is(err, notes.ErrNoteNotFound):
+		c.JSON(404, gin.H{"error": "Note not found"})
+	case errors.Is(err, notes.ErrEmpty):
+		c.JSON(400, gin.H{"error": "Text is required"})
+	case errors.Is(err, notes.ErrTooLong):
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Text is too long (notes up to %d characters, annotations up to %d)", notes.MaxNoteLength, notes.MaxAnnotationLength)})
+	case errors.Is(err, notes.ErrTooMany):
+		c.JSON(400, gin.H{"error": fmt.Sprintf("An item can have at most %d notes and %d annotations", notes.MaxNotes, notes.MaxAnnotations)})
+	case errors.Is(err, notes.ErrInvalidTimestamp):
+		c.JSON(400, gin.H{"error": err.Error()})
+	default:
+		c.JSON(500, gin.H{"error": "Failed to update notes"})
+	}
+	return true
+}
+
+func reindex(ctx context.Context, upload *model.LykedUploads) {
+	if err := search.Default.Index(ctx, search.DocumentFromUpload(*upload)); err != nil {
+		fmt.Printf("Failed to index upload %s: %v\n", upload.ID.Hex(), err)
+	}
+}
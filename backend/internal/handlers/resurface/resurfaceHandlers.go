@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	DB "lyked-backend/internal/database/mongodb"
+	"lyked-backend/internal/resurface"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const (
+	defaultSnoozeDays = 7
+	maxSnoozeDays     = 365
+)
+
+type snoozeRequest struct {
+	Days int `json:"days"`
+}
+
+// FeedHandler returns the day's resurfaced items. The feed is seeded from
+// the user and date, so it is stable for the day; "date" and "seed" can be
+// given to reproduce a feed.
+func FeedHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if raw := c.Query("date"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "date must be YYYY-MM-DD"})
+			return
+		}
+		day = parsed
+	}
+	size := resurface.DefaultSize
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > resurface.MaxSize {
+			c.JSON(400, gin.H{"error": "limit must be between 1 and 50"})
+			return
+		}
+		size = n
+	}
+	seed := resurface.Seed(userID.(string), day)
+	if raw := c.Query("seed"); raw != "" {
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "seed must be an unsigned integer"})
+			return
+		}
+		seed = n
+	}
+
+	uploads, feedback, ok := collections(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	items, err := resurface.Feed(ctx, uploads, feedback, userID.(string), day, seed, size)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to build resurfacing feed"})
+		return
+	}
+	c.JSON(200, gin.H{
+		"date":  day.Format("2006-01-02"),
+		"seed":  strconv.FormatUint(seed, 10),
+		"items": items,
+	})
+}
+
+// SnoozeHandler keeps an item out of the feed for "days" days (default 7).
+func SnoozeHandler(c *gin.Context) {
+	userID, id, ok := feedbackParams(c)
+	if !ok {
+		return
+	}
+	var req snoozeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+	if req.Days == 0 {
+		req.Days = defaultSnoozeDays
+	}
+	if req.Days < 1 || req.Days > maxSnoozeDays {
+		c.JSON(400, gin.H{"error": "days must be between 1 and 365"})
+		return
+	}
+	uploads, feedback, ok := collections(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	entry, err := resurface.Snooze(ctx, uploads, feedback, userID, id, now.AddDate(0, 0, req.Days), now)
+	if errors.Is(err, resurface.ErrUploadNotFound) {
+		c.JSON(404, gin.H{"error": "Upload not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to snooze item"})
+		return
+	}
+	c.JSON(200, gin.H{"feedback": entry})
+}
+
+// DismissHandler keeps an item out of the feed for good.
+func DismissHandler(c *gin.Context) {
+	userID, id, ok := feedbackParams(c)
+	if !ok {
+		return
+	}
+	uploads, feedback, ok := collections(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	entry, err := resurface.Dismiss(ctx, uploads, feedback, userID, id, time.Now().UTC())
+	if errors.Is(err, resurface.ErrUploadNotFound) {
+		c.JSON(404, gin.H{"error": "Upload not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to dismiss item"})
+		return
+	}
+	c.JSON(200, gin.H{"feedback": entry})
+}
+
+// ClearFeedbackHandler undoes a snooze or dismissal.
+func ClearFeedbackHandler(c *gin.Context) {
+	userID, id, ok := feedbackParams(c)
+	if !ok {
+		return
+	}
+	_, feedback, ok := collections(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cleared, err := resurface.Clear(ctx, feedback, userID, id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to clear feedback"})
+		return
+	}
+	if !cleared {
+		c.JSON(404, gin.H{"error": "Item was not snoozed or dismissed"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Item can resurface again"})
+}
+
+func feedbackParams(c *gin.Context) (string, bson.ObjectID, bool) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return "", bson.NilObjectID, false
+	}
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid upload ID"})
+		return "", bson.NilObjectID, false
+	}
+	return userID.(string), id, true
+}
+
+func collections(c *gin.Context) (*mongo.Collection, *mongo.Collection, bool) {
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return nil, nil, false
+	}
+	feedback, err := DB.GetCollection("resurface_feedback")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return nil, nil, false
+	}
+	return uploads, feedback, true
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	DB "lyked-backend/internal/database/mongodb"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/review"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type settingsRequest struct {
+	Enabled   bool     `json:"enabled"`
+	Folders   []string `json:"folders"`
+	Tags      []string `json:"tags"`
+	NewPerDay *int     `json:"new_per_day"`
+}
+
+type gradeRequest struct {
+	Grade *int `json:"grade" binding:"required"`
+}
+
+// GetSettingsHandler returns whether review mode is on and what it covers.
+func GetSettingsHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	store, ok := newStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	settings, err := store.GetSettings(ctx, userID.(string))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch review settings"})
+		return
+	}
+	c.JSON(200, gin.H{"settings": settings})
+}
+
+// UpdateSettingsHandler turns review mode on or off and sets the folders
+// and tags it covers. Schedules are kept when items leave the scope, so
+// adding them back resumes where they were.
+func UpdateSettingsHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var req settingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body"})
+		return
+	}
+	settings := &model.ReviewSettings{
+		UserID:    userID.(string),
+		Enabled:   req.Enabled,
+		Folders:   unique(req.Folders),
+		Tags:      library.NormalizeTags(req.Tags),
+		NewPerDay: review.DefaultNewPerDay,
+		UpdatedAt: time.Now().UTC(),
+	}
+	if req.NewPerDay != nil {
+		settings.NewPerDay = *req.NewPerDay
+	}
+	if settings.NewPerDay < 0 || settings.NewPerDay > review.MaxNewPerDay {
+		c.JSON(400, gin.H{"error": "new_per_day must be between 0 and 100"})
+		return
+	}
+	if settings.Enabled && len(settings.Folders) == 0 && len(settings.Tags) == 0 {
+		c.JSON(400, gin.H{"error": "Choose at least one folder or tag to review"})
+		return
+	}
+
+	store, ok := newStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tags, err := library.ResolveTags(ctx, settings.UserID, settings.Tags)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to resolve tag aliases"})
+		return
+	}
+	settings.Tags = tags
+	err = store.SaveSettings(ctx, settings)
+	if errors.Is(err, review.ErrFolderNotFound) {
+		c.JSON(404, gin.H{"error": "Folder not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to save review settings"})
+		return
+	}
+	c.JSON(200, gin.H{"settings": settings})
+}
+
+// DueHandler returns today's reviews: scheduled items that are due and
+// the day's allowance of new items.
+func DueHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	store, ok := newStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	items, err := store.Due(ctx, userID.(string), time.Now().UTC())
+	if errors.Is(err, review.ErrNotEnabled) {
+		c.JSON(409, gin.H{"error": "Review mode is not enabled"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch due items"})
+		return
+	}
+	c.JSON(200, gin.H{"items": items, "count": len(items)})
+}
+
+// GradeHandler records how well an item was recalled, from 0 (blackout)
+// to 5 (perfect), and returns its new schedule.
+func GradeHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid upload ID"})
+		return
+	}
+	var req gradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if *req.Grade < review.MinGrade || *req.Grade > review.MaxGrade {
+		c.JSON(400, gin.H{"error": "grade must be between 0 and 5"})
+		return
+	}
+	store, ok := newStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	card, err := store.Grade(ctx, userID.(string), id, *req.Grade, time.Now().UTC())
+	switch {
+	case errors.Is(err, review.ErrNotEnabled):
+		c.JSON(409, gin.H{"error": "Review mode is not enabled"})
+	case errors.Is(err, review.ErrNotInReview):
+		c.JSON(404, gin.H{"error": "Item is not in review"})
+	case err != nil:
+		c.JSON(500, gin.H{"error": "Failed to grade item"})
+	default:
+		c.JSON(200, gin.H{"card": card})
+	}
+}
+
+// StatsHandler returns review counts, streak and retention.
+func StatsHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	store, ok := newStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stats, err := store.Stats(ctx, userID.(string), time.Now().UTC())
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to compute review stats"})
+		return
+	}
+	c.JSON(200, gin.H{"stats": stats})
+}
+
+func newStore(c *gin.Context) (*review.Store, bool) {
+	store := &review.Store{}
+	for name, dst := range map[string]**mongo.Collection{
+		"uploads":         &store.Uploads,
+		"folders":         &store.Folders,
+		"review_settings": &store.Settings,
+		"review_cards":    &store.Cards,
+		"review_log":      &store.Log,
+	} {
+		collection, err := DB.GetCollection(name)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Database connection error"})
+			return nil, false
+		}
+		*dst = collection
+	}
+	return store, true
+}
+
+func unique(values []string) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v != "" && !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
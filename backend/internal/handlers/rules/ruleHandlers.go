@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	DB "lyked-backend/internal/database/mongodb"
+	"lyked-backend/internal/library"
+	"lyked-backend/internal/rules"
+	"lyked-backend/internal/search"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ListRulesHandler returns the user's rules in the order they run.
+func ListRulesHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	store, ok := newStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	list, err := store.List(ctx, userID.(string))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch rules"})
+		return
+	}
+	c.JSON(200, gin.H{"rules": list})
+}
+
+// CreateRuleHandler adds a rule that runs on every item saved from now on:
+// {"name", "match": "all"|"any", "conditions": [{"type", "value", "field"}],
+// "actions": {"add_tags", "folder_id", "status"}}.
+func CreateRuleHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var req rules.RuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid rule data"})
+		return
+	}
+	store, ok := newStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rule, err := store.Create(ctx, userID.(string), req, time.Now().UTC())
+	if err != nil {
+		ruleError(c, err, "Failed to create rule")
+		return
+	}
+	c.JSON(201, gin.H{"message": "Rule created", "rule": rule})
+}
+
+// UpdateRuleHandler replaces a rule's name, conditions and actions, and
+// turns it on or off.
+func UpdateRuleHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var req rules.RuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid rule data"})
+		return
+	}
+	store, ok := newStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rule, err := store.Update(ctx, userID.(string), c.Param("id"), req, time.Now().UTC())
+	if err != nil {
+		ruleError(c, err, "Failed to update rule")
+		return
+	}
+	c.JSON(200, gin.H{"message": "Rule updated", "rule": rule})
+}
+
+// DeleteRuleHandler removes a rule. Items it already tagged or filed keep
+// their tags and folders.
+func DeleteRuleHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	store, ok := newStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := store.Delete(ctx, userID.(string), c.Param("id")); err != nil {
+		ruleError(c, err, "Failed to delete rule")
+		return
+	}
+	c.JSON(200, gin.H{"message": "Rule deleted"})
+}
+
+// TestRuleHandler previews a rule without saving it or changing anything:
+// it takes the same body as creating one, name and actions optional, and
+// returns how many items match and what running it would change. ?limit=
+// caps how many matches are listed.
+func TestRuleHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var req rules.RuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid rule data"})
+		return
+	}
+	limit := rules.DefaultPreviewLimit
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 {
+			c.JSON(400, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+	}
+	store, ok := newStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	preview, err := store.Test(ctx, userID.(string), req, limit)
+	if err != nil {
+		ruleError(c, err, "Failed to test rule")
+		return
+	}
+	c.JSON(200, gin.H{"preview": preview})
+}
+
+// RunRulesHandler applies every enabled rule to the items saved before it
+// existed.
+func RunRulesHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	store, ok := newStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	results, changed, err := store.RunAll(ctx, userID.(string), time.Now().UTC())
+	if err != nil {
+		ruleError(c, err, "Failed to run rules")
+		return
+	}
+	reindex(ctx, userID.(string), changed)
+	c.JSON(200, gin.H{"results": results, "updated": len(changed)})
+}
+
+// RunRuleHandler applies one rule to the items already saved, whether or
+// not it is enabled.
+func RunRuleHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	store, ok := newStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	result, changed, err := store.RunOne(ctx, userID.(string), c.Param("id"), time.Now().UTC())
+	if err != nil {
+		ruleError(c, err, "Failed to run rule")
+		return
+	}
+	reindex(ctx, userID.(string), changed)
+	c.JSON(200, gin.H{"result": result})
+}
+
+func ruleError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, rules.ErrRuleNotFound), errors.Is(err, library.ErrFolderNotFound):
+		c.JSON(404, gin.H{"error": err.Error()})
+	case errors.Is(err, library.ErrForbidden):
+		c.JSON(403, gin.H{"error": err.Error()})
+	case errors.Is(err, rules.ErrInvalidRule), errors.Is(err, rules.ErrTooManyRules),
+		errors.Is(err, library.ErrSmartFolder):
+		c.JSON(400, gin.H{"error": err.Error()})
+	default:
+		c.JSON(500, gin.H{"error": fallback})
+	}
+}
+
+// reindex puts items the rules changed back into the search index. The
+// changes are already saved, so a failure is only logged.
+func reindex(ctx context.Context, userID string, ids []bson.ObjectID) {
+	if err := search.Reindex(ctx, userID, ids); err != nil {
+		fmt.Printf("Failed to reindex uploads changed by rules: %v\n", err)
+	}
+}
+
+// newStore wires the rule store to its collections. It writes the error
+// response itself.
+func newStore(c *gin.Context) (*rules.Store, bool) {
+	store := &rules.Store{}
+	for name, dst := range map[string]**mongo.Collection{
+		"rules":   &store.Rules,
+		"uploads": &store.Uploads,
+		"folders": &store.Folders,
+	} {
+		collection, err := DB.GetCollection(name)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Database connection error"})
+			return nil, false
+		}
+		*dst = collection
+	}
+	return store, true
+}
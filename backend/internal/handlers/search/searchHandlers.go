@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	DB "lyked-backend/internal/database/mongodb"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/search"
+	"lyked-backend/internal/search/query"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type searchResult struct {
+	Upload     model.LykedUploads `json:"upload"`
+	Score      float64            `json:"score"`
+	Highlights map[string]string  `json:"highlights,omitempty"`
+}
+
+func SearchHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	if search.Default == nil {
+		c.JSON(500, gin.H{"error": "Search is not available"})
+		return
+	}
+
+	parsed, err := query.Parse(c.Query("q"))
+	var syntaxErr *query.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		c.JSON(400, gin.H{"error": "Invalid search query", "details": syntaxErr.Msg, "position": syntaxErr.Pos})
+		return
+	}
+
+	q := search.Query{
+		UserID:   userID.(string),
+		Text:     c.Query("q"),
+		Tags:     c.QueryArray("tag"),
+		Platform: c.Query("platform"),
+		Folder:   c.Query("folder"),
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			c.JSON(400, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		q.Limit = n
+	}
+	if offset := c.Query("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			c.JSON(400, gin.H{"error": "offset must be a non-negative integer"})
+			return
+		}
+		q.Offset = n
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	aliases, err := library.LoadTagAliases(ctx, q.UserID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Search failed"})
+		return
+	}
+	q.Tags = aliases.ResolveAll(q.Tags)
+	parsed = query.MapField(parsed, "tag", aliases.Resolve)
+
+	// Plain words go straight to the index. Anything using operators,
+	// phrases or OR is evaluated in Mongo first and the index only ranks
+	// the matching items by their free-text part.
+	if !query.IsPlainText(parsed) {
+		filter, err := query.Compile(parsed, time.Now())
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid search query", "details": err.Error()})
+			return
+		}
+		ids, err := matchingIDs(ctx, q.UserID, filter)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Search failed"})
+			return
+		}
+		q.Text = query.FreeText(parsed)
+		q.IDs = ids
+	}
+
+	result, err := search.Default.Search(ctx, q)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Search failed"})
+		return
+	}
+
+	results, err := loadHits(ctx, q.UserID, result.Hits)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch search results"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"results": results,
+		"total":   result.Total,
+		"facets":  result.Facets,
+	})
+}
+
+// matchingIDs returns the ids of the user's uploads matching a compiled query.
+func matchingIDs(ctx context.Context, userID string, filter bson.M) ([]string, error) {
+	collection, err := DB.GetCollection("uploads")
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := collection.Find(ctx,
+		bson.M{"$and": bson.A{bson.M{"user_id": userID, "deleted_at": nil}, filter}},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(docs))
+	for _, d := range docs {
+		ids = append(ids, d.ID.Hex())
+	}
+	return ids, nil
+}
+
+// loadHits fetches the uploads behind a page of hits, keeping relevance order.
+func loadHits(ctx context.Context, userID string, hits []search.Hit) ([]searchResult, error) {
+	results := []searchResult{}
+	if len(hits) == 0 {
+		return results, nil
+	}
+
+	ids := make([]bson.ObjectID, 0, len(hits))
+	for _, h := range hits {
+		if id, err := bson.ObjectIDFromHex(h.ID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	collection, err := DB.GetCollection("uploads")
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID, "deleted_at": nil, "_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var uploads []model.LykedUploads
+	if err := cursor.All(ctx, &uploads); err != nil {
+		return nil, err
+	}
+	byID := make(map[string]model.LykedUploads, len(uploads))
+	for _, u := range uploads {
+		byID[u.ID.Hex()] = u
+	}
+
+	for _, h := range hits {
+		if u, ok := byID[h.ID]; ok {
+			results = append(results, searchResult{Upload: u, Score: h.Score, Highlights: h.Highlights})
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	DB "lyked-backend/internal/database/mongodb"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/sharelink"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// passwordHeader carries the password of a protected link, so it stays out
+// of URLs and server logs.
+const passwordHeader = "X-Share-Password"
+
+// CreateShareLinkHandler makes a public link to a folder or an item:
+// {"folder_id" | "upload_id", "password", "expires_at"}.
+func CreateShareLinkHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var req sharelink.CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid share link data"})
+		return
+	}
+	store, ok := newStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	link, err := store.Create(ctx, userID.(string), req, time.Now().UTC())
+	if err != nil {
+		shareLinkError(c, err, "Failed to create share link")
+		return
+	}
+	c.JSON(201, gin.H{"message": "Share link created", "link": link})
+}
+
+// ListShareLinksHandler returns the user's links with their view counts.
+// ?folder_id= lists every link to a folder the user owns; ?upload_id= the
+// user's links to one item.
+func ListShareLinksHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	store, ok := newStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	links, err := store.List(ctx, userID.(string), c.Query("folder_id"), c.Query("upload_id"))
+	if err != nil {
+		shareLinkError(c, err, "Failed to fetch share links")
+		return
+	}
+	c.JSON(200, gin.H{"links": links})
+}
+
+// RevokeShareLinkHandler turns a link off. It keeps its view count.
+func RevokeShareLinkHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	store, ok := newStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	link, err := store.Revoke(ctx, userID.(string), c.Param("id"), time.Now().UTC())
+	if err != nil {
+		shareLinkError(c, err, "Failed to revoke share link")
+		return
+	}
+	c.JSON(200, gin.H{"message": "Share link revoked", "link": link})
+}
+
+// PublicShareHandler serves a link to anyone holding its token. Folders
+// take ?folder=<subfolder id> to browse below the shared folder, plus
+// cursor and limit. Opening a link, not paging through it, counts as a
+// view.
+func PublicShareHandler(c *gin.Context) {
+	store, ok := newStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	link, err := store.Open(ctx, c.Param("token"), c.GetHeader(passwordHeader), c.ClientIP(), now)
+	if err != nil {
+		shareLinkError(c, err, "Failed to open share link")
+		return
+	}
+
+	resp := gin.H{"kind": link.Kind}
+	switch link.Kind {
+	case model.ShareKindUpload:
+		item, err := store.Item(ctx, link)
+		if err != nil {
+			shareLinkError(c, err, "Failed to fetch item")
+			return
+		}
+		resp["item"] = item
+	case model.ShareKindFolder:
+		limit := library.DefaultLimit
+		if raw := c.Query("limit"); raw != "" {
+			if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 {
+				c.JSON(400, gin.H{"error": "limit must be a positive number"})
+				return
+			}
+		}
+		view, err := store.Folder(ctx, link, c.Query("folder"), c.Query("cursor"), limit)
+		if err != nil {
+			shareLinkError(c, err, "Failed to fetch folder")
+			return
+		}
+		resp["folder"] = view
+	default:
+		c.JSON(404, gin.H{"error": sharelink.ErrLinkNotFound.Error()})
+		return
+	}
+
+	if c.Query("cursor") == "" && c.Query("folder") == "" {
+		if err := store.RecordView(ctx, link, now); err != nil {
+			c.JSON(500, gin.H{"error": "Failed to open share link"})
+			return
+		}
+	}
+	if link.ExpiresAt != nil {
+		resp["expires_at"] = link.ExpiresAt
+	}
+	c.JSON(200, resp)
+}
+
+func shareLinkError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, sharelink.ErrLinkNotFound), errors.Is(err, sharelink.ErrUploadNotFound),
+		errors.Is(err, library.ErrFolderNotFound):
+		c.JSON(404, gin.H{"error": err.Error()})
+	case errors.Is(err, sharelink.ErrLinkGone):
+		c.JSON(410, gin.H{"error": err.Error()})
+	case errors.Is(err, sharelink.ErrPasswordRequired), errors.Is(err, sharelink.ErrWrongPassword):
+		c.JSON(401, gin.H{"error": err.Error(), "password_required": true})
+	case errors.Is(err, sharelink.ErrTooManyAttempts):
+		c.JSON(429, gin.H{"error": err.Error()})
+	case errors.Is(err, library.ErrForbidden):
+		c.JSON(403, gin.H{"error": err.Error()})
+	case errors.Is(err, sharelink.ErrNoTarget), errors.Is(err, sharelink.ErrExpiryPast),
+		errors.Is(err, library.ErrShareSmart), errors.Is(err, library.ErrInvalidCursor):
+		c.JSON(400, gin.H{"error": err.Error()})
+	default:
+		c.JSON(500, gin.H{"error": fallback})
+	}
+}
+
+// newStore wires the share link store to its collections. It writes the
+// error response itself.
+func newStore(c *gin.Context) (*sharelink.Store, bool) {
+	store := &sharelink.Store{}
+	for name, dst := range map[string]**mongo.Collection{
+		"share_links": &store.Links,
+		"uploads":     &store.Uploads,
+		"folders":     &store.Folders,
+	} {
+		collection, err := DB.GetCollection(name)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Database connection error"})
+			return nil, false
+		}
+		*dst = collection
+	}
+	return store, true
+}
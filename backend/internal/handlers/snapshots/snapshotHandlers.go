@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lyked-backend/internal/archive"
+	DB "lyked-backend/internal/database/mongodb"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/warc"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type createSnapshotRequest struct {
+	UploadID string `json:"upload_id" binding:"required"`
+}
+
+// CreateSnapshotHandler queues a WARC capture of an upload's page.
+func CreateSnapshotHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var req createSnapshotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body"})
+		return
+	}
+	id, err := bson.ObjectIDFromHex(req.UploadID)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid upload ID"})
+		return
+	}
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var upload model.LykedUploads
+	err = uploads.FindOne(ctx, bson.M{"_id": id, "user_id": userID, "deleted_at": nil}).Decode(&upload)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(404, gin.H{"error": "Upload not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch upload"})
+		return
+	}
+
+	snapshot, started, err := archive.StartSnapshot(ctx, upload)
+	switch {
+	case errors.Is(err, archive.ErrNotConfigured):
+		c.JSON(503, gin.H{"error": "Archiving is not available"})
+		return
+	case errors.Is(err, archive.ErrQuotaExceeded):
+		c.JSON(403, gin.H{"error": "Archive quota exceeded"})
+		return
+	case err != nil:
+		c.JSON(500, gin.H{"error": "Failed to start snapshot"})
+		return
+	}
+	if started {
+		c.JSON(202, gin.H{"message": "Snapshot started", "snapshot": snapshot})
+		return
+	}
+	c.JSON(200, gin.H{"message": "A snapshot is already in progress", "snapshot": snapshot})
+}
+
+// ListSnapshotsHandler lists the caller's snapshots, newest first,
+// optionally only those of one upload.
+func ListSnapshotsHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	filter := bson.M{"user_id": userID}
+	if uploadID := c.Query("upload_id"); uploadID != "" {
+		id, err := bson.ObjectIDFromHex(uploadID)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid upload ID"})
+			return
+		}
+		filter["upload_id"] = id.Hex()
+	}
+	snapshots, err := DB.GetCollection("snapshots")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := snapshots.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(200))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch snapshots"})
+		return
+	}
+	results := []model.Snapshot{}
+	if err := cursor.All(ctx, &results); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to parse snapshots"})
+		return
+	}
+	c.JSON(200, gin.H{"snapshots": results})
+}
+
+// GetSnapshotHandler returns a snapshot's status and metadata.
+func GetSnapshotHandler(c *gin.Context) {
+	snapshot, ok := findSnapshot(c)
+	if !ok {
+		return
+	}
+	c.JSON(200, gin.H{"snapshot": snapshot})
+}
+
+// ReplaySnapshotHandler serves the captured page as a single HTML document
+// with its sub-resources inlined. With ?url= it serves that one captured
+// response as it was recorded instead. Either way the response is
+// sandboxed so nothing captured can run or reach the network.
+func ReplaySnapshotHandler(c *gin.Context) {
+	snapshot, ok := completedSnapshot(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	captured, err := archive.OpenSnapshot(ctx, snapshot)
+	if errors.Is(err, archive.ErrBlobNotFound) {
+		c.JSON(404, gin.H{"error": "Snapshot file is missing"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to open snapshot"})
+		return
+	}
+
+	c.Header("Content-Security-Policy", warc.ReplayPolicy)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, max-age=31536000, immutable")
+
+	if link := c.Query("url"); link != "" {
+		resp, err := captured.Lookup(link)
+		if err != nil {
+			c.JSON(404, gin.H{"error": "URL is not in this snapshot"})
+			return
+		}
+		contentType := resp.Header.Get("Content-Type")
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		c.Data(resp.StatusCode, contentType, resp.Body)
+		return
+	}
+
+	page, err := captured.Render(captured.Page())
+	if err != nil {
+		c.JSON(422, gin.H{"error": "Snapshot cannot be replayed", "details": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+}
+
+// DownloadSnapshotHandler serves the raw .warc.gz file, for use with
+// other web archive tools.
+func DownloadSnapshotHandler(c *gin.Context) {
+	snapshot, ok := completedSnapshot(c)
+	if !ok {
+		return
+	}
+	blob, err := archive.Store.Open(c.Request.Context(), snapshot.Hash)
+	if errors.Is(err, archive.ErrBlobNotFound) {
+		c.JSON(404, gin.H{"error": "Snapshot file is missing"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to open snapshot"})
+		return
+	}
+	defer blob.Close()
+
+	c.Header("Content-Type", "application/warc")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="snapshot-%s.warc.gz"`, snapshot.ID.Hex()))
+	http.ServeContent(c.Writer, c.Request, "", *snapshot.CompletedAt, blob)
+}
+
+// DeleteSnapshotHandler removes a snapshot and frees its storage.
+func DeleteSnapshotHandler(c *gin.Context) {
+	snapshot, ok := findSnapshot(c)
+	if !ok {
+		return
+	}
+	if snapshot.Status == model.ArchiveStatusQueued || snapshot.Status == model.ArchiveStatusRunning {
+		c.JSON(409, gin.H{"error": "Snapshot is still in progress"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := archive.RemoveSnapshot(ctx, snapshot); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to delete snapshot"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Snapshot deleted"})
+}
+
+// completedSnapshot is findSnapshot for handlers that need the WARC file.
+func completedSnapshot(c *gin.Context) (*model.Snapshot, bool) {
+	snapshot, ok := findSnapshot(c)
+	if !ok {
+		return nil, false
+	}
+	if snapshot.Status != model.ArchiveStatusCompleted {
+		c.JSON(409, gin.H{"error": "Snapshot is not ready", "status": snapshot.Status})
+		return nil, false
+	}
+	if archive.Store == nil {
+		c.JSON(503, gin.H{"error": "Archiving is not available"})
+		return nil, false
+	}
+	return snapshot, true
+}
+
+// findSnapshot loads the caller's snapshot in the :id param, writing the
+// error response itself when there is none.
+func findSnapshot(c *gin.Context) (*model.Snapshot, bool) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return nil, false
+	}
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid snapshot ID"})
+		return nil, false
+	}
+	snapshots, err := DB.GetCollection("snapshots")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return nil, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var snapshot model.Snapshot
+	err = snapshots.FindOne(ctx, bson.M{"_id": id, "user_id": userID}).Decode(&snapshot)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(404, gin.H{"error": "Snapshot not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch snapshot"})
+		return nil, false
+	}
+	return &snapshot, true
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	DB "lyked-backend/internal/database/mongodb"
+	"lyked-backend/internal/suggest"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SuggestTagsHandler proposes tags for an item about to be saved:
+// {"url", "title", "description", "author", "tags"}. Suggestions come from
+// the user's own library and earlier feedback. ?limit= caps how many are
+// returned.
+func SuggestTagsHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var req suggest.Input
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body"})
+		return
+	}
+	limit := suggest.DefaultLimit
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 {
+			c.JSON(400, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+	}
+	store, ok := newSuggestStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	suggestions, err := store.Suggest(ctx, userID.(string), req, limit)
+	if errors.Is(err, suggest.ErrNothingToSuggestFor) {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to suggest tags"})
+		return
+	}
+	c.JSON(200, gin.H{"suggestions": suggestions})
+}
+
+// TagSuggestionFeedbackHandler records which suggested tags were taken:
+// {"url", "author", "accepted": [...], "rejected": [...]}. Later
+// suggestions for similar links follow it.
+func TagSuggestionFeedbackHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var req suggest.FeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body"})
+		return
+	}
+	store, ok := newSuggestStore(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := store.Record(ctx, userID.(string), req, time.Now().UTC())
+	if errors.Is(err, suggest.ErrNoFeedback) {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to save tag feedback"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Feedback saved"})
+}
+
+// newSuggestStore wires the suggestion store to its collections. It writes
+// the error response itself.
+func newSuggestStore(c *gin.Context) (*suggest.Store, bool) {
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return nil, false
+	}
+	feedback, err := DB.GetCollection("tag_feedback")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return nil, false
+	}
+	return &suggest.Store{Uploads: uploads, Feedback: feedback}, true
+}
@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	DB "lyked-backend/internal/database/mongodb"
+	"lyked-backend/internal/library"
+	"lyked-backend/internal/search"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type renameTagRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type mergeTagsRequest struct {
+	Tags []string `json:"tags"`
+	Into string   `json:"into"`
+}
+
+type deleteTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+type aliasRequest struct {
+	Alias string `json:"alias"`
+	Tag   string `json:"tag"`
+}
+
+type deleteAliasesRequest struct {
+	Aliases []string `json:"aliases"`
+}
+
+type tagMetaRequest struct {
+	Tag string `json:"tag"`
+	library.TagMetaUpdate
+}
+
+// ListTagsHandler returns the user's tags with how many items carry each,
+// most used first, and their colors and emoji.
+func ListTagsHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	meta, err := DB.GetCollection("tag_meta")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tags, err := library.ListTags(ctx, uploads, meta, userID.(string))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+	c.JSON(200, gin.H{"tags": tags})
+}
+
+// TagTreeHandler returns the user's tags as a hierarchy: food/baking sits
+// under food. Each tag counts the items carrying it and, in total, those
+// carrying it or a tag below it.
+func TagTreeHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	meta, err := DB.GetCollection("tag_meta")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tree, err := library.TagTree(ctx, uploads, meta, userID.(string))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+	c.JSON(200, gin.H{"tags": tree})
+}
+
+// RenameTagHandler renames a tag on every item. Renaming to a tag that
+// already exists merges the two.
+func RenameTagHandler(c *gin.Context) {
+	var req renameTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body"})
+		return
+	}
+	mergeTags(c, []string{req.From}, req.To)
+}
+
+// MergeTagsHandler replaces several tags with one on every item, e.g. to
+// fold typos into the tag they meant.
+func MergeTagsHandler(c *gin.Context) {
+	var req mergeTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body"})
+		return
+	}
+	mergeTags(c, req.Tags, req.Into)
+}
+
+func mergeTags(c *gin.Context, sources []string, target string) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	meta, err := DB.GetCollection("tag_meta")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	changed, err := library.MergeTags(ctx, uploads, folders, meta, userID.(string), sources, target, time.Now().UTC())
+	if errors.Is(err, library.ErrTagRequired) || errors.Is(err, library.ErrMergeTarget) || errors.Is(err, library.ErrMergeBelow) {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to update tags"})
+		return
+	}
+	reindex(ctx, userID.(string), changed)
+	c.JSON(200, gin.H{"message": "Tags updated", "updated": len(changed)})
+}
+
+// DeleteTagsHandler takes tags off every item: {"tags": ["..."]}.
+func DeleteTagsHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var req deleteTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body"})
+		return
+	}
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	meta, err := DB.GetCollection("tag_meta")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	changed, err := library.DeleteTags(ctx, uploads, meta, userID.(string), req.Tags)
+	if errors.Is(err, library.ErrTagRequired) {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to delete tags"})
+		return
+	}
+	reindex(ctx, userID.(string), changed)
+	c.JSON(200, gin.H{"message": "Tags deleted", "updated": len(changed)})
+}
+
+// ListAliasesHandler returns the user's tag aliases.
+func ListAliasesHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	aliases, err := DB.GetCollection("tag_aliases")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	list, err := library.ListTagAliases(ctx, aliases, userID.(string))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch tag aliases"})
+		return
+	}
+	c.JSON(200, gin.H{"aliases": list})
+}
+
+// SetAliasHandler makes one tag stand for another: {"alias": "recipes",
+// "tag": "food/recipes"}. Items already tagged with the alias are retagged.
+func SetAliasHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var req aliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body"})
+		return
+	}
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	meta, err := DB.GetCollection("tag_meta")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	aliases, err := DB.GetCollection("tag_aliases")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	alias, changed, err := library.SetTagAlias(ctx, uploads, folders, meta, aliases, userID.(string), req.Alias, req.Tag, time.Now().UTC())
+	switch {
+	case errors.Is(err, library.ErrTagRequired), errors.Is(err, library.ErrAliasSelf), errors.Is(err, library.ErrAliasNested):
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(500, gin.H{"error": "Failed to save tag alias"})
+		return
+	}
+	reindex(ctx, userID.(string), changed)
+	c.JSON(200, gin.H{"alias": alias, "updated": len(changed)})
+}
+
+// DeleteAliasesHandler removes aliases: {"aliases": ["..."]}. Items keep
+// their tags.
+func DeleteAliasesHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var req deleteAliasesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body"})
+		return
+	}
+	aliases, err := DB.GetCollection("tag_aliases")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	deleted, err := library.DeleteTagAliases(ctx, aliases, userID.(string), req.Aliases)
+	if errors.Is(err, library.ErrTagRequired) {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to delete tag aliases"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Tag aliases deleted", "deleted": deleted})
+}
+
+// SetTagMetaHandler sets a tag's color and/or emoji; an empty string
+// clears one.
+func SetTagMetaHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var req tagMetaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body"})
+		return
+	}
+	meta, err := DB.GetCollection("tag_meta")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tag, err := library.SetTagMeta(ctx, meta, userID.(string), req.Tag, req.TagMetaUpdate, time.Now().UTC())
+	switch {
+	case errors.Is(err, library.ErrTagRequired), errors.Is(err, library.ErrNoTagMeta),
+		errors.Is(err, library.ErrInvalidColor), errors.Is(err, library.ErrInvalidEmoji):
+		c.JSON(400, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(500, gin.H{"error": "Failed to save tag"})
+	default:
+		c.JSON(200, gin.H{"tag": tag})
+	}
+}
+
+// reindex puts items whose tags changed back into the search index. The
+// change is already saved, so a failure is only logged.
+func reindex(ctx context.Context, userID string, ids []bson.ObjectID) {
+	if err := search.Reindex(ctx, userID, ids); err != nil {
+		fmt.Printf("Failed to reindex retagged uploads: %v\n", err)
+	}
+}
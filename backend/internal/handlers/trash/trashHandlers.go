@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	DB "lyked-backend/internal/database/mongodb"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/search"
+	"lyked-backend/internal/search/query"
+	"lyked-backend/internal/trash"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ListTrashHandler pages through trashed uploads, most recently deleted
+// first, and lists trashed folders. Accepts the GET /upload/all params.
+func ListTrashHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+
+	opts, err := library.ParseTrashParams(c.Request.URL.Query())
+	var syntaxErr *query.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		c.JSON(400, gin.H{"error": "Invalid search query", "details": syntaxErr.Msg, "position": syntaxErr.Pos})
+		return
+	}
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	opts.UserID = userID.(string)
+
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	page, err := library.List(ctx, uploads, opts)
+	if errors.Is(err, library.ErrInvalidCursor) {
+		c.JSON(400, gin.H{"error": "Invalid cursor"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to fetch trash"})
+		return
+	}
+
+	trashedFolders := []model.Folder{}
+	// Folders are few, so they come in full on the first page only.
+	if opts.Cursor == nil {
+		cursor, err := folders.Find(ctx,
+			bson.M{"user_id": opts.UserID, "deleted_at": bson.M{"$ne": nil}},
+			options.Find().SetSort(bson.D{{Key: "deleted_at", Value: -1}}))
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to fetch trashed folders"})
+			return
+		}
+		if err := cursor.All(ctx, &trashedFolders); err != nil {
+			c.JSON(500, gin.H{"error": "Failed to parse trashed folders"})
+			return
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"uploads":     page.Uploads,
+		"folders":     trashedFolders,
+		"next_cursor": page.NextCursor,
+		"has_more":    page.HasMore,
+		"total":       page.Total,
+	})
+}
+
+// RestoreTrashHandler takes uploads and folders out of the trash. Uploads
+// go back into the folders they were in.
+func RestoreTrashHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	var body struct {
+		Uploads []string `json:"uploads"`
+		Folders []string `json:"folders"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || len(body.Uploads)+len(body.Folders) == 0 {
+		c.JSON(400, gin.H{"error": "uploads and/or folders (lists of ids) are required"})
+		return
+	}
+	if len(body.Uploads) > library.MaxBatchSize || len(body.Folders) > library.MaxBatchSize {
+		c.JSON(400, gin.H{"error": library.ErrBatchTooLarge.Error()})
+		return
+	}
+
+	uploadIDs := make([]bson.ObjectID, 0, len(body.Uploads))
+	for _, raw := range body.Uploads {
+		id, err := bson.ObjectIDFromHex(raw)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid upload ID", "id": raw})
+			return
+		}
+		uploadIDs = append(uploadIDs, id)
+	}
+	folderIDs := make([]bson.ObjectID, 0, len(body.Folders))
+	for _, raw := range body.Folders {
+		id, err := bson.ObjectIDFromHex(raw)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid folder ID", "id": raw})
+			return
+		}
+		folderIDs = append(folderIDs, id)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var restoredFolders int64
+	if len(folderIDs) > 0 {
+		n, err := trash.RestoreFolders(ctx, userID.(string), folderIDs)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to restore folders"})
+			return
+		}
+		restoredFolders = n
+	}
+	restoredUploads := []model.LykedUploads{}
+	if len(uploadIDs) > 0 {
+		restored, err := trash.RestoreUploads(ctx, userID.(string), uploadIDs)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to restore uploads"})
+			return
+		}
+		restoredUploads = restored
+	}
+	for _, u := range restoredUploads {
+		if err := search.Default.Index(ctx, search.DocumentFromUpload(u)); err != nil {
+			fmt.Printf("Failed to index upload %s: %v\n", u.ID.Hex(), err)
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"message":          "Restored from trash",
+		"restored_uploads": len(restoredUploads),
+		"restored_folders": restoredFolders,
+	})
+}
+
+// EmptyTrashHandler permanently deletes everything in the user's trash.
+func EmptyTrashHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	purged, err := trash.Empty(ctx, userID.(string))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to empty trash"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Trash emptied", "purged": purged})
+}
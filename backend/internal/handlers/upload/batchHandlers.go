@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	DB "lyked-backend/internal/database/mongodb"
+	"lyked-backend/internal/library"
+	"lyked-backend/internal/search"
+	"lyked-backend/internal/search/query"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// BatchUploadsHandler applies one operation to many uploads, selected by
+// ids or by a listing filter, and reports what happened to each item.
+func BatchUploadsHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+
+	var req library.BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid batch request"})
+		return
+	}
+	err := req.Validate()
+	var syntaxErr *query.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		c.JSON(400, gin.H{"error": "Invalid search query", "details": syntaxErr.Msg, "position": syntaxErr.Pos})
+		return
+	}
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := library.ApplyBatch(ctx, uploads, folders, userID.(string), req, time.Now().UTC())
+	switch {
+	case errors.Is(err, library.ErrFolderNotFound):
+		c.JSON(404, gin.H{"error": "Folder not found"})
+		return
+	case errors.Is(err, library.ErrSmartFolder), errors.Is(err, library.ErrBatchTooLarge):
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(500, gin.H{"error": "Failed to apply batch operation"})
+		return
+	}
+
+	reindexBatch(ctx, userID.(string), req.Operation, result)
+	c.JSON(200, result)
+}
+
+// reindexBatch brings the search index in line with the updated items.
+func reindexBatch(ctx context.Context, userID, op string, result *library.BatchResult) {
+	var ids []bson.ObjectID
+	for _, r := range result.Results {
+		if r.Status != library.BatchUpdated {
+			continue
+		}
+		if op == library.OpDelete {
+			if err := search.Default.Remove(ctx, userID, r.ID); err != nil {
+				fmt.Printf("Failed to remove upload %s from search: %v\n", r.ID, err)
+			}
+			continue
+		}
+		if id, err := bson.ObjectIDFromHex(r.ID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	if err := search.Reindex(ctx, userID, ids); err != nil {
+		fmt.Printf("Failed to reindex batch: %v\n", err)
+	}
+}
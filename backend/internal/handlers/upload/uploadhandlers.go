@@ -2,15 +2,22 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	DB "lyked-backend/internal/database/mongodb"
 	PDB "lyked-backend/internal/database/postgresql"
+	"lyked-backend/internal/library"
 	model "lyked-backend/internal/models/mongodb"
 	modelPG "lyked-backend/internal/models/postgresql"
+	"lyked-backend/internal/rules"
+	"lyked-backend/internal/search"
+	"lyked-backend/internal/search/query"
+	"lyked-backend/internal/trash"
+	"lyked-backend/internal/utils"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 func UploadHandler(c *gin.Context) {
@@ -50,7 +57,22 @@ func UploadHandler(c *gin.Context) {
 		return
 	}
 
-	upload.ID = primitive.NewObjectID()
+	upload.ID = bson.NewObjectID()
+	if err := library.InitState(&upload, time.Now().UTC()); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	upload.Notes = nil // added through /upload/:id/notes once the item exists
+	upload.Annotations = nil
+	// The server owns these: the trash, the link checker and folder order.
+	upload.DeletedAt, upload.DeletedBy = nil, ""
+	upload.LinkStatus, upload.LinkCheckedAt = "", nil
+	upload.Positions = nil
+	upload.Platform = utils.DetectPlatform(upload.VideoLink)
+	upload.Tags = library.NormalizeTags(upload.Tags)
+	if upload.SavedAt.IsZero() {
+		upload.SavedAt = time.Now().UTC()
+	}
 
 	fmt.Printf("Received upload: %#v\n", upload)
 	collection, err := DB.GetCollection("uploads")
@@ -62,18 +84,69 @@ func UploadHandler(c *gin.Context) {
 		return
 	}
 
-	_, err = collection.InsertOne(ctx, upload)
+	folders, err := DB.GetCollection("folders")
 	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+
+	ruleStore := &rules.Store{Uploads: collection, Folders: folders}
+	if ruleStore.Rules, err = DB.GetCollection("rules"); err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ruleSet, err := ruleStore.Active(ctx, upload.UserID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to apply rules"})
+		return
+	}
+	ruleSet.Apply(&upload, time.Now().UTC())
+	if upload.Tags, err = library.ResolveTags(ctx, upload.UserID, upload.Tags); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to resolve tag aliases"})
+		return
+	}
+
+	// The folders are checked in the same transaction as the insert, so the
+	// upload can't be filed into a folder that is purged meanwhile. That
+	// includes the folders rules added.
+	requested := upload.Folders
+	err = DB.WithTransaction(ctx, func(ctx context.Context) error {
+		ids, err := library.StaticFolderIDs(ctx, folders, upload.UserID, requested)
+		if err != nil {
+			return err
+		}
+		upload.Folders = ids
+		if _, err := collection.InsertOne(ctx, upload); err != nil {
+			return err
+		}
+		for _, folderID := range ids {
+			if err := library.AppendToFolder(ctx, collection, folderID, []bson.ObjectID{upload.ID}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, library.ErrFolderNotFound):
+		c.JSON(404, gin.H{"error": "Folder not found"})
+		return
+	case errors.Is(err, library.ErrSmartFolder):
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	case err != nil:
 		c.JSON(500, gin.H{"error": "Failed to save upload"})
 		return
 	}
+	if err := search.Default.Index(ctx, search.DocumentFromUpload(upload)); err != nil {
+		fmt.Printf("Failed to index upload %s: %v\n", upload.ID.Hex(), err)
+	}
 
 	c.JSON(200, gin.H{"message": "Upload successful", "upload_id": upload.ID.Hex()})
 
 }
 
+// DeleteUploadHandler moves an upload to the trash; see /trash to restore it.
 func DeleteUploadHandler(c *gin.Context) {
-	// Handle file deletion logic here
 	id := c.Query("id")
 	userID, exist := c.Get("user_id")
 	if id == "" {
@@ -88,22 +161,28 @@ func DeleteUploadHandler(c *gin.Context) {
 		c.JSON(400, gin.H{"error": "User ID is required"})
 		return
 	}
-	collection, err := DB.GetCollection("uploads")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+	objectID, err := bson.ObjectIDFromHex(id)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Database connection error"})
+		c.JSON(400, gin.H{"error": "Invalid upload ID"})
 		return
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	_, err = collection.DeleteOne(ctx, primitive.M{"_id": id})
+	trashed, err := trash.TrashUploads(ctx, userID.(string), []bson.ObjectID{objectID}, time.Now().UTC())
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to delete upload"})
 		return
 	}
+	if trashed == 0 {
+		c.JSON(404, gin.H{"error": "Upload not found"})
+		return
+	}
+	if err := search.Default.Remove(ctx, userID.(string), id); err != nil {
+		fmt.Printf("Failed to remove upload %s from search: %v\n", id, err)
+	}
 
-	c.JSON(200, gin.H{"message": "Upload deleted successfully"})
+	c.JSON(200, gin.H{"message": "Upload moved to trash"})
 }
 
 func GetAllUploadsHandler(c *gin.Context) {
@@ -117,6 +196,18 @@ func GetAllUploadsHandler(c *gin.Context) {
 		return
 	}
 
+	opts, err := library.ParseListParams(c.Request.URL.Query())
+	var syntaxErr *query.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		c.JSON(400, gin.H{"error": "Invalid search query", "details": syntaxErr.Msg, "position": syntaxErr.Pos})
+		return
+	}
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	opts.UserID = userID.(string)
+
 	collection, err := DB.GetCollection("uploads")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -125,19 +216,139 @@ func GetAllUploadsHandler(c *gin.Context) {
 		c.JSON(500, gin.H{"error": "Database connection error"})
 		return
 	}
+	if !expandFolder(c, ctx, &opts) {
+		return
+	}
 
-	cursor, err := collection.Find(ctx, primitive.M{"user_id": userID})
+	page, err := library.List(ctx, collection, opts)
+	if errors.Is(err, library.ErrInvalidCursor) {
+		c.JSON(400, gin.H{"error": "Invalid cursor"})
+		return
+	}
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to fetch uploads"})
 		return
 	}
-	defer cursor.Close(ctx)
 
-	var uploads []model.LykedUploads
-	if err = cursor.All(ctx, &uploads); err != nil {
-		c.JSON(500, gin.H{"error": "Failed to parse uploads"})
+	c.JSON(200, page)
+}
+
+// ExportUploadsHandler downloads the library, including notes and
+// annotations, as JSON (default) or Markdown. It takes the same filters
+// as /upload/all but always exports the whole selection.
+func ExportUploadsHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	format := c.DefaultQuery("format", library.ExportJSON)
+	contentType, extension := "application/json", "json"
+	switch format {
+	case library.ExportJSON:
+	case library.ExportMarkdown:
+		contentType, extension = "text/markdown; charset=utf-8", "md"
+	default:
+		c.JSON(400, gin.H{"error": "format must be json or markdown"})
 		return
 	}
 
-	c.JSON(200, gin.H{"uploads": uploads})
+	opts, err := library.ParseListParams(c.Request.URL.Query())
+	var syntaxErr *query.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		c.JSON(400, gin.H{"error": "Invalid search query", "details": syntaxErr.Msg, "position": syntaxErr.Pos})
+		return
+	}
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	opts.UserID = userID.(string)
+
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	if !expandFolder(c, ctx, &opts) {
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="lyked-export-%s.%s"`, time.Now().UTC().Format("2006-01-02"), extension))
+	c.Status(200)
+	// Headers are sent by now, so a failure can only cut the file short.
+	if err := library.Export(ctx, uploads, folders, opts, format, c.Writer); err != nil {
+		fmt.Printf("Export for %s failed: %v\n", opts.UserID, err)
+	}
+}
+
+// UpdateUploadHandler sets an upload's favorite flag, rating and watch
+// status. Fields left out of the body are not changed.
+func UpdateUploadHandler(c *gin.Context) {
+	userID, exist := c.Get("user_id")
+	if !exist {
+		c.JSON(401, gin.H{"error": "Unauthorized: user_id not found in context"})
+		return
+	}
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid upload ID"})
+		return
+	}
+	var update library.StateUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if err := update.Validate(); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	collection, err := DB.GetCollection("uploads")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	upload, err := library.UpdateState(ctx, collection, userID.(string), id, update, time.Now().UTC())
+	if errors.Is(err, library.ErrUploadNotFound) {
+		c.JSON(404, gin.H{"error": "Upload not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to update upload"})
+		return
+	}
+	c.JSON(200, gin.H{"upload": upload})
+}
+
+// expandFolder applies include_subfolders to a listing's folder param. It
+// writes the error response itself.
+func expandFolder(c *gin.Context, ctx context.Context, opts *library.ListOptions) bool {
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Database connection error"})
+		return false
+	}
+	err = library.ExpandFolder(ctx, folders, opts, time.Now())
+	switch {
+	case errors.Is(err, library.ErrFolderNotFound):
+		c.JSON(404, gin.H{"error": "Folder not found"})
+		return false
+	case err != nil:
+		c.JSON(400, gin.H{"error": "Failed to resolve subfolders", "details": err.Error()})
+		return false
+	}
+	return true
 }
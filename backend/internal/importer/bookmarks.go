@@ -0,0 +1,319 @@
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	model "lyked-backend/internal/models/mongodb"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// parseNetscape reads the Netscape bookmark file every browser exports.
+// Each bookmark is filed under the innermost <H3> folder around it, which
+// keeps its place among the folders above it.
+func parseNetscape(r io.Reader) ([]Item, []model.ImportRowError, error) {
+	return parseBookmarkHTML(r, "h3")
+}
+
+// parsePocketHTML reads Pocket's ril_export.html. Its <h1> sections are
+// "Unread" and "Read Archive" rather than folders, so nothing is filed.
+func parsePocketHTML(r io.Reader) ([]Item, []model.ImportRowError, error) {
+	return parseBookmarkHTML(r, "")
+}
+
+// parseBookmarkHTML walks <a> tags, reading ADD_DATE/TIME_ADDED and TAGS
+// attributes and the <DD> description that may follow. folderTag names the
+// heading element that opens a folder; the <DL> after it holds its contents.
+func parseBookmarkHTML(r io.Reader, folderTag string) ([]Item, []model.ImportRowError, error) {
+	z := html.NewTokenizer(r)
+	var (
+		items     []Item
+		rowErrors []model.ImportRowError
+		folders   [][]string // paths of the open <dl>s, outermost folder first
+		pending   string     // heading just read, becomes a folder at the next <dl>
+		inHeading bool
+		current   *Item
+		inAnchor  bool
+		inDesc    bool
+		row       int
+	)
+	finish := func() {
+		if current != nil {
+			items = append(items, *current)
+			current = nil
+		}
+	}
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				finish()
+				if len(items) == 0 && len(rowErrors) == 0 {
+					return nil, nil, fmt.Errorf("no bookmarks found")
+				}
+				return items, rowErrors, nil
+			}
+			return nil, nil, z.Err()
+
+		case html.StartTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+			switch {
+			case tag == "a":
+				finish()
+				row++
+				attrs := map[string]string{}
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = z.TagAttr()
+					attrs[string(k)] = string(v)
+				}
+				href := strings.TrimSpace(attrs["href"])
+				if href == "" {
+					rowErrors = append(rowErrors, model.ImportRowError{Row: row, Message: "bookmark has no href"})
+					continue
+				}
+				current = &Item{Row: row, Link: href, Tags: splitTags(attrs["tags"], ",")}
+				if len(folders) > 0 {
+					current.fileUnder(folders[len(folders)-1])
+				}
+				if ts := firstNonEmpty(attrs["add_date"], attrs["time_added"]); ts != "" {
+					if t, ok := parseUnix(ts); ok {
+						current.SavedAt = t
+					}
+				}
+				inAnchor = true
+			case folderTag != "" && tag == folderTag:
+				finish()
+				inHeading = true
+				pending = ""
+			case tag == "dl":
+				finish()
+				// Every <dl> pushes, even the root one, so the matching </dl> can
+				// pop. One without a heading stays in the enclosing folder.
+				var path []string
+				if len(folders) > 0 {
+					path = folders[len(folders)-1]
+				}
+				if pending != "" {
+					path = append(slices.Clone(path), pending)
+				}
+				folders = append(folders, path)
+				pending = ""
+			case tag == "dd":
+				inDesc = current != nil
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch tag := string(name); {
+			case tag == "a":
+				inAnchor = false
+			case folderTag != "" && tag == folderTag:
+				inHeading = false
+			case tag == "dl":
+				finish()
+				if len(folders) > 0 {
+					folders = folders[:len(folders)-1]
+				}
+			}
+
+		case html.TextToken:
+			text := strings.TrimSpace(string(z.Text()))
+			switch {
+			case text == "":
+			case inAnchor && current != nil:
+				current.Title += text
+			case inHeading:
+				pending += text
+			case inDesc && current != nil:
+				current.Description += text
+				inDesc = false
+			}
+		}
+	}
+}
+
+// parsePocketCSV reads the CSV export Pocket switched to in 2024:
+// title,url,time_added,tags,status with tags separated by "|".
+func parsePocketCSV(r io.Reader) ([]Item, []model.ImportRowError, error) {
+	rows, header, err := readCSV(r, "url")
+	if err != nil {
+		return nil, nil, err
+	}
+	var items []Item
+	var rowErrors []model.ImportRowError
+	for i, rec := range rows {
+		row := i + 2 // header is row 1
+		get := header.getter(rec)
+		link := get("url")
+		if link == "" {
+			rowErrors = append(rowErrors, model.ImportRowError{Row: row, Message: "missing url"})
+			continue
+		}
+		item := Item{Row: row, Link: link, Title: get("title"), Tags: splitTags(get("tags"), "|")}
+		if t, ok := parseUnix(get("time_added")); ok {
+			item.SavedAt = t
+		}
+		items = append(items, item)
+	}
+	return items, rowErrors, nil
+}
+
+// parseRaindropCSV reads Raindrop.io's CSV export. Nested collections are
+// written as "Parent/Child"; items go into the innermost one, under its
+// parents.
+func parseRaindropCSV(r io.Reader) ([]Item, []model.ImportRowError, error) {
+	rows, header, err := readCSV(r, "url")
+	if err != nil {
+		return nil, nil, err
+	}
+	var items []Item
+	var rowErrors []model.ImportRowError
+	for i, rec := range rows {
+		row := i + 2
+		get := header.getter(rec)
+		link := get("url")
+		if link == "" {
+			rowErrors = append(rowErrors, model.ImportRowError{Row: row, Message: "missing url"})
+			continue
+		}
+		var path []string
+		if folder := get("folder"); !strings.EqualFold(folder, "Unsorted") {
+			for _, name := range strings.Split(folder, "/") {
+				if name = strings.TrimSpace(name); name != "" {
+					path = append(path, name)
+				}
+			}
+		}
+		item := Item{
+			Row:         row,
+			Link:        link,
+			Title:       get("title"),
+			Description: firstNonEmpty(get("note"), get("excerpt")),
+			Tags:        splitTags(get("tags"), ","),
+		}
+		item.fileUnder(path)
+		if created := get("created"); created != "" {
+			if t, err := time.Parse(time.RFC3339, created); err == nil {
+				item.SavedAt = t.UTC()
+			} else {
+				rowErrors = append(rowErrors, model.ImportRowError{Row: row, Link: link, Message: fmt.Sprintf("invalid created date %q", created)})
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+	return items, rowErrors, nil
+}
+
+// parsePinboard reads Pinboard's JSON export. Pinboard calls the title
+// "description" and the description "extended"; tags are space separated.
+func parsePinboard(r io.Reader) ([]Item, []model.ImportRowError, error) {
+	var export []struct {
+		Href        string `json:"href"`
+		Description string `json:"description"`
+		Extended    string `json:"extended"`
+		Time        string `json:"time"`
+		Tags        string `json:"tags"`
+	}
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, nil, fmt.Errorf("not a Pinboard JSON export: %w", err)
+	}
+	var items []Item
+	var rowErrors []model.ImportRowError
+	for i, b := range export {
+		row := i + 1
+		if b.Href == "" {
+			rowErrors = append(rowErrors, model.ImportRowError{Row: row, Message: "missing href"})
+			continue
+		}
+		item := Item{Row: row, Link: b.Href, Title: b.Description, Description: b.Extended, Tags: splitTags(b.Tags, " ")}
+		if t, err := time.Parse(time.RFC3339, b.Time); err == nil {
+			item.SavedAt = t.UTC()
+		}
+		items = append(items, item)
+	}
+	return items, rowErrors, nil
+}
+
+type csvHeader map[string]int
+
+// getter returns a lookup of trimmed cell values by column name.
+func (h csvHeader) getter(rec []string) func(string) string {
+	return func(col string) string {
+		i, ok := h[col]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+}
+
+// readCSV reads a CSV file with a header row, which must include required.
+func readCSV(r io.Reader, required string) ([][]string, csvHeader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a valid CSV file: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("CSV file is empty")
+	}
+	header := csvHeader{}
+	for i, name := range records[0] {
+		header[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := header[required]; !ok {
+		return nil, nil, fmt.Errorf("CSV header has no %q column", required)
+	}
+	return records[1:], header, nil
+}
+
+func splitTags(s, sep string) []string {
+	var tags []string
+	seen := map[string]bool{}
+	for _, t := range strings.Split(s, sep) {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		tags = append(tags, t)
+	}
+	return tags
+}
+
+func parseUnix(s string) (time.Time, bool) {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}, false
+	}
+	// Some browsers write milliseconds or microseconds instead of seconds.
+	switch {
+	case n > 1e14:
+		n /= 1e6
+	case n > 1e11:
+		n /= 1e3
+	}
+	return time.Unix(n, 0).UTC(), true
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
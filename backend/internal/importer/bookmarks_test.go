@@ -0,0 +1,135 @@
+package importer
+
+import (
+	"context"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestParseNetscape(t *testing.T) {
+	items, rowErrors := parseFixture(t, "netscape", "netscape_bookmarks.html")
+	checkParsed(t, items, rowErrors, []Item{
+		{Row: 1, Link: "https://example.com/unfiled", Title: "Unfiled", SavedAt: date("2023-11-14T22:13:20Z")},
+		{
+			Row:         2,
+			Link:        "https://example.com/soup",
+			Title:       "Soup & Bread",
+			Description: "Grandma's recipe",
+			Tags:        []string{"soup", "winter"},
+			Folder:      "Recipes",
+			SavedAt:     date("2023-11-14T22:15:00Z"), // written in milliseconds
+		},
+		{Row: 3, Link: "https://example.com/rye", Title: "Rye", Folder: "Baking", Parents: []string{"Recipes"}, SavedAt: date("2023-11-14T22:16:40Z")},
+		{Row: 5, Link: "https://example.com/stew", Title: "Stew", Folder: "Recipes"},
+		{Row: 6, Link: "https://example.com/last", Title: "Last"},
+	}, []model.ImportRowError{
+		{Row: 4, Message: "bookmark has no href"},
+	})
+
+	if _, _, err := Parse("netscape", strings.NewReader("<html><body><p>no bookmarks</p></body></html>")); err == nil {
+		t.Error("a file without bookmarks was accepted")
+	}
+}
+
+func TestParsePocket(t *testing.T) {
+	// The export's sections are read states, not folders.
+	items, rowErrors := parseFixture(t, "pocket_html", "pocket_export.html")
+	checkParsed(t, items, rowErrors, []Item{
+		{Row: 1, Link: "https://example.com/article", Title: "An article", Tags: []string{"longread", "tech"}, SavedAt: date("2022-04-15T05:20:00Z")},
+		{Row: 2, Link: "https://example.com/read", Title: "Already read", SavedAt: date("2021-12-20T11:33:20Z")},
+	}, nil)
+
+	items, rowErrors = parseFixture(t, "pocket_csv", "pocket.csv")
+	checkParsed(t, items, rowErrors, []Item{
+		{Row: 2, Link: "https://example.com/p1", Title: "Pocket one", Tags: []string{"news", "tech"}, SavedAt: date("2024-03-09T16:00:00Z")},
+		{Row: 4, Link: "https://example.com/p2", Title: "Pocket two"},
+	}, []model.ImportRowError{
+		{Row: 3, Message: "missing url"},
+	})
+}
+
+func TestParseRaindrop(t *testing.T) {
+	items, rowErrors := parseFixture(t, "raindrop", "raindrop.csv")
+	checkParsed(t, items, rowErrors, []Item{
+		{Row: 2, Link: "https://example.com/r1", Title: "Flat", Description: "An excerpt", Tags: []string{"a", "b"}, Folder: "Reading", SavedAt: date("2024-01-02T03:04:05Z")},
+		{Row: 3, Link: "https://example.com/r2", Title: "Nested", Description: "My note", Folder: "Bread", Parents: []string{"Cooking", "Baking"}, SavedAt: date("2024-01-03T00:00:00Z")},
+		{Row: 4, Link: "https://example.com/r3", Title: "Unsorted"},
+	}, []model.ImportRowError{
+		{Row: 5, Link: "https://example.com/r4", Message: `invalid created date "last week"`},
+		{Row: 6, Message: "missing url"},
+	})
+
+	if _, _, err := Parse("raindrop", strings.NewReader("title,link\nx,y\n")); err == nil {
+		t.Error("a CSV without a url column was accepted")
+	}
+}
+
+func TestParsePinboard(t *testing.T) {
+	items, rowErrors := parseFixture(t, "pinboard", "pinboard.json")
+	checkParsed(t, items, rowErrors, []Item{
+		{Row: 1, Link: "https://example.com/pb1", Title: "Title here", Description: "Longer notes", Tags: []string{"go", "testing"}, SavedAt: date("2023-05-06T07:08:09Z")},
+		{Row: 3, Link: "https://example.com/pb3", Title: "No time"},
+	}, []model.ImportRowError{
+		{Row: 2, Message: "missing href"},
+	})
+}
+
+func TestFolderPath(t *testing.T) {
+	if path := (Item{}).folderPath(); path != nil {
+		t.Errorf("unfiled item has folder path %v", path)
+	}
+	item := Item{Folder: "Bread", Parents: []string{"Cooking", "Baking"}}
+	if path := item.folderPath(); !reflect.DeepEqual(path, []string{"Cooking", "Baking", "Bread"}) {
+		t.Errorf("folderPath = %v", path)
+	}
+	if !reflect.DeepEqual(item.Parents, []string{"Cooking", "Baking"}) {
+		t.Errorf("folderPath changed Parents to %v", item.Parents)
+	}
+}
+
+func TestFolderResolverLookup(t *testing.T) {
+	cooking := model.Folder{ID: bson.NewObjectID(), Name: "Cooking"}
+	baking := model.Folder{ID: bson.NewObjectID(), Name: "Baking", ParentID: cooking.ID.Hex()}
+	topBaking := model.Folder{ID: bson.NewObjectID(), Name: "baking"}
+	r := newFolderResolver("u1")
+	r.index([]model.Folder{cooking, baking, topBaking})
+	r.loaded = true
+
+	tests := []struct {
+		path   []string
+		want   string
+		exists bool
+	}{
+		{[]string{"Cooking"}, cooking.ID.Hex(), true},
+		{[]string{" cooking ", "BAKING"}, baking.ID.Hex(), true}, // names ignore case and spaces
+		{[]string{"Baking"}, topBaking.ID.Hex(), true},           // the top-level one, not Cooking's
+		{[]string{"Cooking", "", "Baking"}, baking.ID.Hex(), true},
+		{[]string{"Cooking", "Baking", "Bread"}, "", false},
+		{[]string{"Bread"}, "", false},
+		{nil, "", false},
+	}
+	for _, tt := range tests {
+		id, exists, err := r.lookup(context.Background(), tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != tt.want || exists != tt.exists {
+			t.Errorf("lookup(%q) = %q, %v; want %q, %v", tt.path, id, exists, tt.want, tt.exists)
+		}
+	}
+}
+
+func TestClampPath(t *testing.T) {
+	deep := strings.Split("a/b/c/d/e/f/g/h/i/j", "/")
+	if got := clampPath(deep); !reflect.DeepEqual(got, deep[:library.MaxFolderDepth]) {
+		t.Errorf("clampPath = %v, want the first %d levels", got, library.MaxFolderDepth)
+	}
+	if got := clampPath([]string{"", " ", "x"}); !reflect.DeepEqual(got, []string{"x"}) {
+		t.Errorf("clampPath = %v, want [x]", got)
+	}
+}
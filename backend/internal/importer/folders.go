@@ -0,0 +1,120 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	DB "lyked-backend/internal/database/mongodb"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// folderResolver maps folder paths from an export onto the user's static
+// folders, creating the ones that don't exist yet under their parents.
+// Paths deeper than library.MaxFolderDepth are cut short, so their items
+// land in the deepest folder allowed.
+type folderResolver struct {
+	userID   string
+	children map[string]string // parent id ("" at the top) + "/" + lower-cased name -> folder id
+	loaded   bool
+}
+
+func newFolderResolver(userID string) *folderResolver {
+	return &folderResolver{userID: userID, children: map[string]string{}}
+}
+
+func childKey(parentID, name string) string {
+	return parentID + "/" + strings.ToLower(strings.TrimSpace(name))
+}
+
+func (r *folderResolver) load(ctx context.Context) error {
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		return err
+	}
+	cursor, err := folders.Find(ctx, bson.M{"user_id": r.userID, "kind": bson.M{"$ne": model.FolderKindSmart}, "deleted_at": nil})
+	if err != nil {
+		return fmt.Errorf("failed to load folders: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var existing []model.Folder
+	if err := cursor.All(ctx, &existing); err != nil {
+		return fmt.Errorf("failed to parse folders: %w", err)
+	}
+	r.index(existing)
+	r.loaded = true
+	return nil
+}
+
+// index records the folders by their parent and name.
+func (r *folderResolver) index(existing []model.Folder) {
+	for _, f := range existing {
+		r.children[childKey(f.ParentID, f.Name)] = f.ID.Hex()
+	}
+}
+
+// lookup returns the id of the existing folder at this path, if any.
+func (r *folderResolver) lookup(ctx context.Context, path []string) (string, bool, error) {
+	if !r.loaded {
+		if err := r.load(ctx); err != nil {
+			return "", false, err
+		}
+	}
+	id := ""
+	for _, name := range clampPath(path) {
+		var ok bool
+		if id, ok = r.children[childKey(id, name)]; !ok {
+			return "", false, nil
+		}
+	}
+	return id, id != "", nil
+}
+
+// resolve returns the id of the folder at this path, creating whatever
+// part of it is missing.
+func (r *folderResolver) resolve(ctx context.Context, path []string) (string, error) {
+	if !r.loaded {
+		if err := r.load(ctx); err != nil {
+			return "", err
+		}
+	}
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		return "", err
+	}
+	id := ""
+	for _, name := range clampPath(path) {
+		if child, ok := r.children[childKey(id, name)]; ok {
+			id = child
+			continue
+		}
+		folder := model.Folder{
+			ID:        bson.NewObjectID(),
+			UserID:    r.userID,
+			Name:      strings.TrimSpace(name),
+			Kind:      model.FolderKindStatic,
+			CreatedAt: time.Now().UTC(),
+		}
+		if err := library.CreateFolder(ctx, folders, &folder, id); err != nil {
+			return "", fmt.Errorf("failed to create folder %q: %w", name, err)
+		}
+		r.children[childKey(id, name)] = folder.ID.Hex()
+		id = folder.ID.Hex()
+	}
+	return id, nil
+}
+
+// clampPath drops empty names and the levels past the deepest allowed.
+func clampPath(path []string) []string {
+	var names []string
+	for _, name := range path {
+		if strings.TrimSpace(name) != "" {
+			names = append(names, name)
+		}
+	}
+	return names[:min(len(names), library.MaxFolderDepth)]
+}
@@ -0,0 +1,114 @@
+// Package importer turns export files from other services into LykedUploads.
+package importer
+
+import (
+	"fmt"
+	"io"
+	model "lyked-backend/internal/models/mongodb"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Item is one saved link read from an export, before it becomes an upload.
+type Item struct {
+	Row         int       `json:"row"` // 1-based position in the export, for error reports
+	Link        string    `json:"link"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Author      string    `json:"author,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Folder      string    `json:"folder,omitempty"`  // folder/board/playlist name, empty when unfiled
+	Parents     []string  `json:"parents,omitempty"` // the folders Folder sits in, outermost first
+	SavedAt     time.Time `json:"saved_at"`
+}
+
+// fileUnder files the item in the last folder of path, outermost first.
+func (i *Item) fileUnder(path []string) {
+	if len(path) == 0 {
+		return
+	}
+	i.Folder = path[len(path)-1]
+	if len(path) > 1 {
+		i.Parents = slices.Clone(path[:len(path)-1])
+	}
+}
+
+// folderPath is the item's folder with the folders above it, outermost
+// first, or nil when the item is unfiled.
+func (i Item) folderPath() []string {
+	if i.Folder == "" {
+		return nil
+	}
+	return append(slices.Clone(i.Parents), i.Folder)
+}
+
+// Parser reads an export. Rows that can't be used are reported in the
+// returned errors; a non-nil error means the file itself is unreadable.
+type Parser func(r io.Reader) ([]Item, []model.ImportRowError, error)
+
+var parsers = map[string]Parser{
+	"tiktok":       parseTikTok,
+	"instagram":    parseInstagram,
+	"youtube_csv":  parseYouTubeCSV,
+	"youtube_json": parseYouTubeJSON,
+	"pinterest":    parsePinterest,
+	"netscape":     parseNetscape,
+	"pocket_html":  parsePocketHTML,
+	"pocket_csv":   parsePocketCSV,
+	"raindrop":     parseRaindropCSV,
+	"pinboard":     parsePinboard,
+}
+
+// Sources lists the supported export formats.
+func Sources() []string {
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func Parse(source string, r io.Reader) ([]Item, []model.ImportRowError, error) {
+	parse, ok := parsers[source]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported import source %q", source)
+	}
+	return parse(r)
+}
+
+// trackingParams are query parameters that differ between shares of the
+// same post and must not defeat deduplication.
+var trackingParams = map[string]bool{
+	"igshid": true, "igsh": true, "is_from_webapp": true, "sender_device": true,
+	"sender_web_id": true, "is_copy_url": true, "_r": true, "_t": true,
+	"si": true, "feature": true, "pp": true, "ref": true, "fbclid": true, "gclid": true,
+}
+
+// CanonicalLink normalizes a link for duplicate detection: lower-cased host
+// without www/m prefixes, no fragment, no tracking params, no trailing slash.
+func CanonicalLink(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(link)
+	}
+	host := strings.ToLower(u.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	host = strings.TrimPrefix(host, "m.")
+
+	q := u.Query()
+	for key := range q {
+		if trackingParams[key] || strings.HasPrefix(key, "utm_") {
+			q.Del(key)
+		}
+	}
+
+	out := "https://" + host + strings.TrimSuffix(u.EscapedPath(), "/")
+	if encoded := q.Encode(); encoded != "" {
+		out += "?" + encoded
+	}
+	return out
+}
@@ -0,0 +1,284 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	DB "lyked-backend/internal/database/mongodb"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/rules"
+	"lyked-backend/internal/search"
+	"lyked-backend/internal/utils"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	batchSize    = 100
+	maxRowErrors = 1000 // the report keeps the first errors only; counts stay exact
+	jobTimeout   = 30 * time.Minute
+)
+
+// Start records a queued job and runs it in the background. Rows the
+// parser already rejected are carried into the job's error report.
+func Start(ctx context.Context, job *model.ImportJob, items []Item, rowErrors []model.ImportRowError) error {
+	jobs, err := DB.GetCollection("import_jobs")
+	if err != nil {
+		return err
+	}
+	job.ID = bson.NewObjectID()
+	job.Status = model.ImportStatusQueued
+	job.Total = len(items) + len(rowErrors)
+	job.Processed = len(rowErrors)
+	job.Failed = len(rowErrors)
+	job.Errors = capErrors(rowErrors)
+	job.CreatedAt = time.Now().UTC()
+	if _, err := jobs.InsertOne(ctx, job); err != nil {
+		return fmt.Errorf("failed to create import job: %w", err)
+	}
+
+	go run(*job, items)
+	return nil
+}
+
+func run(job model.ImportJob, items []Item) {
+	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+	defer cancel()
+
+	job.Status = model.ImportStatusRunning
+	save(ctx, &job)
+
+	if err := process(ctx, &job, items); err != nil {
+		job.Status = model.ImportStatusFailed
+		job.Message = err.Error()
+	} else {
+		job.Status = model.ImportStatusCompleted
+	}
+	now := time.Now().UTC()
+	job.FinishedAt = &now
+	save(ctx, &job)
+}
+
+func process(ctx context.Context, job *model.ImportJob, items []Item) error {
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		return err
+	}
+	seen, err := existingLinks(ctx, job.UserID)
+	if err != nil {
+		return err
+	}
+	folders := newFolderResolver(job.UserID)
+	ruleSet, err := activeRules(ctx, uploads, job.UserID)
+	if err != nil {
+		return err
+	}
+	aliases, err := library.LoadTagAliases(ctx, job.UserID)
+	if err != nil {
+		return err
+	}
+
+	var batch []model.LykedUploads
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		docs := make([]interface{}, len(batch))
+		for i := range batch {
+			docs[i] = batch[i]
+		}
+		if _, err := uploads.InsertMany(ctx, docs); err != nil {
+			return fmt.Errorf("failed to save imported items: %w", err)
+		}
+		if err := appendToFolders(ctx, uploads, batch); err != nil {
+			return err
+		}
+		for _, u := range batch {
+			if err := search.Default.Index(ctx, search.DocumentFromUpload(u)); err != nil {
+				fmt.Printf("Failed to index imported upload %s: %v\n", u.ID.Hex(), err)
+			}
+		}
+		job.Imported += len(batch)
+		batch = batch[:0]
+		save(ctx, job)
+		return nil
+	}
+
+	for _, item := range items {
+		job.Processed++
+		fresh, rowErr := admit(item, seen)
+		if rowErr != nil {
+			job.Failed++
+			job.Errors = appendError(job.Errors, *rowErr)
+			continue
+		}
+		if !fresh {
+			job.Duplicates++
+			continue
+		}
+
+		upload := toUpload(job.UserID, item)
+		if item.Folder != "" {
+			folderID, err := folders.resolve(ctx, item.folderPath())
+			if err != nil {
+				return err
+			}
+			upload.Folders = []string{folderID}
+		}
+		ruleSet.Apply(&upload, time.Now().UTC())
+		upload.Tags = aliases.ResolveAll(upload.Tags)
+		batch = append(batch, upload)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// appendToFolders puts imported items at the end of their folders, in
+// export order.
+func appendToFolders(ctx context.Context, uploads *mongo.Collection, batch []model.LykedUploads) error {
+	var order []string
+	byFolder := map[string][]bson.ObjectID{}
+	for _, u := range batch {
+		for _, folderID := range u.Folders {
+			if _, ok := byFolder[folderID]; !ok {
+				order = append(order, folderID)
+			}
+			byFolder[folderID] = append(byFolder[folderID], u.ID)
+		}
+	}
+	for _, folderID := range order {
+		if err := library.AppendToFolder(ctx, uploads, folderID, byFolder[folderID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// activeRules loads the user's rules, which file and tag imported items
+// like any other saved item.
+func activeRules(ctx context.Context, uploads *mongo.Collection, userID string) (*rules.Set, error) {
+	store := &rules.Store{Uploads: uploads}
+	var err error
+	if store.Rules, err = DB.GetCollection("rules"); err != nil {
+		return nil, err
+	}
+	if store.Folders, err = DB.GetCollection("folders"); err != nil {
+		return nil, err
+	}
+	return store.Active(ctx, userID)
+}
+
+// admit checks an item's link and reports whether it is new: neither in
+// seen, the canonical links already in the library, nor on an earlier row.
+// New links are added to seen.
+func admit(item Item, seen map[string]bool) (bool, *model.ImportRowError) {
+	link := strings.TrimSpace(item.Link)
+	if !isWebLink(link) {
+		return false, &model.ImportRowError{Row: item.Row, Link: link, Message: "link is not an http(s) URL"}
+	}
+	key := CanonicalLink(link)
+	if seen[key] {
+		return false, nil
+	}
+	seen[key] = true
+	return true, nil
+}
+
+// isWebLink reports whether link is an absolute http(s) URL, whatever the
+// case of its scheme.
+func isWebLink(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	return scheme == "http" || scheme == "https"
+}
+
+func toUpload(userID string, item Item) model.LykedUploads {
+	upload := model.LykedUploads{
+		ID:          bson.NewObjectID(),
+		UserID:      userID,
+		Title:       item.Title,
+		Description: item.Description,
+		Author:      item.Author,
+		VideoLink:   strings.TrimSpace(item.Link),
+		Tags:        library.NormalizeTags(item.Tags),
+		Folders:     []string{},
+		SavedAt:     item.SavedAt,
+	}
+	upload.Platform = utils.DetectPlatform(upload.VideoLink)
+	if upload.SavedAt.IsZero() {
+		upload.SavedAt = time.Now().UTC()
+	}
+	return upload
+}
+
+// existingLinks returns the canonical form of every link in the user's
+// library. Trashed items don't count, so importing an export again brings
+// back what was trashed or purged since.
+func existingLinks(ctx context.Context, userID string) (map[string]bool, error) {
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := uploads.Find(ctx, liveUploads(userID), options.Find().SetProjection(bson.M{"video_link": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load existing links: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	seen := map[string]bool{}
+	for cursor.Next(ctx) {
+		var doc struct {
+			VideoLink string `bson:"video_link"`
+		}
+		if err := cursor.Decode(&doc); err == nil {
+			seen[CanonicalLink(doc.VideoLink)] = true
+		}
+	}
+	return seen, cursor.Err()
+}
+
+// liveUploads selects the user's uploads that aren't in the trash.
+func liveUploads(userID string) bson.M {
+	return bson.M{"user_id": userID, "deleted_at": nil}
+}
+
+// save persists the job's progress. Failures are logged, not fatal: the
+// import itself matters more than its progress counter.
+func save(ctx context.Context, job *model.ImportJob) {
+	jobs, err := DB.GetCollection("import_jobs")
+	if err == nil {
+		_, err = jobs.ReplaceOne(ctx, bson.M{"_id": job.ID}, job)
+	}
+	if err != nil {
+		fmt.Printf("Failed to save import job %s: %v\n", job.ID.Hex(), err)
+	}
+}
+
+func appendError(errs []model.ImportRowError, e model.ImportRowError) []model.ImportRowError {
+	if len(errs) >= maxRowErrors {
+		return errs
+	}
+	return append(errs, e)
+}
+
+func capErrors(errs []model.ImportRowError) []model.ImportRowError {
+	if errs == nil {
+		return []model.ImportRowError{}
+	}
+	if len(errs) > maxRowErrors {
+		return errs[:maxRowErrors]
+	}
+	return errs
+}
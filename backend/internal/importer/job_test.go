@@ -0,0 +1,104 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestCanonicalLink(t *testing.T) {
+	tests := []struct {
+		link, want string
+	}{
+		{"https://www.youtube.com/watch?v=abc", "https://youtube.com/watch?v=abc"},
+		{"http://m.youtube.com/watch?v=abc&feature=share&si=xyz", "https://youtube.com/watch?v=abc"},
+		{"https://WWW.Instagram.com/p/C3abc/?igshid=123&utm_source=ig", "https://instagram.com/p/C3abc"},
+		{"HTTPS://www.tiktok.com/@chef/video/1?is_from_webapp=1&sender_device=pc#comments", "https://tiktok.com/@chef/video/1"},
+		{"https://example.com/a/?b=2&a=1", "https://example.com/a?a=1&b=2"},
+		{"https://example.com/Case/Sensitive", "https://example.com/Case/Sensitive"},
+		{"  not a url  ", "not a url"},
+	}
+	for _, tt := range tests {
+		if got := CanonicalLink(tt.link); got != tt.want {
+			t.Errorf("CanonicalLink(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestIsWebLink(t *testing.T) {
+	tests := []struct {
+		link string
+		want bool
+	}{
+		{"https://example.com/a", true},
+		{"http://example.com", true},
+		{"HTTPS://example.com/a", true},
+		{"Http://example.com", true},
+		{"ftp://example.com/file", false},
+		{"javascript:alert(1)", false},
+		{"https://", false},
+		{"example.com/a", false},
+		{"/relative/path", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isWebLink(tt.link); got != tt.want {
+			t.Errorf("isWebLink(%q) = %v, want %v", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestAdmit(t *testing.T) {
+	// The library holds one live item; a trashed one with another link
+	// isn't loaded, so importing it again brings it back.
+	seen := map[string]bool{CanonicalLink("https://www.youtube.com/watch?v=live"): true}
+	rows := []struct {
+		link  string
+		fresh bool
+		err   bool
+	}{
+		{"https://youtube.com/watch?v=live&si=share", false, false}, // already in the library
+		{"https://www.youtube.com/watch?v=trashed", true, false},    // only in the trash
+		{"HTTPS://M.YouTube.com/watch?v=trashed", false, false},     // same link again, any case
+		{"Https://example.com/new", true, false},                    // mixed-case scheme is fine
+		{"ftp://example.com/file", false, true},
+		{"  ", false, true},
+	}
+	for i, row := range rows {
+		fresh, rowErr := admit(Item{Row: i + 1, Link: row.link}, seen)
+		if fresh != row.fresh || (rowErr != nil) != row.err {
+			t.Errorf("admit(%q) = %v, %v; want fresh %v, error %v", row.link, fresh, rowErr, row.fresh, row.err)
+		}
+		if rowErr != nil && (rowErr.Row != i+1 || rowErr.Message == "") {
+			t.Errorf("admit(%q) reported %+v", row.link, rowErr)
+		}
+	}
+}
+
+func TestLiveUploadsSkipsTrash(t *testing.T) {
+	want := bson.M{"user_id": "u1", "deleted_at": nil}
+	if got := liveUploads("u1"); !reflect.DeepEqual(got, want) {
+		t.Errorf("liveUploads = %v, want %v", got, want)
+	}
+}
+
+func TestToUpload(t *testing.T) {
+	item := Item{Row: 1, Link: " https://www.tiktok.com/@chef/video/1 ", Title: "Soup", Tags: []string{"Food", "food", " "}, SavedAt: date("2024-01-02T03:04:05Z")}
+	u := toUpload("u1", item)
+	if u.UserID != "u1" || u.VideoLink != "https://www.tiktok.com/@chef/video/1" || u.Title != "Soup" {
+		t.Errorf("toUpload = %+v", u)
+	}
+	if u.Platform != "tiktok" {
+		t.Errorf("platform = %q, want tiktok", u.Platform)
+	}
+	if !reflect.DeepEqual(u.Tags, []string{"food"}) {
+		t.Errorf("tags = %v, want [food]", u.Tags)
+	}
+	if !u.SavedAt.Equal(item.SavedAt) || u.Folders == nil || u.ID.IsZero() {
+		t.Errorf("toUpload = %+v", u)
+	}
+	if u := toUpload("u1", Item{Link: "https://example.com"}); u.SavedAt.IsZero() {
+		t.Error("an item without a date wasn't dated now")
+	}
+}
@@ -0,0 +1,96 @@
+package importer
+
+import (
+	"context"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	"sort"
+	"strings"
+)
+
+const previewSampleSize = 20
+
+// PreviewResult is what an import would do, computed without saving anything.
+type PreviewResult struct {
+	Total      int                    `json:"total"`
+	New        int                    `json:"new"`
+	Duplicates int                    `json:"duplicates"`
+	Failed     int                    `json:"failed"`
+	Folders    []string               `json:"folders"`     // every folder the items are filed under, as "Parent/Child"
+	NewFolders []string               `json:"new_folders"` // the ones that don't exist yet, in full or in part
+	Tags       []string               `json:"tags"`
+	Sample     []Item                 `json:"sample"`
+	Errors     []model.ImportRowError `json:"errors"`
+}
+
+// Preview runs the same validation and deduplication as an import job and
+// reports the outcome, for a dry run before committing to the import.
+func Preview(ctx context.Context, userID string, items []Item, rowErrors []model.ImportRowError) (*PreviewResult, error) {
+	seen, err := existingLinks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	folders := newFolderResolver(userID)
+
+	result := &PreviewResult{
+		Total:  len(items) + len(rowErrors),
+		Failed: len(rowErrors),
+		Errors: capErrors(rowErrors),
+		Sample: []Item{},
+	}
+	folderSet := map[string]bool{}
+	newFolderSet := map[string]bool{}
+	tagSet := map[string]bool{}
+
+	for _, item := range items {
+		link := strings.TrimSpace(item.Link)
+		if !isWebLink(link) {
+			result.Failed++
+			result.Errors = appendError(result.Errors, model.ImportRowError{Row: item.Row, Link: link, Message: "link is not an http(s) URL"})
+			continue
+		}
+		key := CanonicalLink(link)
+		if seen[key] {
+			result.Duplicates++
+			continue
+		}
+		seen[key] = true
+		result.New++
+
+		if path := item.folderPath(); path != nil && !folderSet[strings.ToLower(strings.Join(path, "/"))] {
+			name := strings.Join(path, "/")
+			folderSet[strings.ToLower(name)] = true
+			result.Folders = append(result.Folders, name)
+			_, exists, err := folders.lookup(ctx, path)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				newFolderSet[name] = true
+			}
+		}
+		for _, tag := range library.NormalizeTags(item.Tags) {
+			tagSet[tag] = true
+		}
+		if len(result.Sample) < previewSampleSize {
+			result.Sample = append(result.Sample, item)
+		}
+	}
+
+	result.NewFolders = sortedKeys(newFolderSet)
+	result.Tags = sortedKeys(tagSet)
+	sort.Strings(result.Folders)
+	if result.Folders == nil {
+		result.Folders = []string{}
+	}
+	return result, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
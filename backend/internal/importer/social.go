@@ -0,0 +1,297 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	model "lyked-backend/internal/models/mongodb"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// parseTikTok reads the favorites from TikTok's user_data.json export.
+func parseTikTok(r io.Reader) ([]Item, []model.ImportRowError, error) {
+	type video struct {
+		Date string `json:"Date"`
+		Link string `json:"Link"`
+	}
+	type favorites struct {
+		FavoriteVideoList []video `json:"FavoriteVideoList"`
+	}
+	var export struct {
+		Activity struct {
+			Favorites favorites `json:"Favorite Videos"`
+		} `json:"Activity"`
+		YourActivity struct {
+			Favorites favorites `json:"Favorite Videos"`
+		} `json:"Your Activity"`
+	}
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, nil, fmt.Errorf("not a TikTok user_data.json file: %w", err)
+	}
+
+	videos := export.Activity.Favorites.FavoriteVideoList
+	if len(videos) == 0 {
+		videos = export.YourActivity.Favorites.FavoriteVideoList
+	}
+
+	var items []Item
+	var rowErrors []model.ImportRowError
+	for i, v := range videos {
+		row := i + 1
+		if v.Link == "" {
+			rowErrors = append(rowErrors, model.ImportRowError{Row: row, Message: "missing link"})
+			continue
+		}
+		savedAt, err := time.Parse("2006-01-02 15:04:05", v.Date)
+		if err != nil {
+			rowErrors = append(rowErrors, model.ImportRowError{Row: row, Link: v.Link, Message: fmt.Sprintf("invalid date %q", v.Date)})
+			continue
+		}
+		items = append(items, Item{Row: row, Link: v.Link, SavedAt: savedAt})
+	}
+	return items, rowErrors, nil
+}
+
+// parseInstagram reads Instagram's saved_posts.json export.
+func parseInstagram(r io.Reader) ([]Item, []model.ImportRowError, error) {
+	var export struct {
+		SavedMedia []struct {
+			Title         string `json:"title"`
+			StringMapData map[string]struct {
+				Href      string `json:"href"`
+				Timestamp int64  `json:"timestamp"`
+			} `json:"string_map_data"`
+		} `json:"saved_saved_media"`
+	}
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, nil, fmt.Errorf("not an Instagram saved_posts.json file: %w", err)
+	}
+
+	var items []Item
+	var rowErrors []model.ImportRowError
+	for i, media := range export.SavedMedia {
+		row := i + 1
+		// The key is localized ("Saved on", "Gespeichert am", ...), so take
+		// whichever entry carries the link.
+		var href string
+		var ts int64
+		for _, v := range media.StringMapData {
+			if v.Href != "" {
+				href, ts = v.Href, v.Timestamp
+				break
+			}
+		}
+		if href == "" {
+			rowErrors = append(rowErrors, model.ImportRowError{Row: row, Message: "missing link"})
+			continue
+		}
+		item := Item{Row: row, Link: href, Author: media.Title}
+		if ts > 0 {
+			item.SavedAt = time.Unix(ts, 0).UTC()
+		}
+		items = append(items, item)
+	}
+	return items, rowErrors, nil
+}
+
+var youtubeTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05 MST",
+	"Jan 2, 2006, 3:04:05 PM MST",
+}
+
+func parseYouTubeTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range youtubeTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func youtubeLink(videoID string) string {
+	return "https://www.youtube.com/watch?v=" + videoID
+}
+
+// parseYouTubeCSV reads a Google Takeout playlist CSV. Older exports start
+// with a playlist metadata block (whose Title names the folder) followed by
+// a blank line and the video rows; newer ones only have the video rows.
+func parseYouTubeCSV(r io.Reader) ([]Item, []model.ImportRowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a valid CSV file: %w", err)
+	}
+
+	playlist := ""
+	idCol, timeCol := -1, -1
+	var items []Item
+	var rowErrors []model.ImportRowError
+	for i, rec := range records {
+		row := i + 1
+		if idCol < 0 {
+			for col, name := range rec {
+				name = strings.ToLower(strings.TrimSpace(name))
+				switch {
+				case name == "video id":
+					idCol = col
+				case strings.Contains(name, "timestamp") || name == "time added":
+					timeCol = col
+				case name == "title" && i+1 < len(records) && col < len(records[i+1]):
+					playlist = strings.TrimSpace(records[i+1][col])
+				}
+			}
+			if idCol < 0 {
+				timeCol = -1
+			}
+			continue
+		}
+		if len(rec) <= idCol || strings.TrimSpace(rec[idCol]) == "" {
+			if len(rec) > 1 || (len(rec) == 1 && strings.TrimSpace(rec[0]) != "") {
+				rowErrors = append(rowErrors, model.ImportRowError{Row: row, Message: "missing video id"})
+			}
+			continue
+		}
+		item := Item{Row: row, Link: youtubeLink(strings.TrimSpace(rec[idCol])), Folder: playlist}
+		if timeCol >= 0 && timeCol < len(rec) {
+			if t, ok := parseYouTubeTime(rec[timeCol]); ok {
+				item.SavedAt = t
+			}
+		}
+		items = append(items, item)
+	}
+	if idCol < 0 {
+		return nil, nil, fmt.Errorf("no 'Video ID' column found")
+	}
+	return items, rowErrors, nil
+}
+
+// parseYouTubeJSON reads the JSON flavour of a Takeout playlist, a list of
+// playlistItem resources.
+func parseYouTubeJSON(r io.Reader) ([]Item, []model.ImportRowError, error) {
+	var export []struct {
+		Snippet struct {
+			Title                  string `json:"title"`
+			Description            string `json:"description"`
+			PublishedAt            string `json:"publishedAt"`
+			VideoOwnerChannelTitle string `json:"videoOwnerChannelTitle"`
+		} `json:"snippet"`
+		ContentDetails struct {
+			VideoID string `json:"videoId"`
+		} `json:"contentDetails"`
+	}
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, nil, fmt.Errorf("not a YouTube playlist JSON file: %w", err)
+	}
+
+	var items []Item
+	var rowErrors []model.ImportRowError
+	for i, v := range export {
+		row := i + 1
+		if v.ContentDetails.VideoID == "" {
+			rowErrors = append(rowErrors, model.ImportRowError{Row: row, Message: "missing video id"})
+			continue
+		}
+		item := Item{
+			Row:         row,
+			Link:        youtubeLink(v.ContentDetails.VideoID),
+			Title:       v.Snippet.Title,
+			Description: v.Snippet.Description,
+			Author:      v.Snippet.VideoOwnerChannelTitle,
+		}
+		// publishedAt on a playlist item is when it was added to the playlist.
+		if t, ok := parseYouTubeTime(v.Snippet.PublishedAt); ok {
+			item.SavedAt = t
+		}
+		items = append(items, item)
+	}
+	return items, rowErrors, nil
+}
+
+var (
+	htmlBreaks = regexp.MustCompile(`(?i)<\s*(br|/p|/div|/li|/tr|/h\d)\s*/?>`)
+	htmlTags   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// parsePinterest reads Pinterest's data export, an HTML (or plain text)
+// document listing each pin as "Key: value" lines. A record ends when a
+// key it already has appears again.
+func parsePinterest(r io.Reader) ([]Item, []model.ImportRowError, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	text := htmlBreaks.ReplaceAllString(string(raw), "\n")
+	text = html.UnescapeString(htmlTags.ReplaceAllString(text, ""))
+
+	var items []Item
+	var rowErrors []model.ImportRowError
+	record := map[string]string{}
+	flush := func() {
+		if len(record) == 0 {
+			return
+		}
+		row := len(items) + len(rowErrors) + 1
+		link := record["canonical link"]
+		if link == "" {
+			link = record["link"]
+		}
+		if link == "" {
+			rowErrors = append(rowErrors, model.ImportRowError{Row: row, Message: "missing link"})
+		} else {
+			item := Item{
+				Row:         row,
+				Link:        link,
+				Title:       record["title"],
+				Description: record["details"],
+				Folder:      record["board name"],
+			}
+			if t, ok := parsePinterestTime(record["created at"]); ok {
+				item.SavedAt = t
+			}
+			items = append(items, item)
+		}
+		record = map[string]string{}
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		switch key {
+		case "title", "details", "link", "canonical link", "board name", "created at":
+		default:
+			continue
+		}
+		if _, seen := record[key]; seen {
+			flush()
+		}
+		record[key] = strings.TrimSpace(value)
+	}
+	flush()
+	if len(items) == 0 && len(rowErrors) == 0 {
+		return nil, nil, fmt.Errorf("no pins found in Pinterest export")
+	}
+	return items, rowErrors, nil
+}
+
+func parsePinterestTime(s string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,142 @@
+package importer
+
+import (
+	model "lyked-backend/internal/models/mongodb"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseFixture runs the source's parser on a file from testdata.
+func parseFixture(t *testing.T, source, name string) ([]Item, []model.ImportRowError) {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	items, rowErrors, err := Parse(source, f)
+	if err != nil {
+		t.Fatalf("Parse(%s, %s): %v", source, name, err)
+	}
+	return items, rowErrors
+}
+
+func checkParsed(t *testing.T, items []Item, rowErrors []model.ImportRowError, wantItems []Item, wantErrors []model.ImportRowError) {
+	t.Helper()
+	if !reflect.DeepEqual(items, wantItems) {
+		t.Errorf("items:\n got %+v\nwant %+v", items, wantItems)
+	}
+	if !reflect.DeepEqual(rowErrors, wantErrors) {
+		t.Errorf("row errors:\n got %+v\nwant %+v", rowErrors, wantErrors)
+	}
+}
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t.UTC()
+}
+
+func TestParseTikTok(t *testing.T) {
+	items, rowErrors := parseFixture(t, "tiktok", "tiktok_user_data.json")
+	checkParsed(t, items, rowErrors, []Item{
+		{Row: 1, Link: "https://www.tiktokv.com/share/video/7341234567890123456/", SavedAt: date("2024-03-01T18:22:05Z")},
+		{Row: 4, Link: "https://www.tiktok.com/@chef/video/7316000000000000000", SavedAt: date("2023-12-24T23:59:59Z")},
+	}, []model.ImportRowError{
+		{Row: 2, Message: "missing link"},
+		{Row: 3, Link: "https://www.tiktokv.com/share/video/7340000000000000000/", Message: `invalid date "yesterday"`},
+	})
+
+	// Newer exports nest the favorites under "Your Activity".
+	items, rowErrors = parseFixture(t, "tiktok", "tiktok_your_activity.json")
+	checkParsed(t, items, rowErrors, []Item{
+		{Row: 1, Link: "https://www.tiktokv.com/share/video/7456000000000000000/", SavedAt: date("2025-01-05T07:30:00Z")},
+	}, nil)
+}
+
+func TestParseInstagram(t *testing.T) {
+	items, rowErrors := parseFixture(t, "instagram", "instagram_saved_posts.json")
+	checkParsed(t, items, rowErrors, []Item{
+		{Row: 1, Link: "https://www.instagram.com/p/C3abcDEF123/", Author: "bakingwithjo", SavedAt: date("2024-03-01T19:30:00Z")},
+		{Row: 2, Link: "https://www.instagram.com/reel/C4xyz987/", Author: "wanderer"}, // localized key, no timestamp
+	}, []model.ImportRowError{
+		{Row: 3, Message: "missing link"},
+	})
+}
+
+func TestParseYouTubeCSV(t *testing.T) {
+	// Older Takeout files start with the playlist, whose title names the
+	// folder. The CSV reader skips the blank line after it.
+	items, rowErrors := parseFixture(t, "youtube_csv", "youtube_playlist_old.csv")
+	checkParsed(t, items, rowErrors, []Item{
+		{Row: 4, Link: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", Folder: "Weeknight Dinners", SavedAt: date("2024-02-03T04:05:06Z")},
+		{Row: 6, Link: "https://www.youtube.com/watch?v=M7lc1UVf-VE", Folder: "Weeknight Dinners"},
+	}, []model.ImportRowError{
+		{Row: 5, Message: "missing video id"},
+	})
+
+	items, rowErrors = parseFixture(t, "youtube_csv", "youtube_playlist.csv")
+	checkParsed(t, items, rowErrors, []Item{
+		{Row: 2, Link: "https://www.youtube.com/watch?v=aqz-KE-bpKQ", SavedAt: date("2024-05-06T07:08:09Z")},
+		{Row: 3, Link: "https://www.youtube.com/watch?v=jNQXAC9IVRw"},
+	}, nil)
+
+	if _, _, err := Parse("youtube_csv", strings.NewReader("Title,URL\nx,https://example.com\n")); err == nil {
+		t.Error("a CSV without a Video ID column was accepted")
+	}
+}
+
+func TestParseYouTubeJSON(t *testing.T) {
+	items, rowErrors := parseFixture(t, "youtube_json", "youtube_playlist.json")
+	checkParsed(t, items, rowErrors, []Item{
+		{
+			Row:         1,
+			Link:        "https://www.youtube.com/watch?v=abc123DEF45",
+			Title:       "Knife skills in 10 minutes",
+			Description: "Dice, julienne, chiffonade.",
+			Author:      "Chef Lab",
+			SavedAt:     date("2024-04-01T12:00:00Z"),
+		},
+		{Row: 3, Link: "https://www.youtube.com/watch?v=zzz999YYY88", Title: "No date"},
+	}, []model.ImportRowError{
+		{Row: 2, Message: "missing video id"},
+	})
+}
+
+func TestParsePinterest(t *testing.T) {
+	items, rowErrors := parseFixture(t, "pinterest", "pinterest.html")
+	checkParsed(t, items, rowErrors, []Item{
+		{
+			Row:         1,
+			Link:        "https://www.pinterest.com/pin/111/", // the canonical link wins
+			Title:       "Sourdough & Rye",
+			Description: "Overnight starter schedule",
+			Folder:      "Bread",
+			SavedAt:     date("2023-09-10T08:00:00Z"),
+		},
+		{Row: 3, Link: "https://example.org/plain", Title: "Plain link", SavedAt: date("2023-09-11T00:00:00Z")},
+	}, []model.ImportRowError{
+		{Row: 2, Message: "missing link"},
+	})
+
+	if _, _, err := Parse("pinterest", strings.NewReader("<html><body>nothing</body></html>")); err == nil {
+		t.Error("an export without pins was accepted")
+	}
+}
+
+func TestParseUnreadable(t *testing.T) {
+	for _, source := range []string{"tiktok", "instagram", "youtube_json"} {
+		if _, _, err := Parse(source, strings.NewReader("not json")); err == nil {
+			t.Errorf("%s accepted a file that isn't JSON", source)
+		}
+	}
+	if _, _, err := Parse("myspace", strings.NewReader("")); err == nil {
+		t.Error("an unknown source was accepted")
+	}
+}
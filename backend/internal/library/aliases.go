@@ -0,0 +1,153 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	DB "lyked-backend/internal/database/mongodb"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/utils"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrAliasSelf   = errors.New("a tag can't be an alias of itself")
+	ErrAliasNested = errors.New("an alias can't be above or below its tag")
+)
+
+// TagAliases maps a user's aliases onto their canonical tags.
+type TagAliases map[string]string
+
+// LoadTagAliases returns the user's aliases. Without an aliases collection
+// there are none.
+func LoadTagAliases(ctx context.Context, userID string) (TagAliases, error) {
+	aliases := TagAliases{}
+	coll, err := DB.GetCollection("tag_aliases")
+	if err != nil {
+		return aliases, nil
+	}
+	list, err := ListTagAliases(ctx, coll, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range list {
+		aliases[a.Alias] = a.Tag
+	}
+	return aliases, nil
+}
+
+// ResolveTags normalizes the tags and resolves them through the user's
+// aliases.
+func ResolveTags(ctx context.Context, userID string, tags []string) ([]string, error) {
+	aliases, err := LoadTagAliases(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return aliases.ResolveAll(tags), nil
+}
+
+// Resolve normalizes a tag and replaces the deepest alias it starts with:
+// with recipes aliased to food/recipes, recipes/pasta is food/recipes/pasta.
+func (a TagAliases) Resolve(tag string) string {
+	tag = utils.NormalizeTag(tag)
+	for prefix := tag; prefix != ""; {
+		if canonical, ok := a[prefix]; ok {
+			return canonical + strings.TrimPrefix(tag, prefix)
+		}
+		i := strings.LastIndex(prefix, utils.TagSeparator)
+		if i < 0 {
+			break
+		}
+		prefix = prefix[:i]
+	}
+	return tag
+}
+
+// ResolveAll is NormalizeTags with every tag resolved through the aliases.
+func (a TagAliases) ResolveAll(tags []string) []string {
+	resolved := make([]string, len(tags))
+	for i, tag := range tags {
+		resolved[i] = a.Resolve(tag)
+	}
+	return NormalizeTags(resolved)
+}
+
+// ListTagAliases returns the user's aliases by name.
+func ListTagAliases(ctx context.Context, aliases *mongo.Collection, userID string) ([]model.TagAlias, error) {
+	cursor, err := aliases.Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.D{{Key: "alias", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tag aliases: %w", err)
+	}
+	list := []model.TagAlias{}
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse tag aliases: %w", err)
+	}
+	return list, nil
+}
+
+// SetTagAlias makes alias stand for tag, or points an existing alias at a
+// new tag. Items already carrying the alias, or tags below it, are merged
+// into the tag, and aliases that pointed at the alias follow it. It
+// returns the alias and the ids of the items that changed.
+func SetTagAlias(ctx context.Context, uploads, folders, meta, aliases *mongo.Collection, userID, alias, tag string, now time.Time) (*model.TagAlias, []bson.ObjectID, error) {
+	alias = utils.NormalizeTag(alias)
+	if alias == "" || utils.NormalizeTag(tag) == "" {
+		return nil, nil, ErrTagRequired
+	}
+
+	result := &model.TagAlias{UserID: userID, Alias: alias}
+	var changed []bson.ObjectID
+	err := DB.WithTransaction(ctx, func(ctx context.Context) error {
+		existing, err := ListTagAliases(ctx, aliases, userID)
+		if err != nil {
+			return err
+		}
+		current := TagAliases{}
+		for _, a := range existing {
+			if a.Alias != alias {
+				current[a.Alias] = a.Tag
+			}
+		}
+		// An alias never points at another alias.
+		canonical := current.Resolve(tag)
+		if canonical == alias {
+			return ErrAliasSelf
+		}
+		if utils.TagWithin(canonical, alias) || utils.TagWithin(alias, canonical) {
+			return ErrAliasNested
+		}
+
+		err = aliases.FindOneAndUpdate(ctx, bson.M{"user_id": userID, "alias": alias},
+			bson.M{"$set": bson.M{"tag": canonical, "updated_at": now}, "$setOnInsert": bson.M{"created_at": now}},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(result)
+		if err != nil {
+			return fmt.Errorf("failed to save tag alias: %w", err)
+		}
+		// Merging also repoints aliases of the alias and of tags below it.
+		changed, err = MergeTags(ctx, uploads, folders, meta, userID, []string{alias}, canonical, now)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, changed, nil
+}
+
+// DeleteTagAliases removes aliases. Items keep the tags the aliases were
+// resolved to. It returns how many aliases were removed.
+func DeleteTagAliases(ctx context.Context, aliases *mongo.Collection, userID string, names []string) (int64, error) {
+	names = NormalizeTags(names)
+	if len(names) == 0 {
+		return 0, ErrTagRequired
+	}
+	res, err := aliases.DeleteMany(ctx, bson.M{"user_id": userID, "alias": bson.M{"$in": names}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete tag aliases: %w", err)
+	}
+	return res.DeletedCount, nil
+}
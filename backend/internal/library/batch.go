@@ -0,0 +1,454 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	DB "lyked-backend/internal/database/mongodb"
+	model "lyked-backend/internal/models/mongodb"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MaxBatchSize caps how many uploads one batch request may touch.
+const MaxBatchSize = 500
+
+const (
+	OpAddTags          = "add_tags"
+	OpRemoveTags       = "remove_tags"
+	OpAddToFolder      = "add_to_folder"
+	OpMoveToFolder     = "move_to_folder" // leaves the item in this folder only
+	OpRemoveFromFolder = "remove_from_folder"
+	OpDelete           = "delete"
+	OpRestore          = "restore"
+	OpMarkWatched      = "mark_watched"
+	OpMarkUnwatched    = "mark_unwatched"
+	OpFavorite         = "favorite"
+	OpUnfavorite       = "unfavorite"
+	OpSetRating        = "set_rating" // 0 clears the rating
+	OpSetStatus        = "set_status"
+)
+
+// Per-item outcomes reported by ApplyBatch.
+const (
+	BatchUpdated   = "updated"
+	BatchUnchanged = "unchanged"
+	BatchNotFound  = "not_found"
+	BatchInvalidID = "invalid_id"
+	BatchSkipped   = "skipped"
+)
+
+var (
+	ErrBatchTooLarge  = fmt.Errorf("a batch may touch at most %d items", MaxBatchSize)
+	ErrFolderNotFound = errors.New("folder not found")
+	ErrSmartFolder    = errors.New("smart folders are filled by their filter")
+)
+
+// BatchRequest selects uploads either by id or by a listing filter and
+// names one operation to apply to all of them.
+type BatchRequest struct {
+	Operation string       `json:"operation"`
+	IDs       []string     `json:"ids"`
+	Filter    *BatchFilter `json:"filter"`
+	Tags      []string     `json:"tags"`      // for add_tags/remove_tags
+	FolderID  string       `json:"folder_id"` // for the folder operations
+	Rating    *int         `json:"rating"`    // for set_rating
+	Status    string       `json:"status"`    // for set_status
+}
+
+// BatchFilter takes the same fields as the GET /upload/all query params.
+type BatchFilter struct {
+	Folder     string   `json:"folder"`
+	Tags       []string `json:"tags"`
+	Platform   string   `json:"platform"`
+	LinkStatus []string `json:"link_status"`
+	Favorite   *bool    `json:"favorite"`
+	MinRating  int      `json:"min_rating"`
+	Status     []string `json:"status"`
+	From       string   `json:"from"`
+	To         string   `json:"to"`
+	Q          string   `json:"q"`
+}
+
+type BatchItemResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BatchResult struct {
+	Operation string            `json:"operation"`
+	Matched   int               `json:"matched"`
+	Updated   int               `json:"updated"`
+	Results   []BatchItemResult `json:"results"`
+}
+
+// Validate checks the request shape before anything is read from the database.
+func (r *BatchRequest) Validate() error {
+	switch r.Operation {
+	case OpAddTags, OpRemoveTags:
+		r.Tags = NormalizeTags(r.Tags)
+		if len(r.Tags) == 0 {
+			return fmt.Errorf("%s needs at least one tag", r.Operation)
+		}
+	case OpAddToFolder, OpMoveToFolder, OpRemoveFromFolder:
+		if r.FolderID == "" {
+			return fmt.Errorf("%s needs a folder_id", r.Operation)
+		}
+	case OpSetRating:
+		if r.Rating == nil {
+			return fmt.Errorf("%s needs a rating", r.Operation)
+		}
+		if err := validateRating(*r.Rating, true); err != nil {
+			return err
+		}
+	case OpSetStatus:
+		if err := ValidateStatus(r.Status); err != nil {
+			return err
+		}
+	case OpDelete, OpRestore, OpMarkWatched, OpMarkUnwatched, OpFavorite, OpUnfavorite:
+	case "":
+		return fmt.Errorf("operation is required")
+	default:
+		return fmt.Errorf("unsupported operation %q", r.Operation)
+	}
+
+	if (len(r.IDs) == 0) == (r.Filter == nil) {
+		return fmt.Errorf("provide either ids or a filter")
+	}
+	if len(r.IDs) > MaxBatchSize {
+		return ErrBatchTooLarge
+	}
+	if r.Filter != nil {
+		if _, err := r.Filter.listOptions(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchTarget is the part of an upload the operations need to decide
+// whether an item changes.
+type batchTarget struct {
+	ID        bson.ObjectID `bson:"_id"`
+	Tags      []string      `bson:"tags"`
+	Folders   []string      `bson:"folders"`
+	WatchedAt *time.Time    `bson:"watched_at"`
+	Favorite  bool          `bson:"favorite"`
+	Rating    int           `bson:"rating"`
+	Status    string        `bson:"status"`
+	DeletedAt *time.Time    `bson:"deleted_at"`
+}
+
+// ApplyBatch runs a validated request against the user's own uploads. Items
+// that need no change are reported as unchanged; the rest are written with
+// a single UpdateMany. The whole batch is one transaction, so a folder
+// can't be purged between being checked and being filed into.
+func ApplyBatch(ctx context.Context, uploads, folders *mongo.Collection, userID string, req BatchRequest, now time.Time) (*BatchResult, error) {
+	var result *BatchResult
+	err := DB.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = applyBatch(ctx, uploads, folders, userID, req, now)
+		return err
+	})
+	return result, err
+}
+
+func applyBatch(ctx context.Context, uploads, folders *mongo.Collection, userID string, req BatchRequest, now time.Time) (*BatchResult, error) {
+	if len(req.Tags) > 0 {
+		aliases, err := LoadTagAliases(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		req.Tags = aliases.ResolveAll(req.Tags)
+	}
+	folderID := ""
+	scope := bson.M{"user_id": userID}
+	if req.FolderID != "" {
+		folder, role, err := staticFolder(ctx, folders, userID, req.FolderID, model.RoleContributor)
+		if err != nil {
+			return nil, err
+		}
+		folderID = folder.ID.Hex()
+		// Editors take anyone's items out of a shared folder; everybody
+		// else only ever changes their own.
+		if req.Operation == OpRemoveFromFolder && RoleAtLeast(role, model.RoleEditor) {
+			scope = bson.M{"$or": bson.A{bson.M{"user_id": userID}, bson.M{"folders": folderID}}}
+		}
+	}
+
+	targets, results, err := loadBatchTargets(ctx, uploads, userID, scope, req)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BatchResult{Operation: req.Operation, Matched: len(targets)}
+	var changed, filed []bson.ObjectID
+	for _, t := range targets {
+		switch {
+		case t.DeletedAt != nil && req.Operation != OpRestore:
+			results = append(results, BatchItemResult{ID: t.ID.Hex(), Status: BatchSkipped, Error: "item is in the trash"})
+		case !needsChange(t, req, folderID):
+			results = append(results, BatchItemResult{ID: t.ID.Hex(), Status: BatchUnchanged})
+		default:
+			changed = append(changed, t.ID)
+			results = append(results, BatchItemResult{ID: t.ID.Hex(), Status: BatchUpdated})
+			if (req.Operation == OpAddToFolder || req.Operation == OpMoveToFolder) && !slices.Contains(t.Folders, folderID) {
+				filed = append(filed, t.ID)
+			}
+		}
+	}
+	result.Results = results
+	if len(changed) == 0 {
+		return result, nil
+	}
+
+	filter := bson.M{"$and": bson.A{scope, bson.M{"_id": bson.M{"$in": changed}}}}
+	if _, err := uploads.UpdateMany(ctx, filter, batchUpdate(req, userID, folderID, now)); err != nil {
+		return nil, fmt.Errorf("failed to update items: %w", err)
+	}
+	// Newly filed items go to the end of the folder's manual order.
+	if err := AppendToFolder(ctx, uploads, folderID, inRequestOrder(filed, req.IDs)); err != nil {
+		return nil, err
+	}
+	result.Updated = len(changed)
+	return result, nil
+}
+
+// loadBatchTargets returns the uploads in scope that the request selects,
+// plus results for requested ids that can't be used.
+func loadBatchTargets(ctx context.Context, uploads *mongo.Collection, userID string, scope bson.M, req BatchRequest) ([]batchTarget, []BatchItemResult, error) {
+	results := []BatchItemResult{}
+	var filter bson.M
+
+	if req.Filter != nil {
+		opts, err := req.Filter.listOptions()
+		if err != nil {
+			return nil, nil, err
+		}
+		opts.UserID = userID
+		if err := opts.resolveTags(ctx); err != nil {
+			return nil, nil, err
+		}
+		filter = opts.Filter()
+		if req.Operation == OpRestore {
+			filter["deleted_at"] = bson.M{"$ne": nil}
+		}
+		delete(filter, "user_id")
+		filter = bson.M{"$and": bson.A{scope, filter}}
+	} else {
+		seen := map[string]bool{}
+		var ids []bson.ObjectID
+		for _, raw := range req.IDs {
+			if seen[raw] {
+				continue
+			}
+			seen[raw] = true
+			id, err := bson.ObjectIDFromHex(raw)
+			if err != nil {
+				results = append(results, BatchItemResult{ID: raw, Status: BatchInvalidID})
+				continue
+			}
+			ids = append(ids, id)
+		}
+		filter = bson.M{"$and": bson.A{scope, bson.M{"_id": bson.M{"$in": ids}}}}
+	}
+
+	cursor, err := uploads.Find(ctx, filter, options.Find().
+		SetProjection(bson.M{"tags": 1, "folders": 1, "watched_at": 1, "favorite": 1, "rating": 1, "status": 1, "deleted_at": 1}).
+		SetLimit(MaxBatchSize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load items: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var targets []batchTarget
+	if err := cursor.All(ctx, &targets); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse items: %w", err)
+	}
+	if len(targets) > MaxBatchSize {
+		return nil, nil, ErrBatchTooLarge
+	}
+
+	if req.Filter == nil {
+		found := map[string]bool{}
+		for _, t := range targets {
+			found[t.ID.Hex()] = true
+		}
+		for _, raw := range req.IDs {
+			id, err := bson.ObjectIDFromHex(raw)
+			if err == nil && !found[id.Hex()] {
+				found[id.Hex()] = true // report duplicates once
+				results = append(results, BatchItemResult{ID: raw, Status: BatchNotFound})
+			}
+		}
+	}
+	return targets, results, nil
+}
+
+func (f *BatchFilter) listOptions() (ListOptions, error) {
+	params := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			params.Set(key, value)
+		}
+	}
+	set("folder", f.Folder)
+	set("platform", f.Platform)
+	set("from", f.From)
+	set("to", f.To)
+	set("q", f.Q)
+	for _, tag := range f.Tags {
+		params.Add("tag", tag)
+	}
+	for _, status := range f.LinkStatus {
+		params.Add("link_status", status)
+	}
+	if f.Favorite != nil {
+		params.Set("favorite", fmt.Sprint(*f.Favorite))
+	}
+	if f.MinRating != 0 {
+		params.Set("min_rating", fmt.Sprint(f.MinRating))
+	}
+	for _, status := range f.Status {
+		params.Add("status", status)
+	}
+	return ParseListParams(params)
+}
+
+func needsChange(t batchTarget, req BatchRequest, folderID string) bool {
+	switch req.Operation {
+	case OpAddTags:
+		for _, tag := range req.Tags {
+			if !slices.Contains(t.Tags, tag) {
+				return true
+			}
+		}
+		return false
+	case OpRemoveTags:
+		for _, tag := range req.Tags {
+			if slices.Contains(t.Tags, tag) {
+				return true
+			}
+		}
+		return false
+	case OpAddToFolder:
+		return !slices.Contains(t.Folders, folderID)
+	case OpMoveToFolder:
+		return len(t.Folders) != 1 || t.Folders[0] != folderID
+	case OpRemoveFromFolder:
+		return slices.Contains(t.Folders, folderID)
+	case OpDelete:
+		return t.DeletedAt == nil
+	case OpRestore:
+		return t.DeletedAt != nil
+	case OpMarkWatched:
+		return t.Status != model.WatchStatusWatched
+	case OpMarkUnwatched:
+		return t.Status != "" && t.Status != model.WatchStatusUnwatched
+	case OpFavorite:
+		return !t.Favorite
+	case OpUnfavorite:
+		return t.Favorite
+	case OpSetRating:
+		return t.Rating != *req.Rating
+	case OpSetStatus:
+		current := t.Status
+		if current == "" {
+			current = model.WatchStatusUnwatched
+		}
+		return current != req.Status
+	}
+	return false
+}
+
+func batchUpdate(req BatchRequest, userID, folderID string, now time.Time) bson.M {
+	switch req.Operation {
+	case OpAddTags:
+		return bson.M{"$addToSet": bson.M{"tags": bson.M{"$each": req.Tags}}}
+	case OpRemoveTags:
+		return bson.M{"$pull": bson.M{"tags": bson.M{"$in": req.Tags}}}
+	case OpAddToFolder:
+		return bson.M{"$addToSet": bson.M{"folders": folderID}}
+	case OpMoveToFolder:
+		return bson.M{"$set": bson.M{"folders": []string{folderID}}}
+	case OpRemoveFromFolder:
+		return bson.M{"$pull": bson.M{"folders": folderID}, "$unset": bson.M{"positions." + folderID: ""}}
+	case OpDelete:
+		return TrashUpdate(userID, now)
+	case OpRestore:
+		return RestoreUpdate()
+	case OpMarkWatched:
+		return statusUpdate(model.WatchStatusWatched, now)
+	case OpMarkUnwatched:
+		return statusUpdate(model.WatchStatusUnwatched, now)
+	case OpSetStatus:
+		return statusUpdate(req.Status, now)
+	case OpFavorite:
+		return bson.M{"$set": bson.M{"favorite": true, "favorited_at": now}}
+	case OpUnfavorite:
+		return bson.M{"$unset": bson.M{"favorite": "", "favorited_at": ""}}
+	case OpSetRating:
+		if *req.Rating == 0 {
+			return bson.M{"$unset": bson.M{"rating": "", "rated_at": ""}}
+		}
+		return bson.M{"$set": bson.M{"rating": *req.Rating, "rated_at": now}}
+	}
+	return bson.M{}
+}
+
+// inRequestOrder sorts ids the way the request listed them, if it did.
+func inRequestOrder(ids []bson.ObjectID, requested []string) []bson.ObjectID {
+	if len(requested) == 0 {
+		return ids
+	}
+	index := make(map[string]int, len(requested))
+	for i, raw := range requested {
+		if _, ok := index[strings.ToLower(raw)]; !ok {
+			index[strings.ToLower(raw)] = i
+		}
+	}
+	slices.SortStableFunc(ids, func(a, b bson.ObjectID) int {
+		return index[a.Hex()] - index[b.Hex()]
+	})
+	return ids
+}
+
+// statusUpdate sets the watch status the same way UpdateState does.
+func statusUpdate(status string, now time.Time) bson.M {
+	update := bson.M{"$set": bson.M{"status": status, "status_changed_at": now}}
+	switch status {
+	case model.WatchStatusWatched:
+		update["$set"].(bson.M)["watched_at"] = now
+	case model.WatchStatusUnwatched:
+		update["$unset"] = bson.M{"watched_at": ""}
+	}
+	return update
+}
+
+// staticFolderID checks that the folder exists, the user may file into it
+// and it holds items by membership. Smart folders can't be filed into.
+func staticFolderID(ctx context.Context, folders *mongo.Collection, userID, rawID string) (string, error) {
+	folder, _, err := staticFolder(ctx, folders, userID, rawID, model.RoleContributor)
+	if err != nil {
+		return "", err
+	}
+	return folder.ID.Hex(), nil
+}
+
+func staticFolder(ctx context.Context, folders *mongo.Collection, userID, rawID, min string) (*model.Folder, string, error) {
+	folder, role, err := AccessFolder(ctx, folders, userID, rawID, min)
+	if err != nil {
+		return nil, "", err
+	}
+	if folder.IsSmart() {
+		return nil, "", ErrSmartFolder
+	}
+	return folder, role, nil
+}
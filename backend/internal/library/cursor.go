@@ -0,0 +1,61 @@
+package library
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks the last item of a page. It is handed to clients as an
+// opaque base64 string and only makes sense for the sort it was made with.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(s string) (Cursor, error) {
+	var c Cursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	if _, ok := sortFields[c.Sort]; !ok {
+		return c, ErrInvalidCursor
+	}
+	if _, err := bson.ObjectIDFromHex(c.ID); err != nil {
+		return c, ErrInvalidCursor
+	}
+	if isTimeSort(c.Sort) {
+		if _, err := time.Parse(time.RFC3339Nano, c.Value); err != nil {
+			return c, ErrInvalidCursor
+		}
+	}
+	return c, nil
+}
+
+// sortValue returns the value the cursor should compare against in Mongo.
+func (c Cursor) sortValue() interface{} {
+	if isTimeSort(c.Sort) {
+		t, _ := time.Parse(time.RFC3339Nano, c.Value)
+		return t
+	}
+	return c.Value
+}
+
+func isTimeSort(sort string) bool {
+	return sort == SortSavedAt || sort == SortDeletedAt
+}
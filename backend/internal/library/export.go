@@ -0,0 +1,200 @@
+package library
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/notes"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	ExportJSON     = "json"
+	ExportMarkdown = "markdown"
+)
+
+// ExportItem is one saved item as written to an export, with folder names
+// in place of IDs so the file stands on its own.
+type ExportItem struct {
+	ID          string             `json:"id"`
+	Title       string             `json:"title"`
+	Description string             `json:"description,omitempty"`
+	Link        string             `json:"link"`
+	Platform    string             `json:"platform,omitempty"`
+	Author      string             `json:"author,omitempty"`
+	Tags        []string           `json:"tags"`
+	Folders     []string           `json:"folders"`
+	SavedAt     time.Time          `json:"saved_at"`
+	Favorite    bool               `json:"favorite"`
+	Rating      int                `json:"rating,omitempty"`
+	Status      string             `json:"status"`
+	WatchedAt   *time.Time         `json:"watched_at,omitempty"`
+	Notes       []model.Note       `json:"notes"`
+	Annotations []model.Annotation `json:"annotations"`
+}
+
+// Export streams every upload matching opts to w, in opts' sort order.
+// Paging options are ignored; an export is always the whole selection.
+func Export(ctx context.Context, uploads, folders *mongo.Collection, opts ListOptions, format string, w io.Writer) error {
+	opts.Cursor = nil
+	if err := opts.Normalize(); err != nil {
+		return err
+	}
+	if err := opts.resolveTags(ctx); err != nil {
+		return err
+	}
+	names, err := folderNames(ctx, folders, opts.UserID)
+	if err != nil {
+		return err
+	}
+	cursor, err := uploads.Find(ctx, opts.Filter(), options.Find().SetSort(opts.sortSpec()))
+	if err != nil {
+		return fmt.Errorf("failed to fetch uploads: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	out := bufio.NewWriter(w)
+	var write func(ExportItem, bool) error
+	switch format {
+	case ExportJSON:
+		fmt.Fprintf(out, "{\"exported_at\":%q,\"items\":[", time.Now().UTC().Format(time.RFC3339))
+		write = func(item ExportItem, first bool) error {
+			if !first {
+				out.WriteString(",")
+			}
+			return json.NewEncoder(out).Encode(item)
+		}
+	case ExportMarkdown:
+		fmt.Fprintf(out, "# Lyked export\n\nExported %s\n", time.Now().UTC().Format("2006-01-02 15:04 MST"))
+		write = func(item ExportItem, _ bool) error {
+			writeMarkdown(out, item)
+			return nil
+		}
+	default:
+		return fmt.Errorf("unknown export format %q, expected %s or %s", format, ExportJSON, ExportMarkdown)
+	}
+
+	first := true
+	for cursor.Next(ctx) {
+		var upload model.LykedUploads
+		if err := cursor.Decode(&upload); err != nil {
+			return fmt.Errorf("failed to parse upload: %w", err)
+		}
+		if err := write(exportItem(upload, names), first); err != nil {
+			return err
+		}
+		first = false
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to fetch uploads: %w", err)
+	}
+	if format == ExportJSON {
+		out.WriteString("]}\n")
+	}
+	return out.Flush()
+}
+
+func exportItem(u model.LykedUploads, names map[string]string) ExportItem {
+	item := ExportItem{
+		ID:          u.ID.Hex(),
+		Title:       u.Title,
+		Description: u.Description,
+		Link:        u.VideoLink,
+		Platform:    u.Platform,
+		Author:      u.Author,
+		Tags:        u.Tags,
+		Folders:     []string{},
+		SavedAt:     u.SavedAt,
+		Favorite:    u.Favorite,
+		Rating:      u.Rating,
+		Status:      StatusOf(u),
+		WatchedAt:   u.WatchedAt,
+		Notes:       u.Notes,
+		Annotations: u.Annotations,
+	}
+	if item.Tags == nil {
+		item.Tags = []string{}
+	}
+	if item.Notes == nil {
+		item.Notes = []model.Note{}
+	}
+	if item.Annotations == nil {
+		item.Annotations = []model.Annotation{}
+	}
+	for _, id := range u.Folders {
+		if name, ok := names[id]; ok {
+			item.Folders = append(item.Folders, name)
+		}
+	}
+	return item
+}
+
+func writeMarkdown(out *bufio.Writer, item ExportItem) {
+	title := item.Title
+	if title == "" {
+		title = item.Link
+	}
+	fmt.Fprintf(out, "\n## [%s](%s)\n\n", strings.ReplaceAll(title, "]", `\]`), item.Link)
+
+	var meta []string
+	if item.Platform != "" {
+		meta = append(meta, "Platform: "+item.Platform)
+	}
+	if item.Author != "" {
+		meta = append(meta, "Author: "+item.Author)
+	}
+	meta = append(meta, "Saved: "+item.SavedAt.UTC().Format("2006-01-02"))
+	meta = append(meta, "Status: "+item.Status)
+	if item.Favorite {
+		meta = append(meta, "Favorite: yes")
+	}
+	if item.Rating > 0 {
+		meta = append(meta, fmt.Sprintf("Rating: %s", strings.Repeat("★", item.Rating)))
+	}
+	if len(item.Tags) > 0 {
+		meta = append(meta, "Tags: "+strings.Join(item.Tags, ", "))
+	}
+	if len(item.Folders) > 0 {
+		meta = append(meta, "Folders: "+strings.Join(item.Folders, ", "))
+	}
+	for _, m := range meta {
+		fmt.Fprintf(out, "- %s\n", m)
+	}
+	if item.Description != "" {
+		fmt.Fprintf(out, "\n%s\n", item.Description)
+	}
+	if len(item.Annotations) > 0 {
+		out.WriteString("\n### Annotations\n\n")
+		for _, a := range item.Annotations {
+			fmt.Fprintf(out, "- **%s** — %s\n", notes.FormatTimestamp(a.At), a.Text)
+		}
+	}
+	for _, n := range item.Notes {
+		fmt.Fprintf(out, "\n### Note (%s)\n\n%s\n", n.UpdatedAt.UTC().Format("2006-01-02"), n.Body)
+	}
+}
+
+func folderNames(ctx context.Context, folders *mongo.Collection, userID string) (map[string]string, error) {
+	cursor, err := folders.Find(ctx, bson.M{"user_id": userID, "deleted_at": nil},
+		options.Find().SetProjection(bson.M{"name": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch folders: %w", err)
+	}
+	var found []model.Folder
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, fmt.Errorf("failed to parse folders: %w", err)
+	}
+	names := make(map[string]string, len(found))
+	for _, f := range found {
+		names[f.ID.Hex()] = f.Name
+	}
+	return names, nil
+}
@@ -0,0 +1,360 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	DB "lyked-backend/internal/database/mongodb"
+	model "lyked-backend/internal/models/mongodb"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MaxFolderDepth is how many levels folders can be nested.
+const MaxFolderDepth = 8
+
+var (
+	ErrFolderCycle     = errors.New("a folder can't be moved into itself or one of its subfolders")
+	ErrFolderTooDeep   = fmt.Errorf("folders can be nested at most %d levels deep", MaxFolderDepth)
+	ErrSmartParent     = errors.New("smart folders can't contain folders")
+	ErrFolderNameTaken = errors.New("a folder with this name already exists here")
+)
+
+// FindFolder returns one of the user's folders that is not in the trash.
+func FindFolder(ctx context.Context, folders *mongo.Collection, userID, rawID string) (*model.Folder, error) {
+	id, err := bson.ObjectIDFromHex(rawID)
+	if err != nil {
+		return nil, ErrFolderNotFound
+	}
+	var folder model.Folder
+	err = folders.FindOne(ctx, bson.M{"_id": id, "user_id": userID, "deleted_at": nil}).Decode(&folder)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrFolderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch folder: %w", err)
+	}
+	return &folder, nil
+}
+
+// CreateFolder inserts a new folder under the parent, or at the top level
+// when parentID is empty. The parent is checked in the same transaction.
+func CreateFolder(ctx context.Context, folders *mongo.Collection, folder *model.Folder, parentID string) error {
+	return DB.WithTransaction(ctx, func(ctx context.Context) error {
+		parent, err := parentFolder(ctx, folders, folder.UserID, parentID)
+		if err != nil {
+			return err
+		}
+		folder.ParentID, folder.Path = "", "/"
+		if parent != nil {
+			if parent.Depth()+1 >= MaxFolderDepth {
+				return ErrFolderTooDeep
+			}
+			folder.ParentID, folder.Path = parent.ID.Hex(), parent.ChildPath()
+			if err := touch(ctx, folders, parent, folder.CreatedAt); err != nil {
+				return err
+			}
+		}
+		if !folder.IsSmart() {
+			taken, err := FolderNameTaken(ctx, folders, folder.UserID, folder.ParentID, folder.Name, bson.NilObjectID)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return ErrFolderNameTaken
+			}
+		}
+		if _, err := folders.InsertOne(ctx, folder); err != nil {
+			return fmt.Errorf("failed to create folder: %w", err)
+		}
+		return nil
+	})
+}
+
+// MoveFolder moves a folder, with everything under it, into another folder
+// or to the top level when parentID is empty.
+func MoveFolder(ctx context.Context, folders *mongo.Collection, userID, rawID, parentID string, now time.Time) (*model.Folder, error) {
+	var moved *model.Folder
+	err := DB.WithTransaction(ctx, func(ctx context.Context) error {
+		folder, err := FindFolder(ctx, folders, userID, rawID)
+		if err != nil {
+			return err
+		}
+		parent, err := parentFolder(ctx, folders, userID, parentID)
+		if err != nil {
+			return err
+		}
+		if err := move(ctx, folders, folder, parent, now, true); err != nil {
+			return err
+		}
+		moved = folder
+		return nil
+	})
+	return moved, err
+}
+
+// DetachFolder moves a folder whose parent is gone to the top level. Like
+// any restore from the trash, it doesn't check for a clashing name.
+func DetachFolder(ctx context.Context, folders *mongo.Collection, folder *model.Folder, now time.Time) error {
+	return DB.WithTransaction(ctx, func(ctx context.Context) error {
+		return move(ctx, folders, folder, nil, now, false)
+	})
+}
+
+func move(ctx context.Context, folders *mongo.Collection, folder, parent *model.Folder, now time.Time, checkName bool) error {
+	newParentID, newPath := "", "/"
+	if parent != nil {
+		if parent.ID == folder.ID || strings.HasPrefix(parent.AncestorPath(), folder.ChildPath()) {
+			return ErrFolderCycle
+		}
+		newParentID, newPath = parent.ID.Hex(), parent.ChildPath()
+	}
+	if newParentID == folder.ParentID && newPath == folder.AncestorPath() {
+		return nil
+	}
+
+	height, err := subtreeHeight(ctx, folders, folder)
+	if err != nil {
+		return err
+	}
+	if strings.Count(newPath, "/")-1+height >= MaxFolderDepth {
+		return ErrFolderTooDeep
+	}
+	if checkName && !folder.IsSmart() {
+		taken, err := FolderNameTaken(ctx, folders, folder.UserID, newParentID, folder.Name, folder.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrFolderNameTaken
+		}
+	}
+	// Writing the new parent makes two moves that would each put one
+	// folder under the other conflict, instead of both succeeding and
+	// leaving a cycle.
+	if parent != nil {
+		if err := touch(ctx, folders, parent, now); err != nil {
+			return err
+		}
+	}
+
+	oldChildPath := folder.ChildPath()
+	update := bson.M{"$set": bson.M{"path": newPath, "updated_at": now}}
+	if newParentID == "" {
+		update["$unset"] = bson.M{"parent_id": ""}
+	} else {
+		update["$set"].(bson.M)["parent_id"] = newParentID
+	}
+	if _, err := folders.UpdateOne(ctx, bson.M{"_id": folder.ID}, update); err != nil {
+		return fmt.Errorf("failed to move folder: %w", err)
+	}
+	folder.ParentID, folder.Path, folder.UpdatedAt = newParentID, newPath, &now
+
+	// Descendants in the trash move too, so a restore finds them in place.
+	newChildPath := folder.ChildPath()
+	_, err = folders.UpdateMany(ctx, descendantsFilter(folder.UserID, oldChildPath), mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"path": bson.M{"$concat": bson.A{
+			newChildPath,
+			bson.M{"$substrBytes": bson.A{"$path", len(oldChildPath), bson.M{"$subtract": bson.A{bson.M{"$strLenBytes": "$path"}, len(oldChildPath)}}}},
+		}}}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to move subfolders: %w", err)
+	}
+	return nil
+}
+
+// Descendants returns every folder below this one that is not in the trash.
+func Descendants(ctx context.Context, folders *mongo.Collection, folder *model.Folder) ([]model.Folder, error) {
+	filter := descendantsFilter(folder.UserID, folder.ChildPath())
+	filter["deleted_at"] = nil
+	cursor, err := folders.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subfolders: %w", err)
+	}
+	descendants := []model.Folder{}
+	if err := cursor.All(ctx, &descendants); err != nil {
+		return nil, fmt.Errorf("failed to parse subfolders: %w", err)
+	}
+	return descendants, nil
+}
+
+// Breadcrumbs returns the folder's ancestors, outermost first, followed by
+// the folder itself. Someone the folder was shared with only sees the path
+// from the outermost folder shared with them.
+func Breadcrumbs(ctx context.Context, folders *mongo.Collection, folder *model.Folder, userID string) ([]model.Folder, error) {
+	var ids []bson.ObjectID
+	for _, raw := range strings.Split(strings.Trim(folder.AncestorPath(), "/"), "/") {
+		if id, err := bson.ObjectIDFromHex(raw); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	crumbs := []model.Folder{}
+	if len(ids) > 0 {
+		cursor, err := folders.Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "user_id": folder.UserID},
+			options.Find().SetProjection(bson.M{"name": 1, "user_id": 1, "parent_id": 1, "path": 1, "kind": 1, "members": 1}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch parent folders: %w", err)
+		}
+		if err := cursor.All(ctx, &crumbs); err != nil {
+			return nil, fmt.Errorf("failed to parse parent folders: %w", err)
+		}
+	}
+	byID := make(map[bson.ObjectID]model.Folder, len(crumbs))
+	for _, f := range crumbs {
+		byID[f.ID] = f
+	}
+	ordered := make([]model.Folder, 0, len(ids)+1)
+	for _, id := range ids {
+		if f, ok := byID[id]; ok {
+			ordered = append(ordered, f)
+		}
+	}
+	ordered = append(ordered, *folder)
+	for i, f := range ordered {
+		if f.UserID == userID || memberRole(f.Members, userID) != "" {
+			ordered = ordered[i:]
+			break
+		}
+	}
+	for i := range ordered {
+		ordered[i].Members = nil
+	}
+	return ordered, nil
+}
+
+// SubtreeFilter matches the items of a folder and all of its subfolders:
+// static ones by membership, smart ones by their filters.
+func SubtreeFilter(ctx context.Context, folders *mongo.Collection, folder *model.Folder, now time.Time) (bson.M, error) {
+	descendants, err := Descendants(ctx, folders, folder)
+	if err != nil {
+		return nil, err
+	}
+	return TreeFilter(append([]model.Folder{*folder}, descendants...), now)
+}
+
+// TreeFilter matches the items of any of the folders. The first folder's
+// smart filter has to compile; broken ones further down are left out.
+func TreeFilter(subtree []model.Folder, now time.Time) (bson.M, error) {
+	var static []string
+	var parts bson.A
+	for i, f := range subtree {
+		if !f.IsSmart() {
+			static = append(static, f.ID.Hex())
+			continue
+		}
+		filter, err := SmartFolderFilter(f.Filter, now)
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			continue
+		}
+		// Smart folders only ever look at their owner's library.
+		parts = append(parts, bson.M{"$and": bson.A{filter, bson.M{"user_id": f.UserID}}})
+	}
+	if len(static) == 1 {
+		parts = append(parts, bson.M{"folders": static[0]})
+	} else if len(static) > 1 {
+		parts = append(parts, bson.M{"folders": bson.M{"$in": static}})
+	}
+	if len(parts) == 1 {
+		return parts[0].(bson.M), nil
+	}
+	return bson.M{"$or": parts}, nil
+}
+
+// ExpandFolder replaces the options' folder with a filter over its whole
+// subtree when subfolders were asked for.
+func ExpandFolder(ctx context.Context, folders *mongo.Collection, opts *ListOptions, now time.Time) error {
+	if opts.Folder == "" || !opts.Subfolders {
+		return nil
+	}
+	folder, err := FindFolder(ctx, folders, opts.UserID, opts.Folder)
+	if err != nil {
+		return err
+	}
+	filter, err := SubtreeFilter(ctx, folders, folder, now)
+	if err != nil {
+		return err
+	}
+	if opts.Query != nil {
+		filter = bson.M{"$and": bson.A{filter, opts.Query}}
+	}
+	opts.Folder, opts.Query = "", filter
+	return nil
+}
+
+// FolderNameTaken reports whether another live static folder under the
+// same parent has this name, ignoring case.
+func FolderNameTaken(ctx context.Context, folders *mongo.Collection, userID, parentID, name string, except bson.ObjectID) (bool, error) {
+	filter := bson.M{
+		"_id":        bson.M{"$ne": except},
+		"user_id":    userID,
+		"kind":       bson.M{"$ne": model.FolderKindSmart},
+		"deleted_at": nil,
+		"name":       bson.Regex{Pattern: "^" + regexp.QuoteMeta(name) + "$", Options: "i"},
+		"parent_id":  nil,
+	}
+	if parentID != "" {
+		filter["parent_id"] = parentID
+	}
+	n, err := folders.CountDocuments(ctx, filter)
+	if err != nil {
+		return false, fmt.Errorf("failed to check folder name: %w", err)
+	}
+	return n > 0, nil
+}
+
+// parentFolder resolves the folder something is being put into; an empty
+// id means the top level.
+func parentFolder(ctx context.Context, folders *mongo.Collection, userID, rawID string) (*model.Folder, error) {
+	if rawID == "" {
+		return nil, nil
+	}
+	parent, err := FindFolder(ctx, folders, userID, rawID)
+	if err != nil {
+		return nil, err
+	}
+	if parent.IsSmart() {
+		return nil, ErrSmartParent
+	}
+	return parent, nil
+}
+
+// subtreeHeight is how many levels of folders sit below this one.
+func subtreeHeight(ctx context.Context, folders *mongo.Collection, folder *model.Folder) (int, error) {
+	descendants, err := Descendants(ctx, folders, folder)
+	if err != nil {
+		return 0, err
+	}
+	height := 0
+	for _, d := range descendants {
+		height = max(height, d.Depth()-folder.Depth())
+	}
+	return height, nil
+}
+
+func touch(ctx context.Context, folders *mongo.Collection, folder *model.Folder, now time.Time) error {
+	if _, err := folders.UpdateOne(ctx, bson.M{"_id": folder.ID}, bson.M{"$set": bson.M{"updated_at": now}}); err != nil {
+		return fmt.Errorf("failed to update folder: %w", err)
+	}
+	return nil
+}
+
+// SubtreeMatch matches a folder and every folder below it, trashed or not.
+func SubtreeMatch(folder *model.Folder) bson.M {
+	return bson.M{"user_id": folder.UserID, "$or": bson.A{
+		bson.M{"_id": folder.ID},
+		bson.M{"path": bson.Regex{Pattern: "^" + regexp.QuoteMeta(folder.ChildPath())}},
+	}}
+}
+
+func descendantsFilter(userID, childPath string) bson.M {
+	return bson.M{"user_id": userID, "path": bson.Regex{Pattern: "^" + regexp.QuoteMeta(childPath)}}
+}
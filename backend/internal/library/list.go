@@ -0,0 +1,380 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/utils"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	SortSavedAt   = "saved_at"
+	SortTitle     = "title"
+	SortPlatform  = "platform"
+	SortDeletedAt = "deleted_at" // trash listings only
+	SortPosition  = "position"   // manual order within a static folder
+
+	// LinkStatusBroken is shorthand for every status that means the link is dead.
+	LinkStatusBroken = "broken"
+
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// sortFields maps the public sort names to the document fields they order by.
+var sortFields = map[string]string{
+	SortSavedAt:   "saved_at",
+	SortTitle:     "title",
+	SortPlatform:  "platform",
+	SortDeletedAt: "deleted_at",
+	SortPosition:  "positions", // .<folder id>
+}
+
+// ListOptions describes one page of a user's library.
+type ListOptions struct {
+	UserID       string
+	Sort         string
+	Ascending    bool
+	Limit        int
+	Cursor       *Cursor
+	Folder       string
+	Subfolders   bool // with Folder, also match items in its subfolders
+	Tags         []string
+	Platform     string
+	LinkStatuses []string // any of
+	Favorite     *bool
+	MinRating    int
+	Statuses     []string // any of
+	From         *time.Time
+	To           *time.Time
+	Query        bson.M // compiled search-language filter, ANDed with the rest
+	IncludeTotal bool
+	Trashed      bool // list the trash instead of the library
+	AnyOwner     bool // don't limit to UserID's items; Folder or Query already scope them
+}
+
+type Page struct {
+	Uploads    []model.LykedUploads `json:"uploads"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+	HasMore    bool                 `json:"has_more"`
+	Total      *int64               `json:"total,omitempty"`
+}
+
+// Normalize fills in defaults and rejects options that can't be served.
+func (o *ListOptions) Normalize() error {
+	if o.Sort == "" {
+		o.Sort = SortSavedAt
+	}
+	if _, ok := sortFields[o.Sort]; !ok || (o.Sort == SortDeletedAt && !o.Trashed) {
+		return fmt.Errorf("unsupported sort %q", o.Sort)
+	}
+	if o.Limit <= 0 {
+		o.Limit = DefaultLimit
+	}
+	if o.Limit > MaxLimit {
+		o.Limit = MaxLimit
+	}
+	if o.AnyOwner && o.Folder == "" && len(o.Query) == 0 {
+		return fmt.Errorf("listing every owner's items needs a folder")
+	}
+	if o.Sort == SortPosition && (o.Folder == "" || o.Subfolders) {
+		return fmt.Errorf("sort=position needs a single folder")
+	}
+	if o.Cursor != nil && o.Cursor.Sort != o.Sort {
+		return ErrInvalidCursor
+	}
+	if o.From != nil && o.To != nil && o.To.Before(*o.From) {
+		return fmt.Errorf("'to' must not be before 'from'")
+	}
+	var statuses []string
+	for _, s := range o.LinkStatuses {
+		switch s {
+		case LinkStatusBroken:
+			statuses = append(statuses, model.LinkStatusRemoved, model.LinkStatusPrivate, model.LinkStatusRegionBlocked)
+		case model.LinkStatusAvailable, model.LinkStatusRemoved, model.LinkStatusPrivate,
+			model.LinkStatusRegionBlocked, model.LinkStatusUnknown:
+			statuses = append(statuses, s)
+		default:
+			return fmt.Errorf("unsupported link_status %q", s)
+		}
+	}
+	o.LinkStatuses = statuses
+	if o.MinRating != 0 {
+		if err := validateRating(o.MinRating, false); err != nil {
+			return err
+		}
+	}
+	for _, s := range o.Statuses {
+		if err := ValidateStatus(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Filter builds the Mongo filter for everything except the cursor position.
+func (o *ListOptions) Filter() bson.M {
+	filter := bson.M{"user_id": o.UserID, "deleted_at": nil}
+	if o.Trashed {
+		filter["deleted_at"] = bson.M{"$ne": nil}
+	}
+	if o.AnyOwner {
+		delete(filter, "user_id")
+	}
+	if o.Folder != "" {
+		filter["folders"] = o.Folder
+	}
+	if len(o.Tags) > 0 {
+		filter["tags"] = bson.M{"$all": TagPatterns(o.Tags)}
+	}
+	if o.Platform != "" {
+		filter["platform"] = o.Platform
+	}
+	if len(o.LinkStatuses) > 0 {
+		filter["link_status"] = bson.M{"$in": o.LinkStatuses}
+	}
+	if o.Favorite != nil {
+		if *o.Favorite {
+			filter["favorite"] = true
+		} else {
+			filter["favorite"] = bson.M{"$ne": true}
+		}
+	}
+	if o.MinRating > 0 {
+		filter["rating"] = bson.M{"$gte": o.MinRating}
+	}
+	if len(o.Statuses) > 0 {
+		filter["status"] = statusFilter(o.Statuses)
+	}
+	if o.From != nil || o.To != nil {
+		savedAt := bson.M{}
+		if o.From != nil {
+			savedAt["$gte"] = *o.From
+		}
+		if o.To != nil {
+			savedAt["$lte"] = *o.To
+		}
+		filter["saved_at"] = savedAt
+	}
+	if len(o.Query) > 0 {
+		filter["$and"] = bson.A{o.Query}
+	}
+	return filter
+}
+
+// seekFilter restricts results to items strictly after the cursor in sort order.
+func (o *ListOptions) seekFilter() bson.M {
+	field := o.sortField()
+	id, _ := bson.ObjectIDFromHex(o.Cursor.ID)
+	if o.Sort == SortPosition {
+		return positionSeekFilter(field, o.Cursor.Value, id, o.Ascending)
+	}
+	op := "$lt"
+	if o.Ascending {
+		op = "$gt"
+	}
+	value := o.Cursor.sortValue()
+	return bson.M{"$or": bson.A{
+		bson.M{field: bson.M{op: value}},
+		bson.M{field: value, "_id": bson.M{op: id}},
+	}}
+}
+
+// positionSeekFilter is seekFilter for ranks, which items filed before
+// ranks existed may lack. Those sort ahead of the ranked ones.
+func positionSeekFilter(field, rank string, id bson.ObjectID, ascending bool) bson.M {
+	switch {
+	case rank == "" && ascending:
+		return bson.M{"$or": bson.A{
+			bson.M{field: nil, "_id": bson.M{"$gt": id}},
+			bson.M{field: bson.M{"$type": "string"}},
+		}}
+	case rank == "":
+		return bson.M{field: nil, "_id": bson.M{"$lt": id}}
+	case ascending:
+		return bson.M{"$or": bson.A{
+			bson.M{field: bson.M{"$gt": rank}},
+			bson.M{field: rank, "_id": bson.M{"$gt": id}},
+		}}
+	default:
+		return bson.M{"$or": bson.A{
+			bson.M{field: bson.M{"$lt": rank}},
+			bson.M{field: rank, "_id": bson.M{"$lt": id}},
+			bson.M{field: nil},
+		}}
+	}
+}
+
+func (o *ListOptions) sortField() string {
+	if o.Sort == SortPosition {
+		return sortFields[SortPosition] + "." + o.Folder
+	}
+	return sortFields[o.Sort]
+}
+
+func (o *ListOptions) sortSpec() bson.D {
+	dir := -1
+	if o.Ascending {
+		dir = 1
+	}
+	return bson.D{{Key: o.sortField(), Value: dir}, {Key: "_id", Value: dir}}
+}
+
+func (o *ListOptions) cursorFor(u model.LykedUploads) Cursor {
+	c := Cursor{Sort: o.Sort, ID: u.ID.Hex()}
+	switch o.Sort {
+	case SortSavedAt:
+		c.Value = u.SavedAt.UTC().Format(time.RFC3339Nano)
+	case SortTitle:
+		c.Value = u.Title
+	case SortPlatform:
+		c.Value = u.Platform
+	case SortDeletedAt:
+		if u.DeletedAt != nil {
+			c.Value = u.DeletedAt.UTC().Format(time.RFC3339Nano)
+		}
+	case SortPosition:
+		c.Value = u.Positions[o.Folder]
+	}
+	return c
+}
+
+// resolveTags resolves the tag filter through the user's aliases.
+func (o *ListOptions) resolveTags(ctx context.Context) error {
+	if len(o.Tags) == 0 || o.UserID == "" {
+		return nil
+	}
+	aliases, err := LoadTagAliases(ctx, o.UserID)
+	if err != nil {
+		return err
+	}
+	o.Tags = aliases.ResolveAll(o.Tags)
+	return nil
+}
+
+// List returns one page of uploads. It fetches a single extra document to
+// find out whether another page exists.
+func List(ctx context.Context, collection *mongo.Collection, opts ListOptions) (*Page, error) {
+	if err := opts.Normalize(); err != nil {
+		return nil, err
+	}
+	if err := opts.resolveTags(ctx); err != nil {
+		return nil, err
+	}
+
+	filter := opts.Filter()
+	query := filter
+	if opts.Cursor != nil {
+		query = bson.M{"$and": bson.A{filter, opts.seekFilter()}}
+	}
+
+	findOpts := options.Find().SetSort(opts.sortSpec()).SetLimit(int64(opts.Limit + 1))
+	cursor, err := collection.Find(ctx, query, findOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch uploads: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	uploads := []model.LykedUploads{}
+	if err := cursor.All(ctx, &uploads); err != nil {
+		return nil, fmt.Errorf("failed to parse uploads: %w", err)
+	}
+
+	page := &Page{Uploads: uploads}
+	if len(uploads) > opts.Limit {
+		page.Uploads = uploads[:opts.Limit]
+		page.HasMore = true
+		page.NextCursor = opts.cursorFor(page.Uploads[opts.Limit-1]).Encode()
+	}
+
+	if opts.IncludeTotal {
+		total, err := collection.CountDocuments(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count uploads: %w", err)
+		}
+		page.Total = &total
+	}
+	return page, nil
+}
+
+// MigrateSortFields fills in saved_at and platform on items saved before
+// they existed: saved_at from the creation time in the item's id, platform
+// from its link. Without them paging by either stops at the first such
+// item. It returns how many items changed.
+func MigrateSortFields(ctx context.Context, uploads *mongo.Collection) (int, error) {
+	cursor, err := uploads.Find(ctx,
+		bson.M{"_id": bson.M{"$type": "objectId"}, "$or": bson.A{
+			bson.M{"saved_at": bson.M{"$in": bson.A{nil, time.Time{}}}},
+			bson.M{"platform": bson.M{"$in": bson.A{nil, ""}}},
+		}},
+		options.Find().SetProjection(bson.M{"saved_at": 1, "platform": 1, "video_link": 1}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find uploads to migrate: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	var fixes []mongo.WriteModel
+	flush := func() error {
+		if len(fixes) == 0 {
+			return nil
+		}
+		if _, err := uploads.BulkWrite(ctx, fixes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return fmt.Errorf("failed to migrate saved_at and platform: %w", err)
+		}
+		migrated += len(fixes)
+		fixes = fixes[:0]
+		return nil
+	}
+	for cursor.Next(ctx) {
+		var u struct {
+			ID        bson.ObjectID `bson:"_id"`
+			SavedAt   time.Time     `bson:"saved_at"`
+			Platform  string        `bson:"platform"`
+			VideoLink string        `bson:"video_link"`
+		}
+		if err := cursor.Decode(&u); err != nil {
+			return migrated, fmt.Errorf("failed to parse upload: %w", err)
+		}
+		set := bson.M{}
+		if u.SavedAt.IsZero() {
+			set["saved_at"] = u.ID.Timestamp().UTC()
+		}
+		if u.Platform == "" {
+			set["platform"] = utils.DetectPlatform(u.VideoLink)
+		}
+		fixes = append(fixes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": u.ID}).
+			SetUpdate(bson.M{"$set": set}))
+		if len(fixes) >= repairChunk {
+			if err := flush(); err != nil {
+				return migrated, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return migrated, fmt.Errorf("failed to find uploads to migrate: %w", err)
+	}
+	return migrated, flush()
+}
+
+// ParseDate accepts either an RFC 3339 timestamp or a plain YYYY-MM-DD date.
+// Plain dates cover the whole day, so endOfDay picks the last instant of it.
+func ParseDate(s string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or RFC 3339", s)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
@@ -0,0 +1,356 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	DB "lyked-backend/internal/database/mongodb"
+	model "lyked-backend/internal/models/mongodb"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const repairChunk = 500
+
+// MembershipReport is what CheckMembership found, and fixed if asked to.
+type MembershipReport struct {
+	Uploads       int  `json:"uploads"`         // uploads checked
+	LegacyIDs     int  `json:"legacy_ids"`      // upload _ids stored as binary by the v1 driver
+	DanglingRefs  int  `json:"dangling_refs"`   // references to missing or smart folders, or folders the uploader has no role on
+	MissingRefs   int  `json:"missing_refs"`    // memberships recorded only in a folder's legacy post_ids
+	LegacyPostIDs int  `json:"legacy_post_ids"` // folders still carrying post_ids
+	Repaired      bool `json:"repaired"`
+}
+
+// Consistent reports whether nothing needed fixing.
+func (r *MembershipReport) Consistent() bool {
+	return r.LegacyIDs == 0 && r.DanglingRefs == 0 && r.MissingRefs == 0 && r.LegacyPostIDs == 0
+}
+
+// StaticFolderIDs checks that every id names one of the user's static
+// folders and returns them without duplicates. Saving an upload runs it
+// in the same transaction as the write.
+func StaticFolderIDs(ctx context.Context, folders *mongo.Collection, userID string, ids []string) ([]string, error) {
+	out := []string{}
+	for _, raw := range ids {
+		id, err := staticFolderID(ctx, folders, userID, raw)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(out, id) {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+// MigrateUploadIDs re-keys uploads whose _id the v1 driver's ObjectID
+// stored as binary data, so they match the ObjectIDs used everywhere now.
+// The hex form, which other collections refer to uploads by, is unchanged.
+// It re-keys one user's uploads, or everyone's when userID is empty.
+func MigrateUploadIDs(ctx context.Context, uploads *mongo.Collection, userID string) (int, error) {
+	filter := bson.M{"_id": bson.M{"$type": "binData"}}
+	if userID != "" {
+		filter["user_id"] = userID
+	}
+	migrated := 0
+	for {
+		cursor, err := uploads.Find(ctx, filter, options.Find().SetLimit(repairChunk))
+		if err != nil {
+			return migrated, fmt.Errorf("failed to find legacy upload ids: %w", err)
+		}
+		var docs []bson.D
+		if err := cursor.All(ctx, &docs); err != nil {
+			return migrated, fmt.Errorf("failed to parse uploads: %w", err)
+		}
+		if len(docs) == 0 {
+			return migrated, nil
+		}
+		for _, doc := range docs {
+			err := DB.WithTransaction(ctx, func(ctx context.Context) error {
+				return rekey(ctx, uploads, doc)
+			})
+			if err != nil {
+				return migrated, err
+			}
+			migrated++
+		}
+	}
+}
+
+// rekey copies the upload under its ObjectID, then deletes the binary
+// one. Without transactions a crash can land between the two; the next
+// run then finds the copy and only finishes the delete.
+func rekey(ctx context.Context, uploads *mongo.Collection, doc bson.D) error {
+	for i, e := range doc {
+		if e.Key != "_id" {
+			continue
+		}
+		old, ok := e.Value.(bson.Binary)
+		if !ok || len(old.Data) != 12 {
+			return fmt.Errorf("upload _id %v is not a 12-byte id", e.Value)
+		}
+		id := bson.ObjectID(old.Data)
+		copied, err := uploads.CountDocuments(ctx, bson.M{"_id": id})
+		if err != nil {
+			return fmt.Errorf("failed to re-key upload %x: %w", old.Data, err)
+		}
+		if copied == 0 {
+			doc[i].Value = id
+			if _, err := uploads.InsertOne(ctx, doc); err != nil {
+				return fmt.Errorf("failed to re-key upload %x: %w", old.Data, err)
+			}
+		}
+		if _, err := uploads.DeleteOne(ctx, bson.M{"_id": old}); err != nil {
+			return fmt.Errorf("failed to re-key upload %x: %w", old.Data, err)
+		}
+		return nil
+	}
+	return nil
+}
+
+// CheckMembership compares folder membership, recorded on the uploads,
+// with the folders it points at, for one user or everyone when userID is
+// empty. With repair set it re-keys legacy ids, drops references to
+// folders that can't hold items, files items that only a folder's legacy
+// post_ids listed, and removes the post_ids.
+func CheckMembership(ctx context.Context, uploads, folders *mongo.Collection, userID string, repair bool) (*MembershipReport, error) {
+	report := &MembershipReport{Repaired: repair}
+	scope := bson.M{}
+	if userID != "" {
+		scope["user_id"] = userID
+	}
+
+	legacy, err := uploads.CountDocuments(ctx, bson.M{"$and": bson.A{scope, bson.M{"_id": bson.M{"$type": "binData"}}}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count legacy upload ids: %w", err)
+	}
+	report.LegacyIDs = int(legacy)
+	if repair && legacy > 0 {
+		if _, err := MigrateUploadIDs(ctx, uploads, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Every folder, trashed ones included: items keep their membership
+	// while a folder is in the trash so a restore brings it back whole.
+	type folderDoc struct {
+		model.Folder `bson:",inline"`
+		PostIDs      []string `bson:"post_ids"`
+	}
+	cursor, err := folders.Find(ctx, scope, options.Find().SetProjection(bson.M{"user_id": 1, "kind": 1, "path": 1, "members": 1, "post_ids": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load folders: %w", err)
+	}
+	var all []folderDoc
+	if err := cursor.All(ctx, &all); err != nil {
+		return nil, fmt.Errorf("failed to parse folders: %w", err)
+	}
+	index := newFolderIndex()
+	for _, f := range all {
+		if !f.IsSmart() {
+			index.folders[f.ID.Hex()] = &f.Folder
+		}
+	}
+	if len(scope) > 0 {
+		// Other users' folders, shared with this one, are looked up as
+		// references to them come.
+		index.lookup = func(rawID string) (*model.Folder, error) {
+			return lookupStaticFolder(ctx, folders, rawID)
+		}
+	}
+
+	if err := checkUploadRefs(ctx, uploads, scope, index, repair, report); err != nil {
+		return nil, err
+	}
+
+	for _, f := range all {
+		if f.PostIDs == nil {
+			continue
+		}
+		report.LegacyPostIDs++
+		if f.IsSmart() {
+			if repair {
+				if _, err := folders.UpdateOne(ctx, bson.M{"_id": f.ID}, bson.M{"$unset": bson.M{"post_ids": ""}}); err != nil {
+					return nil, fmt.Errorf("failed to clear post_ids: %w", err)
+				}
+			}
+			continue
+		}
+		ids := make([]bson.ObjectID, 0, len(f.PostIDs))
+		for _, raw := range f.PostIDs {
+			if id, err := bson.ObjectIDFromHex(raw); err == nil {
+				ids = append(ids, id)
+			}
+		}
+		missing := bson.M{"_id": bson.M{"$in": ids}, "user_id": f.UserID, "folders": bson.M{"$ne": f.ID.Hex()}}
+		if !repair {
+			n, err := uploads.CountDocuments(ctx, missing)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check folder %s: %w", f.ID.Hex(), err)
+			}
+			report.MissingRefs += int(n)
+			continue
+		}
+		var filed int64
+		err := DB.WithTransaction(ctx, func(ctx context.Context) error {
+			res, err := uploads.UpdateMany(ctx, missing, bson.M{"$addToSet": bson.M{"folders": f.ID.Hex()}})
+			if err != nil {
+				return fmt.Errorf("failed to file items into folder %s: %w", f.ID.Hex(), err)
+			}
+			filed = res.ModifiedCount
+			if _, err := folders.UpdateOne(ctx, bson.M{"_id": f.ID}, bson.M{"$unset": bson.M{"post_ids": ""}}); err != nil {
+				return fmt.Errorf("failed to clear post_ids: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		report.MissingRefs += int(filed)
+	}
+	return report, nil
+}
+
+// folderIndex answers which role an uploader has on the static folders
+// their items are filed in, walking up to shared ancestors as FolderRole
+// does. Trashed folders count: their items are restored with them.
+type folderIndex struct {
+	folders map[string]*model.Folder // static folders by id; nil when missing or smart
+	lookup  func(rawID string) (*model.Folder, error)
+	roles   map[[2]string]string // folder id, user id -> role
+}
+
+func newFolderIndex() *folderIndex {
+	return &folderIndex{folders: map[string]*model.Folder{}, roles: map[[2]string]string{}}
+}
+
+// folder returns a static folder, looking it up if it isn't loaded yet.
+func (x *folderIndex) folder(rawID string) (*model.Folder, error) {
+	f, ok := x.folders[rawID]
+	if ok || x.lookup == nil {
+		return f, nil
+	}
+	f, err := x.lookup(rawID)
+	if err != nil {
+		return nil, err
+	}
+	x.folders[rawID] = f
+	return f, nil
+}
+
+// role is the user's role on a static folder, empty when they have none
+// or the folder is missing or smart.
+func (x *folderIndex) role(rawID, userID string) (string, error) {
+	key := [2]string{rawID, userID}
+	if role, ok := x.roles[key]; ok {
+		return role, nil
+	}
+	f, err := x.folder(rawID)
+	if err != nil || f == nil {
+		return "", err
+	}
+	var ancestors []model.Folder
+	for _, id := range ancestorIDs(f) {
+		a, err := x.folder(id.Hex())
+		if err != nil {
+			return "", err
+		}
+		if a != nil {
+			ancestors = append(ancestors, *a)
+		}
+	}
+	role := roleIn(f, ancestors, userID)
+	x.roles[key] = role
+	return role, nil
+}
+
+// checkUploadRefs finds references to folders that are missing, smart or
+// that the upload's owner has no role on, and pulls them when repairing.
+// Any role will do: a member demoted to viewer keeps what they filed.
+func checkUploadRefs(ctx context.Context, uploads *mongo.Collection, scope bson.M, index *folderIndex, repair bool, report *MembershipReport) error {
+	cursor, err := uploads.Find(ctx, scope, options.Find().SetProjection(bson.M{"user_id": 1, "folders": 1}))
+	if err != nil {
+		return fmt.Errorf("failed to load uploads: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var fixes []mongo.WriteModel
+	flush := func() error {
+		if len(fixes) == 0 {
+			return nil
+		}
+		if _, err := uploads.BulkWrite(ctx, fixes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return fmt.Errorf("failed to repair folder references: %w", err)
+		}
+		fixes = fixes[:0]
+		return nil
+	}
+	for cursor.Next(ctx) {
+		var u struct {
+			ID      bson.RawValue `bson:"_id"`
+			UserID  string        `bson:"user_id"`
+			Folders []string      `bson:"folders"`
+		}
+		if err := cursor.Decode(&u); err != nil {
+			return fmt.Errorf("failed to parse upload: %w", err)
+		}
+		report.Uploads++
+		dangling, err := index.dangling(u.UserID, u.Folders)
+		if err != nil {
+			return err
+		}
+		report.DanglingRefs += len(dangling)
+		if repair && len(dangling) > 0 {
+			fixes = append(fixes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": u.ID}).
+				SetUpdate(bson.M{"$pull": bson.M{"folders": bson.M{"$in": dangling}}}))
+			if len(fixes) >= repairChunk {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to load uploads: %w", err)
+	}
+	return flush()
+}
+
+// dangling returns the refs of the user's upload that can't hold it.
+func (x *folderIndex) dangling(userID string, refs []string) ([]string, error) {
+	var dangling []string
+	for _, ref := range refs {
+		role, err := x.role(ref, userID)
+		if err != nil {
+			return nil, err
+		}
+		if role == "" {
+			dangling = append(dangling, ref)
+		}
+	}
+	return dangling, nil
+}
+
+// lookupStaticFolder loads a static folder, trashed or not, or returns
+// nil when there is none.
+func lookupStaticFolder(ctx context.Context, folders *mongo.Collection, rawID string) (*model.Folder, error) {
+	id, err := bson.ObjectIDFromHex(rawID)
+	if err != nil {
+		return nil, nil
+	}
+	var f model.Folder
+	err = folders.FindOne(ctx, bson.M{"_id": id, "kind": bson.M{"$ne": model.FolderKindSmart}},
+		options.FindOne().SetProjection(bson.M{"user_id": 1, "kind": 1, "path": 1, "members": 1})).Decode(&f)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load folder %s: %w", rawID, err)
+	}
+	return &f, nil
+}
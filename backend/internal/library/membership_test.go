@@ -0,0 +1,134 @@
+package library
+
+import (
+	"errors"
+	model "lyked-backend/internal/models/mongodb"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// sharedTree is alice's "team" folder shared with bob as a contributor
+// and carol as a viewer, with a trashed subfolder and a sub-subfolder
+// below it, plus alice's unshared "private" folder, a folder of dave's
+// shared with erin only, and a smart folder.
+func sharedTree() (*folderIndex, map[string]string) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	team := &model.Folder{ID: bson.NewObjectID(), UserID: "alice", Members: []model.FolderMember{
+		{UserID: "bob", Role: model.RoleContributor},
+		{UserID: "carol", Role: model.RoleViewer},
+	}}
+	sub := &model.Folder{ID: bson.NewObjectID(), UserID: "alice", Path: team.ChildPath(), DeletedAt: &now}
+	subsub := &model.Folder{ID: bson.NewObjectID(), UserID: "alice", Path: sub.ChildPath()}
+	private := &model.Folder{ID: bson.NewObjectID(), UserID: "alice"}
+	daves := &model.Folder{ID: bson.NewObjectID(), UserID: "dave", Members: []model.FolderMember{
+		{UserID: "erin", Role: model.RoleEditor},
+	}}
+
+	index := newFolderIndex()
+	names := map[string]string{}
+	for name, f := range map[string]*model.Folder{"team": team, "sub": sub, "subsub": subsub, "private": private, "daves": daves} {
+		index.folders[f.ID.Hex()] = f
+		names[name] = f.ID.Hex()
+	}
+	names["smart"] = bson.NewObjectID().Hex()
+	index.folders[names["smart"]] = nil
+	names["missing"] = bson.NewObjectID().Hex()
+	return index, names
+}
+
+func TestFolderIndexDangling(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		refs     []string
+		dangling []string
+	}{
+		{"owner anywhere", "alice", []string{"team", "sub", "subsub", "private"}, nil},
+		{"member in the shared folder", "bob", []string{"team"}, nil},
+		{"member in a subfolder", "bob", []string{"sub", "subsub"}, nil},
+		{"viewer keeps what they filed", "carol", []string{"team", "subsub"}, nil},
+		{"member outside the shared folder", "bob", []string{"team", "private"}, []string{"private"}},
+		{"another folder's member", "erin", []string{"daves", "team", "subsub"}, []string{"team", "subsub"}},
+		{"no role at all", "mallory", []string{"daves"}, []string{"daves"}},
+		{"missing and smart", "alice", []string{"missing", "smart", "team"}, []string{"missing", "smart"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, names := sharedTree()
+			var refs, want []string
+			for _, r := range tt.refs {
+				refs = append(refs, names[r])
+			}
+			for _, r := range tt.dangling {
+				want = append(want, names[r])
+			}
+			got, err := index.dangling(tt.user, refs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("dangling = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestFolderIndexLooksUpAncestors(t *testing.T) {
+	index, names := sharedTree()
+	// Only the sub-subfolder is loaded, as when checking one user.
+	loaded := map[string]*model.Folder{}
+	for id, f := range index.folders {
+		loaded[id] = f
+	}
+	index = newFolderIndex()
+	index.folders[names["subsub"]] = loaded[names["subsub"]]
+	var looked []string
+	index.lookup = func(rawID string) (*model.Folder, error) {
+		looked = append(looked, rawID)
+		return loaded[rawID], nil
+	}
+
+	for range 2 {
+		dangling, err := index.dangling("bob", []string{names["subsub"]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dangling) != 0 {
+			t.Fatalf("bob's item in a subfolder of a shared folder is dangling")
+		}
+	}
+	if want := []string{names["team"], names["sub"]}; !slices.Equal(looked, want) {
+		t.Errorf("looked up %v, want %v once each", looked, want)
+	}
+
+	index.lookup = func(string) (*model.Folder, error) { return nil, errors.New("boom") }
+	if _, err := index.dangling("bob", []string{names["missing"]}); err == nil {
+		t.Error("lookup error was swallowed")
+	}
+}
+
+func TestRoleIn(t *testing.T) {
+	top := model.Folder{ID: bson.NewObjectID(), UserID: "alice", Members: []model.FolderMember{{UserID: "bob", Role: model.RoleEditor}}}
+	other := model.Folder{ID: bson.NewObjectID(), UserID: "dave", Members: []model.FolderMember{{UserID: "bob", Role: model.RoleOwner}}}
+	folder := &model.Folder{ID: bson.NewObjectID(), UserID: "alice", Path: top.ChildPath(), Members: []model.FolderMember{{UserID: "bob", Role: model.RoleViewer}}}
+
+	tests := []struct {
+		user      string
+		ancestors []model.Folder
+		want      string
+	}{
+		{"alice", nil, model.RoleOwner},
+		{"bob", nil, model.RoleViewer},
+		{"bob", []model.Folder{top}, model.RoleEditor},
+		{"bob", []model.Folder{other}, model.RoleViewer}, // another owner's folder doesn't count
+		{"carol", []model.Folder{top}, ""},
+	}
+	for _, tt := range tests {
+		if got := roleIn(folder, tt.ancestors, tt.user); got != tt.want {
+			t.Errorf("roleIn(%s, %d ancestors) = %q, want %q", tt.user, len(tt.ancestors), got, tt.want)
+		}
+	}
+}
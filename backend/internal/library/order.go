@@ -0,0 +1,264 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	DB "lyked-backend/internal/database/mongodb"
+	model "lyked-backend/internal/models/mongodb"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrNotInFolder  = errors.New("item is not in this folder")
+	ErrBadNeighbors = errors.New("after_id must come before before_id")
+)
+
+// MoveRequest places one item of a folder right after AfterID and/or right
+// before BeforeID, the way a drag-and-drop ends.
+type MoveRequest struct {
+	UploadID string `json:"upload_id"`
+	AfterID  string `json:"after_id"`
+	BeforeID string `json:"before_id"`
+}
+
+func (r *MoveRequest) Validate() error {
+	if r.UploadID == "" {
+		return fmt.Errorf("upload_id is required")
+	}
+	if r.AfterID == "" && r.BeforeID == "" {
+		return fmt.Errorf("after_id or before_id is required")
+	}
+	if r.AfterID == r.UploadID || r.BeforeID == r.UploadID {
+		return fmt.Errorf("an item can't be placed next to itself")
+	}
+	return nil
+}
+
+// MoveInFolder gives the item a rank between its new neighbours and returns
+// it. Normally that is the only write; the folder is renumbered only when
+// it has unranked items or the gap has run out.
+func MoveInFolder(ctx context.Context, uploads, folders *mongo.Collection, userID, rawFolderID string, req MoveRequest) (string, error) {
+	var rank string
+	err := DB.WithTransaction(ctx, func(ctx context.Context) error {
+		folder, _, err := staticFolder(ctx, folders, userID, rawFolderID, model.RoleEditor)
+		if err != nil {
+			return err
+		}
+		folderID := folder.ID.Hex()
+		field := "positions." + folderID
+		item, err := memberID(ctx, uploads, folderID, req.UploadID)
+		if err != nil {
+			return err
+		}
+		if err := rankUnranked(ctx, uploads, folderID); err != nil {
+			return err
+		}
+
+		for renumbered := false; ; renumbered = true {
+			lo, hi, err := neighborRanks(ctx, uploads, folderID, item, req)
+			if err != nil {
+				return err
+			}
+			if hi == "" || lo < hi {
+				rank = RankBetween(lo, hi)
+				if len(rank) <= maxRankLength {
+					break
+				}
+			}
+			if renumbered {
+				return ErrBadNeighbors
+			}
+			if err := renumber(ctx, uploads, folderID); err != nil {
+				return err
+			}
+		}
+
+		if _, err := uploads.UpdateOne(ctx, bson.M{"_id": item}, bson.M{"$set": bson.M{field: rank}}); err != nil {
+			return fmt.Errorf("failed to move item: %w", err)
+		}
+		return nil
+	})
+	return rank, err
+}
+
+// AppendToFolder ranks the items after everything already in the folder,
+// in the order given. Ranks are per folder, whoever filed the items.
+func AppendToFolder(ctx context.Context, uploads *mongo.Collection, folderID string, ids []bson.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	field := "positions." + folderID
+	var last struct {
+		Positions map[string]string `bson:"positions"`
+	}
+	err := uploads.FindOne(ctx,
+		bson.M{"folders": folderID, field: bson.M{"$type": "string"}, "_id": bson.M{"$nin": ids}},
+		options.FindOne().SetSort(bson.D{{Key: field, Value: -1}}).SetProjection(bson.M{field: 1}),
+	).Decode(&last)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("failed to read folder order: %w", err)
+	}
+	ranks := RanksBetween(last.Positions[folderID], "", len(ids))
+	if err := setRanks(ctx, uploads, field, ids, ranks); err != nil {
+		return err
+	}
+	if len(ranks[len(ranks)-1]) > maxRankLength {
+		return renumber(ctx, uploads, folderID)
+	}
+	return nil
+}
+
+// neighborRanks returns the ranks the moved item goes between. A missing
+// neighbour is looked up next to the given one, ignoring the item itself.
+func neighborRanks(ctx context.Context, uploads *mongo.Collection, folderID string, item bson.ObjectID, req MoveRequest) (string, string, error) {
+	var lo, hi string
+	if req.AfterID != "" {
+		id, err := memberID(ctx, uploads, folderID, req.AfterID)
+		if err != nil {
+			return "", "", err
+		}
+		if lo, err = rankOf(ctx, uploads, folderID, id); err != nil {
+			return "", "", err
+		}
+	}
+	if req.BeforeID != "" {
+		id, err := memberID(ctx, uploads, folderID, req.BeforeID)
+		if err != nil {
+			return "", "", err
+		}
+		if hi, err = rankOf(ctx, uploads, folderID, id); err != nil {
+			return "", "", err
+		}
+	}
+
+	var err error
+	switch {
+	case req.BeforeID == "":
+		hi, err = adjacentRank(ctx, uploads, folderID, item, bson.M{"$gt": lo}, 1)
+	case req.AfterID == "":
+		lo, err = adjacentRank(ctx, uploads, folderID, item, bson.M{"$lt": hi}, -1)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read folder order: %w", err)
+	}
+	return lo, hi, nil
+}
+
+// adjacentRank returns the closest live rank in the given range, or ""
+// when there is none.
+func adjacentRank(ctx context.Context, uploads *mongo.Collection, folderID string, item bson.ObjectID, bound bson.M, dir int) (string, error) {
+	field := "positions." + folderID
+	var doc struct {
+		Positions map[string]string `bson:"positions"`
+	}
+	err := uploads.FindOne(ctx,
+		bson.M{"folders": folderID, "deleted_at": nil, "_id": bson.M{"$ne": item}, field: bound},
+		options.FindOne().SetSort(bson.D{{Key: field, Value: dir}, {Key: "_id", Value: dir}}).SetProjection(bson.M{field: 1}),
+	).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return doc.Positions[folderID], nil
+}
+
+// memberID checks that the upload is a live item of the folder.
+func memberID(ctx context.Context, uploads *mongo.Collection, folderID, rawID string) (bson.ObjectID, error) {
+	id, err := bson.ObjectIDFromHex(rawID)
+	if err != nil {
+		return id, ErrNotInFolder
+	}
+	n, err := uploads.CountDocuments(ctx, bson.M{"_id": id, "folders": folderID, "deleted_at": nil})
+	if err != nil {
+		return id, fmt.Errorf("failed to fetch item: %w", err)
+	}
+	if n == 0 {
+		return id, ErrNotInFolder
+	}
+	return id, nil
+}
+
+func rankOf(ctx context.Context, uploads *mongo.Collection, folderID string, id bson.ObjectID) (string, error) {
+	var doc struct {
+		Positions map[string]string `bson:"positions"`
+	}
+	if err := uploads.FindOne(ctx, bson.M{"_id": id}, options.FindOne().SetProjection(bson.M{"positions." + folderID: 1})).Decode(&doc); err != nil {
+		return "", fmt.Errorf("failed to fetch item: %w", err)
+	}
+	return doc.Positions[folderID], nil
+}
+
+// rankUnranked gives items filed without a rank one ahead of the ranked
+// items, oldest first, which is where listings already show them.
+func rankUnranked(ctx context.Context, uploads *mongo.Collection, folderID string) error {
+	field := "positions." + folderID
+	unranked, err := folderOrder(ctx, uploads, bson.M{"folders": folderID, field: bson.M{"$exists": false}}, field)
+	if err != nil || len(unranked) == 0 {
+		return err
+	}
+	var first struct {
+		Positions map[string]string `bson:"positions"`
+	}
+	err = uploads.FindOne(ctx,
+		bson.M{"folders": folderID, field: bson.M{"$type": "string"}},
+		options.FindOne().SetSort(bson.D{{Key: field, Value: 1}}).SetProjection(bson.M{field: 1}),
+	).Decode(&first)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("failed to read folder order: %w", err)
+	}
+	return setRanks(ctx, uploads, field, unranked, RanksBetween("", first.Positions[folderID], len(unranked)))
+}
+
+// renumber spreads fresh, short ranks over the folder's items, trashed ones
+// included, keeping their order.
+func renumber(ctx context.Context, uploads *mongo.Collection, folderID string) error {
+	field := "positions." + folderID
+	ids, err := folderOrder(ctx, uploads, bson.M{"folders": folderID}, field)
+	if err != nil {
+		return err
+	}
+	return setRanks(ctx, uploads, field, ids, RanksBetween("", "", len(ids)))
+}
+
+// folderOrder returns the ids of the matching items in folder order.
+func folderOrder(ctx context.Context, uploads *mongo.Collection, filter bson.M, field string) ([]bson.ObjectID, error) {
+	cursor, err := uploads.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: field, Value: 1}, {Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read folder order: %w", err)
+	}
+	var docs []struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to read folder order: %w", err)
+	}
+	ids := make([]bson.ObjectID, len(docs))
+	for i, d := range docs {
+		ids[i] = d.ID
+	}
+	return ids, nil
+}
+
+func setRanks(ctx context.Context, uploads *mongo.Collection, field string, ids []bson.ObjectID, ranks []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, len(ids))
+	for i, id := range ids {
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id}).
+			SetUpdate(bson.M{"$set": bson.M{field: ranks[i]}})
+	}
+	if _, err := uploads.BulkWrite(ctx, models); err != nil {
+		return fmt.Errorf("failed to save folder order: %w", err)
+	}
+	return nil
+}
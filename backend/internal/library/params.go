@@ -0,0 +1,112 @@
+package library
+
+import (
+	"fmt"
+	"lyked-backend/internal/search/query"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ParseListParams reads paging, sorting and filter query params for a
+// library listing. The caller fills in UserID.
+func ParseListParams(params url.Values) (ListOptions, error) {
+	return parseListParams(params, false)
+}
+
+// ParseTrashParams is ParseListParams for the trash, which additionally
+// sorts by deleted_at and does so by default.
+func ParseTrashParams(params url.Values) (ListOptions, error) {
+	return parseListParams(params, true)
+}
+
+func parseListParams(params url.Values, trashed bool) (ListOptions, error) {
+	opts := ListOptions{
+		Trashed:      trashed,
+		Sort:         params.Get("sort"),
+		Folder:       params.Get("folder"),
+		Subfolders:   params.Get("include_subfolders") == "true",
+		Tags:         NormalizeTags(params["tag"]),
+		Platform:     params.Get("platform"),
+		LinkStatuses: params["link_status"],
+		Statuses:     params["status"],
+		IncludeTotal: params.Get("include_total") == "true",
+	}
+
+	if trashed && opts.Sort == "" {
+		opts.Sort = SortDeletedAt
+	}
+
+	switch params.Get("order") {
+	case "asc":
+		opts.Ascending = true
+	case "":
+		// Manual order reads top to bottom.
+		opts.Ascending = opts.Sort == SortPosition
+	case "desc":
+	default:
+		return opts, fmt.Errorf("order must be 'asc' or 'desc'")
+	}
+
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return opts, fmt.Errorf("limit must be a positive integer")
+		}
+		opts.Limit = n
+	}
+
+	if raw := params.Get("cursor"); raw != "" {
+		cursor, err := DecodeCursor(raw)
+		if err != nil {
+			return opts, err
+		}
+		opts.Cursor = &cursor
+	}
+
+	switch params.Get("favorite") {
+	case "true":
+		favorite := true
+		opts.Favorite = &favorite
+	case "false":
+		favorite := false
+		opts.Favorite = &favorite
+	case "":
+	default:
+		return opts, fmt.Errorf("favorite must be 'true' or 'false'")
+	}
+
+	if rating := params.Get("min_rating"); rating != "" {
+		n, err := strconv.Atoi(rating)
+		if err != nil {
+			return opts, fmt.Errorf("min_rating must be an integer")
+		}
+		opts.MinRating = n
+	}
+
+	if from := params.Get("from"); from != "" {
+		t, err := ParseDate(from, false)
+		if err != nil {
+			return opts, err
+		}
+		opts.From = &t
+	}
+	if to := params.Get("to"); to != "" {
+		t, err := ParseDate(to, true)
+		if err != nil {
+			return opts, err
+		}
+		opts.To = &t
+	}
+
+	if q := params.Get("q"); q != "" {
+		parsed, err := query.Parse(q)
+		if err != nil {
+			return opts, err
+		}
+		if opts.Query, err = query.Compile(parsed, time.Now()); err != nil {
+			return opts, err
+		}
+	}
+	return opts, opts.Normalize()
+}
@@ -0,0 +1,69 @@
+package library
+
+import "strings"
+
+// Ranks order items within a folder. They are strings over rankDigits that
+// compare like fractions (0.xyz), so there is always room for a new rank
+// between two others and moving an item only rewrites that item. A rank
+// never ends in the lowest digit, which keeps that guarantee.
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// maxRankLength is how long ranks may grow through repeated inserts into
+// the same gap before the folder is renumbered.
+const maxRankLength = 48
+
+// RankBetween returns a rank that sorts after lo and before hi. An empty lo
+// means the start, an empty hi the end; lo must be less than hi.
+func RankBetween(lo, hi string) string {
+	if hi != "" {
+		n := 0
+		for n < len(hi) && rankDigit(lo, n) == hi[n] {
+			n++
+		}
+		if n > 0 {
+			return hi[:n] + RankBetween(rankTail(lo, n), hi[n:])
+		}
+	}
+	dlo := 0
+	if lo != "" {
+		dlo = strings.IndexByte(rankDigits, lo[0])
+	}
+	dhi := len(rankDigits)
+	if hi != "" {
+		dhi = strings.IndexByte(rankDigits, hi[0])
+	}
+	if dhi-dlo > 1 {
+		return string(rankDigits[(dlo+dhi)/2])
+	}
+	// Adjacent digits: a longer hi can be cut short, otherwise go a level deeper.
+	if len(hi) > 1 {
+		return hi[:1]
+	}
+	return string(rankDigits[dlo]) + RankBetween(rankTail(lo, 1), "")
+}
+
+// RanksBetween returns n ascending ranks between lo and hi, spread by
+// bisection so they stay short.
+func RanksBetween(lo, hi string, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+	mid := RankBetween(lo, hi)
+	left := (n - 1) / 2
+	ranks := append(RanksBetween(lo, mid, left), mid)
+	return append(ranks, RanksBetween(mid, hi, n-1-left)...)
+}
+
+func rankDigit(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return rankDigits[0]
+}
+
+func rankTail(s string, n int) string {
+	if n >= len(s) {
+		return ""
+	}
+	return s[n:]
+}
@@ -0,0 +1,142 @@
+package library
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// checkRank fails unless lo < r < hi, with empty bounds open, and r is
+// made of rank digits without a trailing lowest digit.
+func checkRank(t *testing.T, lo, r, hi string) {
+	t.Helper()
+	if r == "" || strings.Trim(r, rankDigits) != "" {
+		t.Fatalf("RankBetween(%q, %q) = %q, not a rank", lo, hi, r)
+	}
+	if r[len(r)-1] == rankDigits[0] {
+		t.Fatalf("RankBetween(%q, %q) = %q ends in %q", lo, hi, r, rankDigits[0])
+	}
+	if (lo != "" && r <= lo) || (hi != "" && r >= hi) {
+		t.Fatalf("RankBetween(%q, %q) = %q is out of order", lo, hi, r)
+	}
+}
+
+func TestRankBetween(t *testing.T) {
+	tests := []struct {
+		lo, hi, want string
+	}{
+		{"", "", "i"},
+		{"", "i", "9"},
+		{"i", "", "r"},
+		{"a", "c", "b"},
+		{"9", "a", "9i"},      // adjacent digits: a level deeper
+		{"a", "b5", "b"},      // a longer hi is cut short
+		{"z", "", "zi"},       // past the last digit
+		{"", "1", "0i"},       // below the first non-zero digit
+		{"", "001", "000i"},   // below a run of zeros
+		{"a", "a1", "a0i"},    // lo is a prefix of hi
+		{"ab", "az", "an"},    // shared prefix
+		{"a5x", "a6", "a5y"},  // deeper lo
+		{"h", "hzz", "hh"},    // hi extends lo
+		{"zz", "zzi", "zz9"},  // hi extends lo at the top
+		{"iz", "j", "izi"},    // lo at the top of its digit
+		{"zzzz", "", "zzzzi"}, // the end grows by one digit
+	}
+	for _, tt := range tests {
+		got := RankBetween(tt.lo, tt.hi)
+		if got != tt.want {
+			t.Errorf("RankBetween(%q, %q) = %q, want %q", tt.lo, tt.hi, got, tt.want)
+		}
+		checkRank(t, tt.lo, got, tt.hi)
+	}
+}
+
+// TestRankBetweenRandomInserts drops items into random gaps of a folder
+// and checks every rank lands in its gap and grows by at most a digit.
+func TestRankBetweenRandomInserts(t *testing.T) {
+	rng := rand.New(rand.NewPCG(44, 1))
+	ranks := []string{}
+	for range 5000 {
+		i := rng.IntN(len(ranks) + 1)
+		var lo, hi string
+		if i > 0 {
+			lo = ranks[i-1]
+		}
+		if i < len(ranks) {
+			hi = ranks[i]
+		}
+		r := RankBetween(lo, hi)
+		checkRank(t, lo, r, hi)
+		if len(r) > max(len(lo), len(hi))+1 {
+			t.Fatalf("RankBetween(%q, %q) = %q grew by more than a digit", lo, hi, r)
+		}
+		ranks = slices.Insert(ranks, i, r)
+	}
+	if !slices.IsSorted(ranks) {
+		t.Fatal("ranks are out of order")
+	}
+}
+
+// TestRankGrowth checks how fast repeated inserts into one gap use up
+// maxRankLength, which is when a folder gets renumbered.
+func TestRankGrowth(t *testing.T) {
+	for _, front := range []bool{true, false} {
+		lo, hi := "a", "b"
+		inserts := 0
+		for len(lo) <= maxRankLength && len(hi) <= maxRankLength {
+			r := RankBetween(lo, hi)
+			checkRank(t, lo, r, hi)
+			if front {
+				hi = r
+			} else {
+				lo = r
+			}
+			inserts++
+		}
+		// Each digit halves the gap about five times before the next one.
+		if inserts < 4*maxRankLength {
+			t.Errorf("front=%v: ranks passed %d digits after %d inserts", front, maxRankLength, inserts)
+		}
+	}
+
+	last := ""
+	for range 4 * maxRankLength {
+		last = RankBetween(last, "")
+	}
+	if len(last) > maxRankLength {
+		t.Errorf("%d appends made a rank of %d digits", 4*maxRankLength, len(last))
+	}
+}
+
+func TestRanksBetween(t *testing.T) {
+	tests := []struct {
+		lo, hi string
+		n      int
+		maxLen int
+	}{
+		{"", "", 0, 0},
+		{"", "", 1, 1},
+		{"", "", 5, 1},
+		{"", "", 1000, 2},
+		{"", "", 10000, 3},
+		{"a", "b", 100, 3},
+		{"r", "", 50, 2},
+		{"", "0i", 20, 3},
+	}
+	for _, tt := range tests {
+		ranks := RanksBetween(tt.lo, tt.hi, tt.n)
+		if len(ranks) != tt.n {
+			t.Fatalf("RanksBetween(%q, %q, %d) returned %d ranks", tt.lo, tt.hi, tt.n, len(ranks))
+		}
+		prev := tt.lo
+		for _, r := range ranks {
+			checkRank(t, prev, r, tt.hi)
+			if len(r) > tt.maxLen {
+				t.Errorf("RanksBetween(%q, %q, %d) made %q, longer than %d", tt.lo, tt.hi, tt.n, r, tt.maxLen)
+				break
+			}
+			prev = r
+		}
+	}
+}
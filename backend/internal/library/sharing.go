@@ -0,0 +1,372 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	DB "lyked-backend/internal/database/mongodb"
+	model "lyked-backend/internal/models/mongodb"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrForbidden        = errors.New("you don't have permission to do this in this folder")
+	ErrInvalidRole      = errors.New("role must be viewer, contributor, editor or owner")
+	ErrAlreadyMember    = errors.New("this user already has access to the folder")
+	ErrInviteNotFound   = errors.New("invite not found")
+	ErrMemberNotFound   = errors.New("member not found")
+	ErrInviteYourself   = errors.New("you can't invite yourself")
+	ErrShareSmart       = errors.New("smart folders can't be shared")
+	ErrMoveAcrossOwners = errors.New("folders can only be moved within their owner's folders")
+)
+
+var roleRanks = map[string]int{
+	model.RoleViewer:      1,
+	model.RoleContributor: 2,
+	model.RoleEditor:      3,
+	model.RoleOwner:       4,
+}
+
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleAtLeast reports whether role grants everything min does. The empty
+// role grants nothing.
+func RoleAtLeast(role, min string) bool {
+	return roleRanks[role] >= roleRanks[min]
+}
+
+// FolderRole is the user's role on the folder: owner for the folder's own
+// user, otherwise the strongest role they were given on it or on any
+// folder above it. Empty means no access.
+func FolderRole(ctx context.Context, folders *mongo.Collection, folder *model.Folder, userID string) (string, error) {
+	if folder.UserID == userID {
+		return model.RoleOwner, nil
+	}
+	ancestors := ancestorIDs(folder)
+	if len(ancestors) == 0 || memberRole(folder.Members, userID) == model.RoleOwner {
+		return roleIn(folder, nil, userID), nil
+	}
+	cursor, err := folders.Find(ctx,
+		bson.M{"_id": bson.M{"$in": ancestors}, "user_id": folder.UserID, "members.user_id": userID, "deleted_at": nil},
+		options.Find().SetProjection(bson.M{"user_id": 1, "members": 1}))
+	if err != nil {
+		return "", fmt.Errorf("failed to check folder access: %w", err)
+	}
+	var shared []model.Folder
+	if err := cursor.All(ctx, &shared); err != nil {
+		return "", fmt.Errorf("failed to check folder access: %w", err)
+	}
+	return roleIn(folder, shared, userID), nil
+}
+
+// roleIn works out FolderRole from the folders above the folder.
+// Ancestors of another owner are ignored.
+func roleIn(folder *model.Folder, ancestors []model.Folder, userID string) string {
+	if folder.UserID == userID {
+		return model.RoleOwner
+	}
+	role := memberRole(folder.Members, userID)
+	for _, f := range ancestors {
+		if f.UserID != folder.UserID {
+			continue
+		}
+		if r := memberRole(f.Members, userID); roleRanks[r] > roleRanks[role] {
+			role = r
+		}
+	}
+	return role
+}
+
+// ancestorIDs are the ids in the folder's path, outermost first.
+func ancestorIDs(folder *model.Folder) []bson.ObjectID {
+	var ids []bson.ObjectID
+	for _, raw := range strings.Split(strings.Trim(folder.AncestorPath(), "/"), "/") {
+		if id, err := bson.ObjectIDFromHex(raw); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// AccessFolder returns a live folder the user holds at least the min role
+// on, whoever owns it, along with their role. Folders the user can't see at
+// all are reported as not found.
+func AccessFolder(ctx context.Context, folders *mongo.Collection, userID, rawID, min string) (*model.Folder, string, error) {
+	id, err := bson.ObjectIDFromHex(rawID)
+	if err != nil {
+		return nil, "", ErrFolderNotFound
+	}
+	var folder model.Folder
+	err = folders.FindOne(ctx, bson.M{"_id": id, "deleted_at": nil}).Decode(&folder)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, "", ErrFolderNotFound
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch folder: %w", err)
+	}
+	role, err := FolderRole(ctx, folders, &folder, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if role == "" {
+		return nil, "", ErrFolderNotFound
+	}
+	if !RoleAtLeast(role, min) {
+		return nil, role, ErrForbidden
+	}
+	return &folder, role, nil
+}
+
+// SharedWithMe returns the live folders other users have shared with this
+// one. Their subfolders are reached through them.
+func SharedWithMe(ctx context.Context, folders *mongo.Collection, userID string) ([]model.Folder, error) {
+	cursor, err := folders.Find(ctx, bson.M{"members.user_id": userID, "deleted_at": nil},
+		options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch shared folders: %w", err)
+	}
+	shared := []model.Folder{}
+	if err := cursor.All(ctx, &shared); err != nil {
+		return nil, fmt.Errorf("failed to parse shared folders: %w", err)
+	}
+	return shared, nil
+}
+
+// Invite offers the invitee a role on the folder. Inviting someone who
+// already has a pending invite updates its role.
+func Invite(ctx context.Context, invites, folders *mongo.Collection, userID, rawFolderID, inviteeID, inviteeName, role string, now time.Time) (*model.FolderInvite, error) {
+	if !ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	if inviteeID == userID {
+		return nil, ErrInviteYourself
+	}
+	folder, _, err := AccessFolder(ctx, folders, userID, rawFolderID, model.RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	if folder.IsSmart() {
+		return nil, ErrShareSmart
+	}
+	if folder.UserID == inviteeID || memberRole(folder.Members, inviteeID) != "" {
+		return nil, ErrAlreadyMember
+	}
+
+	invite := model.FolderInvite{}
+	err = invites.FindOneAndUpdate(ctx,
+		bson.M{"folder_id": folder.ID, "user_id": inviteeID, "status": model.InviteStatusPending},
+		bson.M{
+			"$set":         bson.M{"role": role, "invited_by": userID, "folder_name": folder.Name, "username": inviteeName},
+			"$setOnInsert": bson.M{"_id": bson.NewObjectID(), "created_at": now},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&invite)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save invite: %w", err)
+	}
+	return &invite, nil
+}
+
+// PendingInvites lists the invites waiting for the user's answer.
+func PendingInvites(ctx context.Context, invites *mongo.Collection, userID string) ([]model.FolderInvite, error) {
+	return findInvites(ctx, invites, bson.M{"user_id": userID, "status": model.InviteStatusPending})
+}
+
+// FolderInvites lists the folder's pending invites.
+func FolderInvites(ctx context.Context, invites *mongo.Collection, folderID bson.ObjectID) ([]model.FolderInvite, error) {
+	return findInvites(ctx, invites, bson.M{"folder_id": folderID, "status": model.InviteStatusPending})
+}
+
+// RespondToInvite accepts or declines one of the user's pending invites.
+// Accepting adds them to the folder's members in the same transaction.
+func RespondToInvite(ctx context.Context, invites, folders *mongo.Collection, userID, rawInviteID string, accept bool, now time.Time) (*model.FolderInvite, error) {
+	id, err := bson.ObjectIDFromHex(rawInviteID)
+	if err != nil {
+		return nil, ErrInviteNotFound
+	}
+	status := model.InviteStatusDeclined
+	if accept {
+		status = model.InviteStatusAccepted
+	}
+	var invite model.FolderInvite
+	err = DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := invites.FindOneAndUpdate(ctx,
+			bson.M{"_id": id, "user_id": userID, "status": model.InviteStatusPending},
+			bson.M{"$set": bson.M{"status": status, "responded_at": now}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&invite)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrInviteNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update invite: %w", err)
+		}
+		if !accept {
+			return nil
+		}
+		member := model.FolderMember{UserID: userID, Username: invite.Username, Role: invite.Role, AddedAt: now}
+		res, err := folders.UpdateOne(ctx,
+			bson.M{"_id": invite.FolderID, "deleted_at": nil, "members.user_id": bson.M{"$ne": userID}},
+			bson.M{"$push": bson.M{"members": member}})
+		if err != nil {
+			return fmt.Errorf("failed to add member: %w", err)
+		}
+		if res.MatchedCount == 0 {
+			// The folder is gone, or they joined through another invite.
+			return ErrInviteNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// RevokeInvite withdraws a pending invite to the folder.
+func RevokeInvite(ctx context.Context, invites, folders *mongo.Collection, userID, rawFolderID, rawInviteID string, now time.Time) error {
+	folder, _, err := AccessFolder(ctx, folders, userID, rawFolderID, model.RoleOwner)
+	if err != nil {
+		return err
+	}
+	id, err := bson.ObjectIDFromHex(rawInviteID)
+	if err != nil {
+		return ErrInviteNotFound
+	}
+	res, err := invites.UpdateOne(ctx,
+		bson.M{"_id": id, "folder_id": folder.ID, "status": model.InviteStatusPending},
+		bson.M{"$set": bson.M{"status": model.InviteStatusRevoked, "responded_at": now}})
+	if err != nil {
+		return fmt.Errorf("failed to revoke invite: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrInviteNotFound
+	}
+	return nil
+}
+
+// SetMemberRole changes a member's role on the folder.
+func SetMemberRole(ctx context.Context, folders *mongo.Collection, userID, rawFolderID, memberID, role string) error {
+	if !ValidRole(role) {
+		return ErrInvalidRole
+	}
+	folder, _, err := AccessFolder(ctx, folders, userID, rawFolderID, model.RoleOwner)
+	if err != nil {
+		return err
+	}
+	res, err := folders.UpdateOne(ctx,
+		bson.M{"_id": folder.ID, "members.user_id": memberID},
+		bson.M{"$set": bson.M{"members.$.role": role}})
+	if err != nil {
+		return fmt.Errorf("failed to update member: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+// RemoveMember takes a member off the folder. Owners can remove anyone;
+// everyone can remove themselves. Items they filed come out of the folder
+// and its subfolders, unless they still have a role there through another
+// folder; the items stay in their own library.
+func RemoveMember(ctx context.Context, uploads, folders *mongo.Collection, userID, rawFolderID, memberID string) error {
+	min := model.RoleOwner
+	if memberID == userID {
+		min = model.RoleViewer
+	}
+	folder, _, err := AccessFolder(ctx, folders, userID, rawFolderID, min)
+	if err != nil {
+		return err
+	}
+	return DB.WithTransaction(ctx, func(ctx context.Context) error {
+		res, err := folders.UpdateOne(ctx,
+			bson.M{"_id": folder.ID, "members.user_id": memberID},
+			bson.M{"$pull": bson.M{"members": bson.M{"user_id": memberID}}})
+		if err != nil {
+			return fmt.Errorf("failed to remove member: %w", err)
+		}
+		if res.MatchedCount == 0 {
+			return ErrMemberNotFound
+		}
+		return unfileLostItems(ctx, uploads, folders, folder, memberID)
+	})
+}
+
+// unfileLostItems takes the user's items out of the folders of the subtree
+// they no longer have a role on. Trashed subfolders are included, so a
+// restore doesn't bring the items back.
+func unfileLostItems(ctx context.Context, uploads, folders *mongo.Collection, folder *model.Folder, userID string) error {
+	cursor, err := folders.Find(ctx,
+		bson.M{"user_id": folder.UserID, "kind": bson.M{"$ne": model.FolderKindSmart}, "$or": bson.A{
+			bson.M{"_id": folder.ID},
+			bson.M{"path": bson.Regex{Pattern: "^" + regexp.QuoteMeta(folder.ChildPath())}},
+		}},
+		options.Find().SetProjection(bson.M{"user_id": 1, "kind": 1, "path": 1, "members": 1}))
+	if err != nil {
+		return fmt.Errorf("failed to load subfolders: %w", err)
+	}
+	var subtree []model.Folder
+	if err := cursor.All(ctx, &subtree); err != nil {
+		return fmt.Errorf("failed to parse subfolders: %w", err)
+	}
+	index := newFolderIndex()
+	for _, f := range subtree {
+		index.folders[f.ID.Hex()] = &f
+	}
+	index.lookup = func(rawID string) (*model.Folder, error) {
+		return lookupStaticFolder(ctx, folders, rawID)
+	}
+
+	lost := []string{}
+	unset := bson.M{}
+	for _, f := range subtree {
+		role, err := index.role(f.ID.Hex(), userID)
+		if err != nil {
+			return err
+		}
+		if role == "" {
+			lost = append(lost, f.ID.Hex())
+			unset["positions."+f.ID.Hex()] = ""
+		}
+	}
+	if len(lost) == 0 {
+		return nil
+	}
+	_, err = uploads.UpdateMany(ctx, bson.M{"user_id": userID, "folders": bson.M{"$in": lost}},
+		bson.M{"$pull": bson.M{"folders": bson.M{"$in": lost}}, "$unset": unset})
+	if err != nil {
+		return fmt.Errorf("failed to take items out of the folder: %w", err)
+	}
+	return nil
+}
+
+func findInvites(ctx context.Context, invites *mongo.Collection, filter bson.M) ([]model.FolderInvite, error) {
+	cursor, err := invites.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch invites: %w", err)
+	}
+	found := []model.FolderInvite{}
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, fmt.Errorf("failed to parse invites: %w", err)
+	}
+	return found, nil
+}
+
+func memberRole(members []model.FolderMember, userID string) string {
+	for _, m := range members {
+		if m.UserID == userID {
+			return m.Role
+		}
+	}
+	return ""
+}
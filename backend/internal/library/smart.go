@@ -0,0 +1,64 @@
+package library
+
+import (
+	"fmt"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/search/query"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var relativeWithin = regexp.MustCompile(`^\d+[dwmy]$`)
+
+// SmartFolderFilter compiles a smart folder definition into a Mongo filter,
+// evaluated against now so relative ranges keep moving. It does not add the
+// user scope.
+func SmartFolderFilter(f *model.SmartFilter, now time.Time) (bson.M, error) {
+	if f == nil {
+		return nil, fmt.Errorf("smart folder has no filter")
+	}
+	parts := bson.A{}
+	if tags := NormalizeTags(f.Tags); len(tags) > 0 {
+		parts = append(parts, bson.M{"tags": bson.M{"$all": TagPatterns(tags)}})
+	}
+	if tags := NormalizeTags(f.ExcludeTags); len(tags) > 0 {
+		parts = append(parts, bson.M{"tags": bson.M{"$nin": TagPatterns(tags)}})
+	}
+	if len(f.Platforms) > 0 {
+		parts = append(parts, bson.M{"platform": bson.M{"$in": f.Platforms}})
+	}
+	if f.From != nil {
+		parts = append(parts, bson.M{"saved_at": bson.M{"$gte": *f.From}})
+	}
+	if f.To != nil {
+		parts = append(parts, bson.M{"saved_at": bson.M{"$lte": *f.To}})
+	}
+	if f.SavedWithin != "" {
+		value := f.SavedWithin
+		if relativeWithin.MatchString(value) {
+			value += ".." // "30d" means the last 30 days, up to now
+		}
+		within, err := query.Compile(query.Field{Name: "saved", Value: value}, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid saved_within: %w", err)
+		}
+		parts = append(parts, within)
+	}
+	if f.Query != "" {
+		parsed, err := query.Parse(f.Query)
+		if err != nil {
+			return nil, err
+		}
+		compiled, err := query.Compile(parsed, now)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, compiled)
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("smart folder filter must set at least one condition")
+	}
+	return bson.M{"$and": parts}, nil
+}
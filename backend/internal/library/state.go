@@ -0,0 +1,200 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	model "lyked-backend/internal/models/mongodb"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var ErrUploadNotFound = errors.New("upload not found")
+
+// StateUpdate changes an item's favorite flag, rating and watch status.
+// Nil fields are left alone; a rating of 0 clears it.
+type StateUpdate struct {
+	Favorite *bool   `json:"favorite"`
+	Rating   *int    `json:"rating"`
+	Status   *string `json:"status"`
+}
+
+func (u *StateUpdate) Validate() error {
+	if u.Favorite == nil && u.Rating == nil && u.Status == nil {
+		return fmt.Errorf("provide favorite, rating or status")
+	}
+	if u.Rating != nil {
+		if err := validateRating(*u.Rating, true); err != nil {
+			return err
+		}
+	}
+	if u.Status != nil {
+		if err := ValidateStatus(*u.Status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateState applies the update to one of the user's uploads and returns
+// the upload as it is afterwards. Timestamps only move for values that
+// actually change.
+func UpdateState(ctx context.Context, uploads *mongo.Collection, userID string, id bson.ObjectID, u StateUpdate, now time.Time) (*model.LykedUploads, error) {
+	filter := bson.M{"_id": id, "user_id": userID, "deleted_at": nil}
+	var upload model.LykedUploads
+	err := uploads.FindOne(ctx, filter).Decode(&upload)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch upload: %w", err)
+	}
+
+	set, unset := bson.M{}, bson.M{}
+	if u.Favorite != nil && *u.Favorite != upload.Favorite {
+		upload.Favorite = *u.Favorite
+		if upload.Favorite {
+			upload.FavoritedAt = &now
+			set["favorite"] = true
+			set["favorited_at"] = now
+		} else {
+			upload.FavoritedAt = nil
+			unset["favorite"] = ""
+			unset["favorited_at"] = ""
+		}
+	}
+	if u.Rating != nil && *u.Rating != upload.Rating {
+		upload.Rating = *u.Rating
+		if upload.Rating == 0 {
+			upload.RatedAt = nil
+			unset["rating"] = ""
+			unset["rated_at"] = ""
+		} else {
+			upload.RatedAt = &now
+			set["rating"] = upload.Rating
+			set["rated_at"] = now
+		}
+	}
+	if u.Status != nil && *u.Status != StatusOf(upload) {
+		upload.Status = *u.Status
+		upload.StatusChangedAt = &now
+		set["status"] = upload.Status
+		set["status_changed_at"] = now
+		if upload.Status == model.WatchStatusWatched {
+			upload.WatchedAt = &now
+			set["watched_at"] = now
+		} else if upload.Status == model.WatchStatusUnwatched {
+			upload.WatchedAt = nil
+			unset["watched_at"] = ""
+		}
+	}
+	if len(set) == 0 && len(unset) == 0 {
+		return &upload, nil
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	res, err := uploads.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update upload: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, ErrUploadNotFound
+	}
+	return &upload, nil
+}
+
+// InitState checks the state fields of a new upload and stamps the
+// timestamps of those that are set.
+func InitState(upload *model.LykedUploads, now time.Time) error {
+	if err := validateRating(upload.Rating, true); err != nil {
+		return err
+	}
+	if upload.Status != "" {
+		if err := ValidateStatus(upload.Status); err != nil {
+			return err
+		}
+	}
+	upload.FavoritedAt, upload.RatedAt, upload.StatusChangedAt, upload.WatchedAt = nil, nil, nil, nil
+	if upload.Favorite {
+		upload.FavoritedAt = &now
+	}
+	if upload.Rating != 0 {
+		upload.RatedAt = &now
+	}
+	if upload.Status != "" {
+		upload.StatusChangedAt = &now
+	}
+	if upload.Status == model.WatchStatusWatched {
+		upload.WatchedAt = &now
+	}
+	return nil
+}
+
+// StatusOf returns the upload's watch status, unwatched when none is set.
+func StatusOf(upload model.LykedUploads) string {
+	if upload.Status == "" {
+		return model.WatchStatusUnwatched
+	}
+	return upload.Status
+}
+
+// statusFilter matches any of the statuses; unwatched also matches items
+// that never had one.
+func statusFilter(statuses []string) bson.M {
+	values := bson.A{}
+	for _, s := range statuses {
+		values = append(values, s)
+		if s == model.WatchStatusUnwatched {
+			values = append(values, nil)
+		}
+	}
+	return bson.M{"$in": values}
+}
+
+// TrashUpdate moves uploads or folders to the trash. The trash package
+// owns when that happens; batches write it through here too, so both set
+// the same fields.
+func TrashUpdate(userID string, now time.Time) bson.M {
+	return bson.M{"$set": bson.M{"deleted_at": now, "deleted_by": userID}}
+}
+
+// RestoreUpdate takes uploads or folders out of the trash.
+func RestoreUpdate() bson.M {
+	return bson.M{"$unset": bson.M{"deleted_at": "", "deleted_by": ""}}
+}
+
+// MigrateWatchStatus gives items that were marked watched before watch
+// statuses existed the watched status.
+func MigrateWatchStatus(ctx context.Context, uploads *mongo.Collection) error {
+	_, err := uploads.UpdateMany(ctx,
+		bson.M{"status": nil, "watched_at": bson.M{"$ne": nil}},
+		[]bson.M{{"$set": bson.M{"status": model.WatchStatusWatched, "status_changed_at": "$watched_at"}}})
+	if err != nil {
+		return fmt.Errorf("failed to migrate watch status: %w", err)
+	}
+	return nil
+}
+
+func validateRating(rating int, allowZero bool) error {
+	if (rating == 0 && allowZero) || (rating >= model.MinRating && rating <= model.MaxRating) {
+		return nil
+	}
+	return fmt.Errorf("rating must be between %d and %d", model.MinRating, model.MaxRating)
+}
+
+// ValidateStatus rejects anything that isn't one of the watch statuses.
+func ValidateStatus(status string) error {
+	switch status {
+	case model.WatchStatusUnwatched, model.WatchStatusInProgress, model.WatchStatusWatched, model.WatchStatusArchived:
+		return nil
+	}
+	return fmt.Errorf("unsupported status %q", status)
+}
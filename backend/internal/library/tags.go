@@ -0,0 +1,582 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	DB "lyked-backend/internal/database/mongodb"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/utils"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const maxEmojiRunes = 8
+
+var (
+	ErrTagRequired  = errors.New("tag is required")
+	ErrMergeTarget  = errors.New("a tag can't be merged into itself")
+	ErrMergeBelow   = errors.New("a tag can't be merged into a tag below it")
+	ErrNoTagMeta    = errors.New("color or emoji is required")
+	ErrInvalidColor = errors.New("color must look like #1e90ff")
+	ErrInvalidEmoji = errors.New("emoji must be a short symbol without letters or spaces")
+)
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// TagCount is one of the user's tags with how many live items carry it and
+// how it is displayed.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+	Color string `json:"color,omitempty"`
+	Emoji string `json:"emoji,omitempty"`
+}
+
+// TagNode is a tag in the hierarchy. Count is how many live items carry
+// the tag itself, Total how many carry it or a tag below it. Levels no item
+// carries on their own show up with a count of 0.
+type TagNode struct {
+	Tag      string    `json:"tag"`
+	Name     string    `json:"name"` // the last level of the tag
+	Count    int       `json:"count"`
+	Total    int       `json:"total"`
+	Color    string    `json:"color,omitempty"`
+	Emoji    string    `json:"emoji,omitempty"`
+	Children []TagNode `json:"children"`
+}
+
+// TagMetaUpdate changes how a tag is displayed. Nil fields are kept, empty
+// ones cleared.
+type TagMetaUpdate struct {
+	Color *string `json:"color"`
+	Emoji *string `json:"emoji"`
+}
+
+func (u *TagMetaUpdate) Validate() error {
+	if u.Color == nil && u.Emoji == nil {
+		return ErrNoTagMeta
+	}
+	if u.Color != nil {
+		*u.Color = strings.ToLower(strings.TrimSpace(*u.Color))
+		if *u.Color != "" && !colorPattern.MatchString(*u.Color) {
+			return ErrInvalidColor
+		}
+	}
+	if u.Emoji != nil {
+		*u.Emoji = strings.TrimSpace(*u.Emoji)
+		if utf8.RuneCountInString(*u.Emoji) > maxEmojiRunes {
+			return ErrInvalidEmoji
+		}
+		for _, r := range *u.Emoji {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) {
+				return ErrInvalidEmoji
+			}
+		}
+	}
+	return nil
+}
+
+// NormalizeTags normalizes the tags and drops empty ones and duplicates,
+// keeping the first occurrence's position.
+func NormalizeTags(tags []string) []string {
+	out := []string{}
+	for _, tag := range tags {
+		tag = utils.NormalizeTag(tag)
+		if tag != "" && !slices.Contains(out, tag) {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// TagPatterns matches each tag and its descendants, for use with $all, $in
+// and $nin on the tags field.
+func TagPatterns(tags []string) bson.A {
+	patterns := make(bson.A, len(tags))
+	for i, tag := range tags {
+		patterns[i] = bson.Regex{Pattern: utils.TagPattern(tag)}
+	}
+	return patterns
+}
+
+// ListTags returns every tag on the user's live items, most used first,
+// along with tags that only have display metadata so far.
+func ListTags(ctx context.Context, uploads, meta *mongo.Collection, userID string) ([]TagCount, error) {
+	cursor, err := uploads.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID, "deleted_at": nil}}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tags: %w", err)
+	}
+	var buckets []struct {
+		Tag   string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return nil, fmt.Errorf("failed to parse tag counts: %w", err)
+	}
+
+	metas, err := tagMetas(ctx, meta, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]TagCount, 0, len(buckets))
+	for _, b := range buckets {
+		t := TagCount{Tag: b.Tag, Count: b.Count}
+		if m, ok := metas[b.Tag]; ok {
+			t.Color, t.Emoji = m.Color, m.Emoji
+			delete(metas, b.Tag)
+		}
+		tags = append(tags, t)
+	}
+	for _, m := range metas {
+		tags = append(tags, TagCount{Tag: m.Tag, Color: m.Color, Emoji: m.Emoji})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags, nil
+}
+
+// TagTree returns the user's tags as a hierarchy, each level sorted by
+// name.
+func TagTree(ctx context.Context, uploads, meta *mongo.Collection, userID string) ([]TagNode, error) {
+	cursor, err := uploads.Find(ctx, bson.M{"user_id": userID, "deleted_at": nil, "tags.0": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"tags": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tags: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	nodes := map[string]*TagNode{}
+	node := func(tag string) *TagNode {
+		n, ok := nodes[tag]
+		if !ok {
+			n = &TagNode{Tag: tag, Name: tag[strings.LastIndex(tag, utils.TagSeparator)+1:], Children: []TagNode{}}
+			nodes[tag] = n
+		}
+		return n
+	}
+	for cursor.Next(ctx) {
+		var u struct {
+			Tags []string `bson:"tags"`
+		}
+		if err := cursor.Decode(&u); err != nil {
+			return nil, fmt.Errorf("failed to parse tags: %w", err)
+		}
+		// An item counts once towards each level above its tags.
+		levels := map[string]bool{}
+		for _, tag := range u.Tags {
+			node(tag).Count++
+			for _, level := range tagLevels(tag) {
+				levels[level] = true
+			}
+		}
+		for level := range levels {
+			node(level).Total++
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch tags: %w", err)
+	}
+
+	metas, err := tagMetas(ctx, meta, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	for tag, m := range metas {
+		for _, level := range tagLevels(tag) {
+			node(level)
+		}
+		nodes[tag].Color, nodes[tag].Emoji = m.Color, m.Emoji
+	}
+
+	children := map[string][]string{}
+	for tag := range nodes {
+		parent := ""
+		if i := strings.LastIndex(tag, utils.TagSeparator); i >= 0 {
+			parent = tag[:i]
+		}
+		children[parent] = append(children[parent], tag)
+	}
+	var build func(parent string) []TagNode
+	build = func(parent string) []TagNode {
+		tags := children[parent]
+		sort.Strings(tags)
+		out := make([]TagNode, 0, len(tags))
+		for _, tag := range tags {
+			n := *nodes[tag]
+			n.Children = build(tag)
+			out = append(out, n)
+		}
+		return out
+	}
+	return build(""), nil
+}
+
+// tagLevels returns the tag and every tag above it: food/baking/bread,
+// food/baking and food.
+func tagLevels(tag string) []string {
+	levels := []string{tag}
+	for i := strings.LastIndex(tag, utils.TagSeparator); i >= 0; i = strings.LastIndex(tag, utils.TagSeparator) {
+		tag = tag[:i]
+		levels = append(levels, tag)
+	}
+	return levels
+}
+
+// MergeTags replaces the source tags with target on every item of the
+// user's, trashed ones included, and in their smart folder filters, review
+// settings, rules and aliases. Tags below a source move along, so merging
+// food into cooking turns food/baking into cooking/baking. Renaming a tag
+// is merging it into its new name. It returns the ids of the items that
+// changed.
+func MergeTags(ctx context.Context, uploads, folders, meta *mongo.Collection, userID string, sources []string, target string, now time.Time) ([]bson.ObjectID, error) {
+	aliases, err := LoadTagAliases(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	target = aliases.Resolve(target)
+	if target == "" {
+		return nil, ErrTagRequired
+	}
+	sources = NormalizeTags(sources)
+	if len(sources) == 0 {
+		return nil, ErrTagRequired
+	}
+	if sources = slices.DeleteFunc(sources, func(t string) bool { return t == target }); len(sources) == 0 {
+		return nil, ErrMergeTarget
+	}
+	for _, source := range sources {
+		if utils.TagWithin(target, source) {
+			return nil, ErrMergeBelow
+		}
+	}
+	// The most specific source wins, so merging food and food/baking into
+	// cooking turns food/baking/bread into cooking/bread.
+	sort.Slice(sources, func(i, j int) bool { return len(sources[i]) > len(sources[j]) })
+
+	var changed []bson.ObjectID
+	err = DB.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if changed, err = taggedIDs(ctx, uploads, userID, bson.M{"$in": TagPatterns(sources)}); err != nil {
+			return err
+		}
+		if len(changed) > 0 {
+			_, err = uploads.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": changed}},
+				bson.A{bson.M{"$set": bson.M{"tags": renameTagsExpr("tags", sources, target)}}})
+			if err != nil {
+				return fmt.Errorf("failed to rename tags: %w", err)
+			}
+		}
+		_, err = folders.UpdateMany(ctx,
+			bson.M{"user_id": userID, "kind": model.FolderKindSmart, "$or": bson.A{
+				bson.M{"filter.tags": bson.M{"$in": sources}},
+				bson.M{"filter.exclude_tags": bson.M{"$in": sources}},
+			}},
+			bson.A{bson.M{"$set": bson.M{
+				"filter.tags":         renameTagsExpr("filter.tags", sources, target),
+				"filter.exclude_tags": renameTagsExpr("filter.exclude_tags", sources, target),
+			}}})
+		if err != nil {
+			return fmt.Errorf("failed to update smart folders: %w", err)
+		}
+		if settings, err := DB.GetCollection("review_settings"); err == nil {
+			_, err = settings.UpdateOne(ctx, bson.M{"_id": userID, "tags": bson.M{"$in": sources}},
+				bson.A{bson.M{"$set": bson.M{"tags": renameTagsExpr("tags", sources, target)}}})
+			if err != nil {
+				return fmt.Errorf("failed to update review settings: %w", err)
+			}
+		}
+		if rules, err := DB.GetCollection("rules"); err == nil {
+			_, err = rules.UpdateMany(ctx, bson.M{"user_id": userID, "actions.add_tags": bson.M{"$in": sources}},
+				bson.A{bson.M{"$set": bson.M{"actions.add_tags": renameTagsExpr("actions.add_tags", sources, target)}}})
+			if err != nil {
+				return fmt.Errorf("failed to update rules: %w", err)
+			}
+		}
+		if aliases, err := DB.GetCollection("tag_aliases"); err == nil {
+			_, err = aliases.UpdateMany(ctx, bson.M{"user_id": userID, "tag": bson.M{"$in": TagPatterns(sources)}},
+				bson.A{bson.M{"$set": bson.M{"tag": renameTagExpr("$tag", sources, target)}}})
+			if err != nil {
+				return fmt.Errorf("failed to update tag aliases: %w", err)
+			}
+		}
+		return mergeTagMeta(ctx, meta, userID, sources, target, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// DeleteTags takes the tags off every item of the user's, trashed ones
+// included, and drops their metadata. Tags below them are kept. Smart
+// folders and review settings keep naming them and simply match nothing
+// for them. It returns the ids of the items that changed.
+func DeleteTags(ctx context.Context, uploads, meta *mongo.Collection, userID string, tags []string) ([]bson.ObjectID, error) {
+	tags = NormalizeTags(tags)
+	if len(tags) == 0 {
+		return nil, ErrTagRequired
+	}
+	var changed []bson.ObjectID
+	err := DB.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if changed, err = taggedIDs(ctx, uploads, userID, bson.M{"$in": tags}); err != nil {
+			return err
+		}
+		if len(changed) > 0 {
+			_, err = uploads.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": changed}}, bson.M{"$pull": bson.M{"tags": bson.M{"$in": tags}}})
+			if err != nil {
+				return fmt.Errorf("failed to delete tags: %w", err)
+			}
+		}
+		if _, err := meta.DeleteMany(ctx, bson.M{"user_id": userID, "tag": bson.M{"$in": tags}}); err != nil {
+			return fmt.Errorf("failed to delete tag metadata: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// SetTagMeta changes a tag's color and emoji. A tag left with neither has
+// its metadata removed.
+func SetTagMeta(ctx context.Context, meta *mongo.Collection, userID, tag string, update TagMetaUpdate, now time.Time) (*model.TagMeta, error) {
+	tag = utils.NormalizeTag(tag)
+	if tag == "" {
+		return nil, ErrTagRequired
+	}
+	if err := update.Validate(); err != nil {
+		return nil, err
+	}
+	set, unset := bson.M{"updated_at": now}, bson.M{}
+	for field, value := range map[string]*string{"color": update.Color, "emoji": update.Emoji} {
+		switch {
+		case value == nil:
+		case *value == "":
+			unset[field] = ""
+		default:
+			set[field] = *value
+		}
+	}
+	change := bson.M{"$set": set}
+	if len(unset) > 0 {
+		change["$unset"] = unset
+	}
+	result := model.TagMeta{}
+	err := meta.FindOneAndUpdate(ctx, bson.M{"user_id": userID, "tag": tag}, change,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save tag metadata: %w", err)
+	}
+	if result.Color == "" && result.Emoji == "" {
+		if _, err := meta.DeleteOne(ctx, bson.M{"user_id": userID, "tag": tag}); err != nil {
+			return nil, fmt.Errorf("failed to save tag metadata: %w", err)
+		}
+	}
+	return &result, nil
+}
+
+// MigrateTags normalizes tags saved before tags were normalized or had
+// levels, merging the ones that only differed in case or spacing and
+// tidying the slashes of hierarchical ones: "Food / Baking/" becomes
+// food/baking. It returns how many items changed.
+func MigrateTags(ctx context.Context, uploads *mongo.Collection) (int, error) {
+	cursor, err := uploads.Find(ctx,
+		bson.M{"tags": bson.Regex{Pattern: `\p{Lu}|^\s|\s$|\s\s|[\t\n\r\f\v]|^/|/$|//|\s/|/\s`}},
+		options.Find().SetProjection(bson.M{"tags": 1}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find tags to normalize: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	var fixes []mongo.WriteModel
+	flush := func() error {
+		if len(fixes) == 0 {
+			return nil
+		}
+		if _, err := uploads.BulkWrite(ctx, fixes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return fmt.Errorf("failed to normalize tags: %w", err)
+		}
+		migrated += len(fixes)
+		fixes = fixes[:0]
+		return nil
+	}
+	for cursor.Next(ctx) {
+		var u struct {
+			ID   bson.RawValue `bson:"_id"`
+			Tags []string      `bson:"tags"`
+		}
+		if err := cursor.Decode(&u); err != nil {
+			return migrated, fmt.Errorf("failed to parse upload: %w", err)
+		}
+		if tags := NormalizeTags(u.Tags); !slices.Equal(tags, u.Tags) {
+			fixes = append(fixes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": u.ID}).
+				SetUpdate(bson.M{"$set": bson.M{"tags": tags}}))
+			if len(fixes) >= repairChunk {
+				if err := flush(); err != nil {
+					return migrated, err
+				}
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return migrated, fmt.Errorf("failed to find tags to normalize: %w", err)
+	}
+	return migrated, flush()
+}
+
+// taggedIDs returns the ids of the user's items whose tags match.
+func taggedIDs(ctx context.Context, uploads *mongo.Collection, userID string, match bson.M) ([]bson.ObjectID, error) {
+	cursor, err := uploads.Find(ctx, bson.M{"user_id": userID, "tags": match},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tagged items: %w", err)
+	}
+	var docs []struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to parse tagged items: %w", err)
+	}
+	ids := make([]bson.ObjectID, len(docs))
+	for i, d := range docs {
+		ids[i] = d.ID
+	}
+	return ids, nil
+}
+
+// renameTagsExpr is an update expression for the array field with the
+// sources, and the tags below them, renamed where they stood and
+// duplicates removed.
+func renameTagsExpr(field string, sources []string, target string) bson.M {
+	renamed := bson.M{"$map": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$" + field, bson.A{}}},
+		"as":    "t",
+		"in":    renameTagExpr("$$t", sources, target),
+	}}
+	return bson.M{"$reduce": bson.M{
+		"input":        renamed,
+		"initialValue": bson.A{},
+		"in": bson.M{"$cond": bson.A{
+			bson.M{"$in": bson.A{"$$this", "$$value"}},
+			"$$value",
+			bson.M{"$concatArrays": bson.A{"$$value", bson.A{"$$this"}}},
+		}},
+	}}
+}
+
+// renameTagExpr is renameTag as an aggregation expression on a tag value.
+// sources must be sorted longest first.
+func renameTagExpr(tag string, sources []string, target string) bson.M {
+	match := bson.M{"$arrayElemAt": bson.A{bson.M{"$filter": bson.M{
+		"input": sources,
+		"as":    "s",
+		"cond": bson.M{"$or": bson.A{
+			bson.M{"$eq": bson.A{tag, "$$s"}},
+			bson.M{"$eq": bson.A{bson.M{"$indexOfCP": bson.A{tag, bson.M{"$concat": bson.A{"$$s", utils.TagSeparator}}}}, 0}},
+		}},
+	}}, 0}}
+	return bson.M{"$let": bson.M{
+		"vars": bson.M{"m": match},
+		"in": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$type": "$$m"}, "missing"}},
+			tag,
+			bson.M{"$concat": bson.A{target, bson.M{"$substrCP": bson.A{tag, bson.M{"$strLenCP": "$$m"}, bson.M{"$strLenCP": tag}}}}},
+		}},
+	}}
+}
+
+// renameTag moves a tag at or below the first source it falls within to
+// the same place below target. sources must be sorted longest first.
+func renameTag(tag string, sources []string, target string) string {
+	for _, source := range sources {
+		if utils.TagWithin(tag, source) {
+			return target + strings.TrimPrefix(tag, source)
+		}
+	}
+	return tag
+}
+
+// mergeTagMeta moves the metadata of the sources and the tags below them
+// to their new names, keeping what the new names already had.
+func mergeTagMeta(ctx context.Context, meta *mongo.Collection, userID string, sources []string, target string, now time.Time) error {
+	moving, err := tagMetas(ctx, meta, bson.M{"user_id": userID, "tag": bson.M{"$in": TagPatterns(sources)}})
+	if err != nil || len(moving) == 0 {
+		return err
+	}
+	old := make([]string, 0, len(moving))
+	renamed := make([]string, 0, len(moving))
+	for tag := range moving {
+		old = append(old, tag)
+		renamed = append(renamed, renameTag(tag, sources, target))
+	}
+	taken, err := tagMetas(ctx, meta, bson.M{"user_id": userID, "tag": bson.M{"$in": renamed}})
+	if err != nil {
+		return err
+	}
+	if _, err := meta.DeleteMany(ctx, bson.M{"user_id": userID, "tag": bson.M{"$in": old}}); err != nil {
+		return fmt.Errorf("failed to delete tag metadata: %w", err)
+	}
+	for _, tag := range old {
+		delete(taken, tag)
+	}
+	// Sources come first, so a target without metadata takes the first
+	// source's.
+	sort.Slice(old, func(i, j int) bool {
+		ri, rj := slices.Index(sources, old[i]), slices.Index(sources, old[j])
+		if (ri < 0) != (rj < 0) {
+			return ri >= 0
+		}
+		return old[i] < old[j]
+	})
+	for _, tag := range old {
+		name := renameTag(tag, sources, target)
+		if _, ok := taken[name]; ok {
+			continue
+		}
+		m := moving[tag]
+		m.Tag, m.UpdatedAt = name, now
+		if _, err := meta.InsertOne(ctx, m); err != nil {
+			return fmt.Errorf("failed to move tag metadata: %w", err)
+		}
+		taken[name] = m
+	}
+	return nil
+}
+
+func tagMetas(ctx context.Context, meta *mongo.Collection, filter bson.M) (map[string]model.TagMeta, error) {
+	cursor, err := meta.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tag metadata: %w", err)
+	}
+	var found []model.TagMeta
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, fmt.Errorf("failed to parse tag metadata: %w", err)
+	}
+	metas := make(map[string]model.TagMeta, len(found))
+	for _, m := range found {
+		metas[m.Tag] = m
+	}
+	return metas, nil
+}
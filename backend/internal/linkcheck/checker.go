@@ -0,0 +1,182 @@
+// Package linkcheck probes saved links and records whether they still work.
+package linkcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	DB "lyked-backend/internal/database/mongodb"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/utils"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DefaultHostInterval = 2 * time.Second    // between two requests to the same host
+	DefaultRecheckAfter = 7 * 24 * time.Hour // how stale a check may get before it is redone
+
+	userAgent      = "Mozilla/5.0 (compatible; LykedLinkChecker/1.0)"
+	maxBodyBytes   = 256 << 10 // enough to reach the markers in the page head
+	requestTimeout = 15 * time.Second
+	sweepBatch     = 200
+	workers        = 4
+)
+
+// Result is the outcome of probing one link.
+type Result struct {
+	Status     string    `json:"status"`
+	HTTPStatus int       `json:"http_status,omitempty"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// Checker probes links, never hitting a host more than once per interval.
+type Checker struct {
+	client  *http.Client
+	limiter *hostLimiter
+}
+
+func NewChecker(hostInterval time.Duration) *Checker {
+	return &Checker{
+		client:  &http.Client{Transport: utils.PublicTransport(false), Timeout: requestTimeout},
+		limiter: newHostLimiter(hostInterval),
+	}
+}
+
+// Default is shared by the background sweep and on-demand checks, so both
+// respect the same per-host limits.
+var Default = NewChecker(DefaultHostInterval)
+
+// Check fetches the link and classifies the response. Network failures
+// and anything ambiguous come back as unknown.
+func (c *Checker) Check(ctx context.Context, link string) Result {
+	result := Result{Status: model.LinkStatusUnknown, CheckedAt: time.Now().UTC()}
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return result
+	}
+	if err := c.limiter.wait(ctx, u.Hostname()); err != nil {
+		return result
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return result
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept-Language", "en") // the page markers are English
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return result
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+
+	result.HTTPStatus = resp.StatusCode
+	result.Status = Classify(utils.DetectPlatform(link), resp.StatusCode, resp.Request.URL.String(), body)
+	result.CheckedAt = time.Now().UTC()
+	return result
+}
+
+// Record stores a result on the upload. An unknown result doesn't wipe out
+// a status learned earlier; it only moves the check time forward.
+func Record(ctx context.Context, id bson.ObjectID, r Result) error {
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		return err
+	}
+	set := bson.M{"link_checked_at": r.CheckedAt}
+	filter := bson.M{"_id": id}
+	if r.Status != model.LinkStatusUnknown {
+		set["link_status"] = r.Status
+	}
+	if _, err := uploads.UpdateOne(ctx, filter, bson.M{"$set": set}); err != nil {
+		return fmt.Errorf("failed to record link status: %w", err)
+	}
+	if r.Status == model.LinkStatusUnknown {
+		_, err = uploads.UpdateOne(ctx, bson.M{"_id": id, "link_status": nil},
+			bson.M{"$set": bson.M{"link_status": r.Status}})
+		if err != nil {
+			return fmt.Errorf("failed to record link status: %w", err)
+		}
+	}
+	return nil
+}
+
+type linkTarget struct {
+	ID        bson.ObjectID `bson:"_id"`
+	VideoLink string        `bson:"video_link"`
+}
+
+// Sweep checks the links whose last check is missing or older than
+// recheckAfter, oldest first, and returns how many it checked.
+func (c *Checker) Sweep(ctx context.Context, recheckAfter time.Duration) (int, error) {
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().UTC().Add(-recheckAfter)
+	cursor, err := uploads.Find(ctx,
+		bson.M{"deleted_at": nil, "$or": bson.A{
+			bson.M{"link_checked_at": nil},
+			bson.M{"link_checked_at": bson.M{"$lt": cutoff}},
+		}},
+		options.Find().
+			SetProjection(bson.M{"video_link": 1}).
+			SetSort(bson.D{{Key: "link_checked_at", Value: 1}}).
+			SetLimit(sweepBatch))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find links to check: %w", err)
+	}
+	var targets []linkTarget
+	if err := cursor.All(ctx, &targets); err != nil {
+		return 0, fmt.Errorf("failed to parse links to check: %w", err)
+	}
+
+	jobs := make(chan linkTarget)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range jobs {
+				if err := Record(ctx, t.ID, c.Check(ctx, t.VideoLink)); err != nil {
+					fmt.Printf("Failed to record link check for %s: %v\n", t.ID.Hex(), err)
+				}
+			}
+		}()
+	}
+	checked := 0
+	for _, t := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- t
+		checked++
+	}
+	close(jobs)
+	wg.Wait()
+	return checked, nil
+}
+
+// Start runs sweeps in the background for the lifetime of the process.
+func Start(interval, recheckAfter time.Duration) {
+	go func() {
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+			checked, err := Default.Sweep(ctx, recheckAfter)
+			cancel()
+			if err != nil {
+				fmt.Printf("Link check sweep failed: %v\n", err)
+			} else if checked > 0 {
+				fmt.Printf("🔗 Checked %d saved links\n", checked)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
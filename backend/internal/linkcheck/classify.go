@@ -0,0 +1,82 @@
+package linkcheck
+
+import (
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/utils"
+	"net/http"
+	"strings"
+)
+
+// pageMarkers are phrases a platform serves with a 200 response when the
+// post itself is gone. They are matched case-insensitively against the
+// start of the page, most specific status first.
+var pageMarkers = map[string][]struct {
+	text   string
+	status string
+}{
+	utils.PlatformYouTube: {
+		{"not made this video available in your country", model.LinkStatusRegionBlocked},
+		{"this video is private", model.LinkStatusPrivate},
+		{`"status":"login_required"`, model.LinkStatusPrivate},
+		{"this video has been removed", model.LinkStatusRemoved},
+		{"this video isn't available anymore", model.LinkStatusRemoved},
+		{"video unavailable", model.LinkStatusRemoved},
+	},
+	utils.PlatformTikTok: {
+		{"not available in your region", model.LinkStatusRegionBlocked},
+		{"this video is private", model.LinkStatusPrivate},
+		{"this account is private", model.LinkStatusPrivate},
+		{"video currently unavailable", model.LinkStatusRemoved},
+		{"couldn't find this video", model.LinkStatusRemoved},
+	},
+	utils.PlatformInstagram: {
+		{"not available in your country", model.LinkStatusRegionBlocked},
+		{"this account is private", model.LinkStatusPrivate},
+		{"sorry, this page isn't available", model.LinkStatusRemoved},
+	},
+	utils.PlatformPinterest: {
+		{"sorry, we couldn't find that pin", model.LinkStatusRemoved},
+	},
+}
+
+// loginPaths are where platforms redirect anonymous visitors of posts they
+// only show to signed-in users.
+var loginPaths = []string{"/accounts/login", "/login"}
+
+// Classify turns a probe response into a link status. body is the start of
+// the page; finalURL is where redirects ended up.
+func Classify(platform string, statusCode int, finalURL string, body []byte) string {
+	switch {
+	case statusCode == http.StatusNotFound || statusCode == http.StatusGone:
+		return model.LinkStatusRemoved
+	case statusCode == http.StatusUnavailableForLegalReasons:
+		return model.LinkStatusRegionBlocked
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		// Social sites answer bots with 403 as often as private posts, so
+		// only trust it from plain web pages.
+		if platform == utils.PlatformWeb {
+			return model.LinkStatusPrivate
+		}
+		return model.LinkStatusUnknown
+	case statusCode < 200 || statusCode >= 300:
+		return model.LinkStatusUnknown
+	}
+
+	for _, path := range loginPaths {
+		if platform != utils.PlatformWeb && strings.Contains(strings.ToLower(finalURL), path) {
+			return model.LinkStatusPrivate
+		}
+	}
+	page := strings.ToLower(string(body))
+	for _, marker := range pageMarkers[platform] {
+		if strings.Contains(page, marker.text) {
+			return marker.status
+		}
+	}
+	return model.LinkStatusAvailable
+}
+
+// IsBroken reports whether a status means the user can no longer open the link.
+func IsBroken(status string) bool {
+	return status == model.LinkStatusRemoved || status == model.LinkStatusPrivate || status == model.LinkStatusRegionBlocked
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	ArchiveStatusQueued    = "queued"
+	ArchiveStatusRunning   = "running"
+	ArchiveStatusCompleted = "completed"
+	ArchiveStatusFailed    = "failed"
+
+	ArchiveKindMedia = "media" // the video/image itself
+	ArchiveKindPage  = "page"  // the page HTML, when no media could be found
+)
+
+// Archive is the offline copy of one saved item. The bytes live in the
+// blob store under their SHA-256, so identical files are stored once.
+type Archive struct {
+	ID          bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      string        `bson:"user_id" json:"user_id"`
+	UploadID    string        `bson:"upload_id" json:"upload_id"`
+	Status      string        `bson:"status" json:"status"`
+	Kind        string        `bson:"kind,omitempty" json:"kind,omitempty"`
+	SourceURL   string        `bson:"source_url,omitempty" json:"source_url,omitempty"` // what was actually downloaded
+	ContentType string        `bson:"content_type,omitempty" json:"content_type,omitempty"`
+	Size        int64         `bson:"size" json:"size"`
+	Hash        string        `bson:"hash,omitempty" json:"hash,omitempty"`
+	Message     string        `bson:"message,omitempty" json:"message,omitempty"`
+	CreatedAt   time.Time     `bson:"created_at" json:"created_at"`
+	CompletedAt *time.Time    `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
+
+// ArchiveUsage is the running total of a user's archived bytes.
+type ArchiveUsage struct {
+	UserID   string `bson:"_id" json:"user_id"`
+	Bytes    int64  `bson:"bytes" json:"bytes"`
+	Count    int    `bson:"count" json:"count"`
+	Reserved int64  `bson:"reserved,omitempty" json:"reserved"` // held for archives and snapshots in progress
+}
@@ -1,12 +1,69 @@
 package model
 
 import (
+	"strings"
+	"time"
+
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+const (
+	FolderKindStatic = "static"
+	FolderKindSmart  = "smart"
+)
+
+// Folder is a user's folder. Which items a static folder holds is recorded
+// only on the items, in LykedUploads.Folders; smart folders hold whatever
+// matches their filter. A shared static folder can hold other members'
+// items.
 type Folder struct {
-	ID      bson.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID  string             `bson:"user_id,omitempty" json:"user_id"`
-	Name    string             `bson:"name,omitempty" json:"name"`
-	PostIDs []string           `bson:"post_ids,omitempty" json:"post_ids"`
+	ID        bson.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID    string         `bson:"user_id,omitempty" json:"user_id"`
+	Name      string         `bson:"name,omitempty" json:"name"`
+	ParentID  string         `bson:"parent_id,omitempty" json:"parent_id,omitempty"` // empty for top-level folders
+	Path      string         `bson:"path,omitempty" json:"path"`                     // ancestor ids, "/a/b/"; "/" at the top
+	Kind      string         `bson:"kind,omitempty" json:"kind"`                     // empty means static
+	Filter    *SmartFilter   `bson:"filter,omitempty" json:"filter,omitempty"`
+	Pinned    bool           `bson:"pinned" json:"pinned"`
+	Members   []FolderMember `bson:"members,omitempty" json:"members,omitempty"` // who the folder is shared with
+	CreatedAt time.Time      `bson:"created_at,omitempty" json:"created_at"`
+	UpdatedAt *time.Time     `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	DeletedAt *time.Time     `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Set while the folder is in the trash
+	DeletedBy string         `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+}
+
+// SmartFilter defines a smart folder. Its contents are whatever currently
+// matches, so the folder updates itself as items are saved or changed.
+type SmartFilter struct {
+	Tags        []string   `bson:"tags,omitempty" json:"tags,omitempty"` // all must be present
+	ExcludeTags []string   `bson:"exclude_tags,omitempty" json:"exclude_tags,omitempty"`
+	Platforms   []string   `bson:"platforms,omitempty" json:"platforms,omitempty"` // any of
+	From        *time.Time `bson:"from,omitempty" json:"from,omitempty"`
+	To          *time.Time `bson:"to,omitempty" json:"to,omitempty"`
+	SavedWithin string     `bson:"saved_within,omitempty" json:"saved_within,omitempty"` // relative date, e.g. "this-month" or "30d"
+	Query       string     `bson:"query,omitempty" json:"query,omitempty"`               // search language
+}
+
+func (f *Folder) IsSmart() bool {
+	return f.Kind == FolderKindSmart
+}
+
+// AncestorPath is the folder's path, which folders created before nesting
+// don't store.
+func (f *Folder) AncestorPath() string {
+	if f.Path == "" {
+		return "/"
+	}
+	return f.Path
+}
+
+// ChildPath is the path of the folder's children; its descendants' paths
+// all start with it.
+func (f *Folder) ChildPath() string {
+	return f.AncestorPath() + f.ID.Hex() + "/"
+}
+
+// Depth is 0 for a top-level folder.
+func (f *Folder) Depth() int {
+	return strings.Count(f.AncestorPath(), "/") - 1
 }
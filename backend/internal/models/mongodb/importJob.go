@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	ImportStatusQueued    = "queued"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// ImportJob tracks one background import of a data-export file.
+type ImportJob struct {
+	ID         bson.ObjectID    `bson:"_id,omitempty" json:"id"`
+	UserID     string           `bson:"user_id" json:"user_id"`
+	Source     string           `bson:"source" json:"source"`
+	FileName   string           `bson:"file_name" json:"file_name"`
+	Status     string           `bson:"status" json:"status"`
+	Total      int              `bson:"total" json:"total"`
+	Processed  int              `bson:"processed" json:"processed"`
+	Imported   int              `bson:"imported" json:"imported"`
+	Duplicates int              `bson:"duplicates" json:"duplicates"`
+	Failed     int              `bson:"failed" json:"failed"`
+	Errors     []ImportRowError `bson:"errors" json:"errors"`
+	Message    string           `bson:"message,omitempty" json:"message,omitempty"`
+	CreatedAt  time.Time        `bson:"created_at" json:"created_at"`
+	FinishedAt *time.Time       `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+// ImportRowError explains why one row of an export was skipped.
+type ImportRowError struct {
+	Row     int    `bson:"row" json:"row"`
+	Link    string `bson:"link,omitempty" json:"link,omitempty"`
+	Message string `bson:"message" json:"message"`
+}
@@ -1,15 +1,56 @@
 package model
 
 import (
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Link availability as last seen by the link checker.
+const (
+	LinkStatusAvailable     = "available"
+	LinkStatusRemoved       = "removed"
+	LinkStatusPrivate       = "private"
+	LinkStatusRegionBlocked = "region_blocked"
+	LinkStatusUnknown       = "unknown"
+)
+
+// Watch status of an item. A missing status means unwatched.
+const (
+	WatchStatusUnwatched  = "unwatched"
+	WatchStatusInProgress = "in_progress"
+	WatchStatusWatched    = "watched"
+	WatchStatusArchived   = "archived"
+)
+
+const (
+	MinRating = 1
+	MaxRating = 5
 )
 
 type LykedUploads struct {
-	ID          primitive.ObjectID `bson:"_id" json:"id"`
-	UserID      string             `bson:"user_id" json:"user_id"` // Store User UUID as a string
-	Title       string             `bson:"title" json:"title"`
-	Description string             `bson:"description" json:"description"`
-	VideoLink   string             `bson:"video_link" json:"video_link"`
-	Folders     []string           `bson:"folders" json:"folders"`
-	Tags        []string           `bson:"tags" json:"tags"`
+	ID              bson.ObjectID     `bson:"_id" json:"id"`
+	UserID          string            `bson:"user_id" json:"user_id"` // Store User UUID as a string
+	Title           string            `bson:"title" json:"title"`
+	Description     string            `bson:"description" json:"description"`
+	VideoLink       string            `bson:"video_link" json:"video_link"`
+	Platform        string            `bson:"platform" json:"platform"` // Derived from VideoLink on save
+	Author          string            `bson:"author" json:"author"`
+	Folders         []string          `bson:"folders" json:"folders"`
+	Positions       map[string]string `bson:"positions,omitempty" json:"positions,omitempty"` // Rank within each static folder, keyed by folder id
+	Tags            []string          `bson:"tags" json:"tags"`
+	SavedAt         time.Time         `bson:"saved_at" json:"saved_at"`
+	Favorite        bool              `bson:"favorite,omitempty" json:"favorite"`
+	FavoritedAt     *time.Time        `bson:"favorited_at,omitempty" json:"favorited_at,omitempty"`
+	Rating          int               `bson:"rating,omitempty" json:"rating,omitempty"` // 1-5, 0 when unrated
+	RatedAt         *time.Time        `bson:"rated_at,omitempty" json:"rated_at,omitempty"`
+	Status          string            `bson:"status,omitempty" json:"status,omitempty"` // WatchStatus*, empty means unwatched
+	StatusChangedAt *time.Time        `bson:"status_changed_at,omitempty" json:"status_changed_at,omitempty"`
+	WatchedAt       *time.Time        `bson:"watched_at,omitempty" json:"watched_at,omitempty"` // When the item was last marked watched
+	DeletedAt       *time.Time        `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Set while the upload is in the trash
+	DeletedBy       string            `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+	LinkStatus      string            `bson:"link_status,omitempty" json:"link_status,omitempty"` // Empty until the link has been checked
+	LinkCheckedAt   *time.Time        `bson:"link_checked_at,omitempty" json:"link_checked_at,omitempty"`
+	Notes           []Note            `bson:"notes,omitempty" json:"notes,omitempty"`
+	Annotations     []Annotation      `bson:"annotations,omitempty" json:"annotations,omitempty"` // Kept sorted by At
 }
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Note is a markdown note on a saved item.
+type Note struct {
+	ID        string    `bson:"id" json:"id"`
+	Body      string    `bson:"body" json:"body"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// Annotation is a note pinned to a moment in a video, e.g. "2:31 - the
+// actual recipe starts".
+type Annotation struct {
+	ID        string    `bson:"id" json:"id"`
+	At        float64   `bson:"at" json:"at"` // seconds from the start
+	Text      string    `bson:"text" json:"text"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
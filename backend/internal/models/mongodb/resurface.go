@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	ResurfaceSnoozed   = "snoozed"
+	ResurfaceDismissed = "dismissed"
+)
+
+// ResurfaceFeedback keeps an item out of the resurfacing feed, until a
+// date when snoozed or for good when dismissed.
+type ResurfaceFeedback struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    string        `bson:"user_id" json:"user_id"`
+	UploadID  string        `bson:"upload_id" json:"upload_id"`
+	Action    string        `bson:"action" json:"action"`
+	Until     *time.Time    `bson:"until,omitempty" json:"until,omitempty"` // snoozes only
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+}
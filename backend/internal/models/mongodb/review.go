@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// ReviewSettings opts a user into review mode and picks what is reviewed:
+// items in any of the folders or carrying any of the tags.
+type ReviewSettings struct {
+	UserID    string    `bson:"_id" json:"user_id"`
+	Enabled   bool      `bson:"enabled" json:"enabled"`
+	Folders   []string  `bson:"folders" json:"folders"`
+	Tags      []string  `bson:"tags" json:"tags"`
+	NewPerDay int       `bson:"new_per_day" json:"new_per_day"` // new items introduced per day
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// ReviewCard is an item's SM-2 schedule.
+type ReviewCard struct {
+	UserID          string     `bson:"user_id" json:"user_id"`
+	UploadID        string     `bson:"upload_id" json:"upload_id"`
+	Ease            float64    `bson:"ease" json:"ease"`
+	Interval        int        `bson:"interval" json:"interval"` // days
+	Repetitions     int        `bson:"repetitions" json:"repetitions"`
+	Lapses          int        `bson:"lapses" json:"lapses"`
+	DueAt           time.Time  `bson:"due_at" json:"due_at"`
+	FirstReviewedAt *time.Time `bson:"first_reviewed_at,omitempty" json:"first_reviewed_at,omitempty"`
+	LastReviewedAt  *time.Time `bson:"last_reviewed_at,omitempty" json:"last_reviewed_at,omitempty"`
+	CreatedAt       time.Time  `bson:"created_at" json:"created_at"`
+}
+
+// ReviewLog records one graded review.
+type ReviewLog struct {
+	UserID     string    `bson:"user_id" json:"user_id"`
+	UploadID   string    `bson:"upload_id" json:"upload_id"`
+	Grade      int       `bson:"grade" json:"grade"`
+	Interval   int       `bson:"interval" json:"interval"` // the new interval
+	ReviewedAt time.Time `bson:"reviewed_at" json:"reviewed_at"`
+}
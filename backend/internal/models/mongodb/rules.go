@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// What a rule condition tests.
+const (
+	RuleCondDomain   = "domain"         // the link's host, optionally with a path prefix: "youtube.com/@babish"
+	RuleCondPlatform = "platform"       // the detected platform
+	RuleCondAuthor   = "author"         // the author, ignoring case and a leading @
+	RuleCondKeywords = "title_keywords" // every keyword appears in the title
+	RuleCondRegex    = "regex"          // a regular expression against one field
+)
+
+// How a rule combines its conditions.
+const (
+	RuleMatchAll = "all"
+	RuleMatchAny = "any"
+)
+
+// Fields a regex condition can test.
+const (
+	RuleFieldLink        = "link"
+	RuleFieldTitle       = "title"
+	RuleFieldDescription = "description"
+	RuleFieldAuthor      = "author"
+)
+
+type RuleCondition struct {
+	Type  string `bson:"type" json:"type"` // RuleCond*
+	Value string `bson:"value" json:"value"`
+	Field string `bson:"field,omitempty" json:"field,omitempty"` // RuleField*, regex only; defaults to the link
+}
+
+// RuleActions is what a rule does to the items it matches. Tags and
+// folders are only ever added. At save time the status is only set when
+// the item doesn't come with one.
+type RuleActions struct {
+	AddTags  []string `bson:"add_tags,omitempty" json:"add_tags,omitempty"`
+	FolderID string   `bson:"folder_id,omitempty" json:"folder_id,omitempty"` // a static folder
+	Status   string   `bson:"status,omitempty" json:"status,omitempty"`       // WatchStatus*
+}
+
+// Rule files and tags items as they are saved, and on demand for items
+// saved before. Rules run in the order they were created.
+type Rule struct {
+	ID         bson.ObjectID   `bson:"_id" json:"id"`
+	UserID     string          `bson:"user_id" json:"-"`
+	Name       string          `bson:"name" json:"name"`
+	Enabled    bool            `bson:"enabled" json:"enabled"`
+	Match      string          `bson:"match" json:"match"` // RuleMatch*
+	Conditions []RuleCondition `bson:"conditions" json:"conditions"`
+	Actions    RuleActions     `bson:"actions" json:"actions"`
+	CreatedAt  time.Time       `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time       `bson:"updated_at" json:"updated_at"`
+}
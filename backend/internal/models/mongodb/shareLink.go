@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// What a share link points at.
+const (
+	ShareKindFolder = "folder"
+	ShareKindUpload = "upload"
+)
+
+// ShareLink lets anyone holding its token read a folder or a single item
+// without an account, until it expires or is revoked.
+type ShareLink struct {
+	ID           bson.ObjectID `bson:"_id" json:"id"`
+	Token        string        `bson:"token" json:"token"`
+	UserID       string        `bson:"user_id" json:"user_id"` // who created it
+	Kind         string        `bson:"kind" json:"kind"`       // ShareKind*
+	TargetID     bson.ObjectID `bson:"target_id" json:"target_id"`
+	PasswordHash string        `bson:"password_hash,omitempty" json:"-"` // bcrypt, empty when open
+	Protected    bool          `bson:"protected" json:"protected"`
+	ExpiresAt    *time.Time    `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	RevokedAt    *time.Time    `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	Views        int64         `bson:"views" json:"views"`
+	LastViewedAt *time.Time    `bson:"last_viewed_at,omitempty" json:"last_viewed_at,omitempty"`
+	CreatedAt    time.Time     `bson:"created_at" json:"created_at"`
+}
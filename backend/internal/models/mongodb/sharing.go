@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Roles someone can have on a shared folder, weakest first. Viewers read,
+// contributors also file their own items, editors manage every item and
+// subfolder, and owners also manage the folder and who it is shared with.
+const (
+	RoleViewer      = "viewer"
+	RoleContributor = "contributor"
+	RoleEditor      = "editor"
+	RoleOwner       = "owner"
+)
+
+const (
+	InviteStatusPending  = "pending"
+	InviteStatusAccepted = "accepted"
+	InviteStatusDeclined = "declined"
+	InviteStatusRevoked  = "revoked"
+)
+
+// FolderMember is someone a folder is shared with. Their role covers the
+// folder's subfolders too.
+type FolderMember struct {
+	UserID   string    `bson:"user_id" json:"user_id"`
+	Username string    `bson:"username" json:"username"`
+	Role     string    `bson:"role" json:"role"`
+	AddedAt  time.Time `bson:"added_at" json:"added_at"`
+}
+
+// FolderInvite offers someone a role on a folder until they accept or
+// decline it.
+type FolderInvite struct {
+	ID          bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	FolderID    bson.ObjectID `bson:"folder_id" json:"folder_id"`
+	FolderName  string        `bson:"folder_name" json:"folder_name"`
+	InvitedBy   string        `bson:"invited_by" json:"invited_by"`
+	UserID      string        `bson:"user_id" json:"user_id"` // the invitee
+	Username    string        `bson:"username" json:"username"`
+	Role        string        `bson:"role" json:"role"`
+	Status      string        `bson:"status" json:"status"`
+	CreatedAt   time.Time     `bson:"created_at" json:"created_at"`
+	RespondedAt *time.Time    `bson:"responded_at,omitempty" json:"responded_at,omitempty"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Snapshot is a WARC capture of a saved item's page and everything it
+// loads. Unlike Archive, an item can have many snapshots over time. The
+// WARC file lives in the archive blob store under its SHA-256.
+type Snapshot struct {
+	ID          bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      string        `bson:"user_id" json:"user_id"`
+	UploadID    string        `bson:"upload_id" json:"upload_id"`
+	Status      string        `bson:"status" json:"status"` // ArchiveStatus* values
+	URL         string        `bson:"url" json:"url"`       // the page after redirects
+	Size        int64         `bson:"size" json:"size"`     // of the .warc.gz file
+	Hash        string        `bson:"hash,omitempty" json:"hash,omitempty"`
+	Resources   int           `bson:"resources" json:"resources"`
+	Failed      []string      `bson:"failed,omitempty" json:"failed,omitempty"`
+	Message     string        `bson:"message,omitempty" json:"message,omitempty"`
+	CreatedAt   time.Time     `bson:"created_at" json:"created_at"`
+	CompletedAt *time.Time    `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
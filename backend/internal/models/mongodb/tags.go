@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// TagMeta is how a user's tag is displayed. Tags themselves live on the
+// items; a tag can have metadata before or after any item carries it.
+type TagMeta struct {
+	UserID    string    `bson:"user_id" json:"-"`
+	Tag       string    `bson:"tag" json:"tag"`
+	Color     string    `bson:"color,omitempty" json:"color,omitempty"` // #rrggbb
+	Emoji     string    `bson:"emoji,omitempty" json:"emoji,omitempty"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// TagAlias maps a synonym onto the tag it stands for. Tags are resolved
+// through aliases as they are saved and filtered by, so items only ever
+// carry the canonical tag.
+type TagAlias struct {
+	UserID    string    `bson:"user_id" json:"-"`
+	Alias     string    `bson:"alias" json:"alias"`
+	Tag       string    `bson:"tag" json:"tag"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// TagFeedback counts how often a suggested tag was accepted or rejected in
+// one context: anywhere, or for links from one domain, author or platform.
+type TagFeedback struct {
+	UserID    string    `bson:"user_id" json:"-"`
+	Tag       string    `bson:"tag" json:"tag"`
+	Context   string    `bson:"context" json:"context"` // "", "domain:<host>", "author:<name>" or "platform:<name>"
+	Accepted  int       `bson:"accepted" json:"accepted"`
+	Rejected  int       `bson:"rejected" json:"rejected"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,199 @@
+// Package notes manages the markdown notes and timestamped video
+// annotations kept on saved items.
+package notes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	model "lyked-backend/internal/models/mongodb"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	MaxNotes            = 100
+	MaxNoteLength       = 20000
+	MaxAnnotations      = 500
+	MaxAnnotationLength = 1000
+)
+
+var (
+	ErrUploadNotFound = errors.New("upload not found")
+	ErrNoteNotFound   = errors.New("note not found")
+	ErrTooMany        = errors.New("too many notes on this item")
+	ErrEmpty          = errors.New("text is required")
+	ErrTooLong        = errors.New("text is too long")
+)
+
+// AddNote appends a note and returns the updated upload with it.
+func AddNote(ctx context.Context, uploads *mongo.Collection, userID string, uploadID bson.ObjectID, body string, now time.Time) (*model.LykedUploads, *model.Note, error) {
+	body, err := clean(body, MaxNoteLength)
+	if err != nil {
+		return nil, nil, err
+	}
+	note := model.Note{ID: bson.NewObjectID().Hex(), Body: body, CreatedAt: now, UpdatedAt: now}
+	filter := owned(userID, uploadID)
+	filter[fmt.Sprintf("notes.%d", MaxNotes-1)] = bson.M{"$exists": false}
+
+	upload, err := update(ctx, uploads, userID, uploadID, filter, bson.M{"$push": bson.M{"notes": note}}, ErrTooMany)
+	if err != nil {
+		return nil, nil, err
+	}
+	return upload, &note, nil
+}
+
+// UpdateNote replaces a note's body.
+func UpdateNote(ctx context.Context, uploads *mongo.Collection, userID string, uploadID bson.ObjectID, noteID, body string, now time.Time) (*model.LykedUploads, *model.Note, error) {
+	body, err := clean(body, MaxNoteLength)
+	if err != nil {
+		return nil, nil, err
+	}
+	filter := owned(userID, uploadID)
+	filter["notes.id"] = noteID
+	upload, err := update(ctx, uploads, userID, uploadID, filter, bson.M{"$set": bson.M{
+		"notes.$.body":       body,
+		"notes.$.updated_at": now,
+	}}, ErrNoteNotFound)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range upload.Notes {
+		if upload.Notes[i].ID == noteID {
+			return upload, &upload.Notes[i], nil
+		}
+	}
+	return nil, nil, ErrNoteNotFound
+}
+
+// DeleteNote removes a note.
+func DeleteNote(ctx context.Context, uploads *mongo.Collection, userID string, uploadID bson.ObjectID, noteID string) (*model.LykedUploads, error) {
+	filter := owned(userID, uploadID)
+	filter["notes.id"] = noteID
+	return update(ctx, uploads, userID, uploadID, filter, bson.M{"$pull": bson.M{"notes": bson.M{"id": noteID}}}, ErrNoteNotFound)
+}
+
+// AddAnnotation inserts an annotation, keeping the list in video order.
+func AddAnnotation(ctx context.Context, uploads *mongo.Collection, userID string, uploadID bson.ObjectID, at float64, text string, now time.Time) (*model.LykedUploads, *model.Annotation, error) {
+	text, err := clean(text, MaxAnnotationLength)
+	if err != nil {
+		return nil, nil, err
+	}
+	if at < 0 {
+		return nil, nil, ErrInvalidTimestamp
+	}
+	annotation := model.Annotation{ID: bson.NewObjectID().Hex(), At: at, Text: text, CreatedAt: now, UpdatedAt: now}
+	filter := owned(userID, uploadID)
+	filter[fmt.Sprintf("annotations.%d", MaxAnnotations-1)] = bson.M{"$exists": false}
+
+	upload, err := update(ctx, uploads, userID, uploadID, filter, bson.M{"$push": bson.M{"annotations": bson.M{
+		"$each": bson.A{annotation},
+		"$sort": bson.M{"at": 1},
+	}}}, ErrTooMany)
+	if err != nil {
+		return nil, nil, err
+	}
+	return upload, &annotation, nil
+}
+
+// UpdateAnnotation changes an annotation's time and/or text; nil leaves
+// that part as it is.
+func UpdateAnnotation(ctx context.Context, uploads *mongo.Collection, userID string, uploadID bson.ObjectID, annotationID string, at *float64, text *string, now time.Time) (*model.LykedUploads, *model.Annotation, error) {
+	set := bson.M{"annotations.$.updated_at": now}
+	if text != nil {
+		cleaned, err := clean(*text, MaxAnnotationLength)
+		if err != nil {
+			return nil, nil, err
+		}
+		set["annotations.$.text"] = cleaned
+	}
+	if at != nil {
+		if *at < 0 {
+			return nil, nil, ErrInvalidTimestamp
+		}
+		set["annotations.$.at"] = *at
+	}
+	filter := owned(userID, uploadID)
+	filter["annotations.id"] = annotationID
+	upload, err := update(ctx, uploads, userID, uploadID, filter, bson.M{"$set": set}, ErrNoteNotFound)
+	if err != nil {
+		return nil, nil, err
+	}
+	if at != nil {
+		// A $push of nothing with $sort re-sorts the array in place.
+		upload, err = update(ctx, uploads, userID, uploadID, owned(userID, uploadID), bson.M{"$push": bson.M{"annotations": bson.M{
+			"$each": bson.A{},
+			"$sort": bson.M{"at": 1},
+		}}}, ErrUploadNotFound)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	for i := range upload.Annotations {
+		if upload.Annotations[i].ID == annotationID {
+			return upload, &upload.Annotations[i], nil
+		}
+	}
+	return nil, nil, ErrNoteNotFound
+}
+
+// DeleteAnnotation removes an annotation.
+func DeleteAnnotation(ctx context.Context, uploads *mongo.Collection, userID string, uploadID bson.ObjectID, annotationID string) (*model.LykedUploads, error) {
+	filter := owned(userID, uploadID)
+	filter["annotations.id"] = annotationID
+	return update(ctx, uploads, userID, uploadID, filter, bson.M{"$pull": bson.M{"annotations": bson.M{"id": annotationID}}}, ErrNoteNotFound)
+}
+
+// Text is everything written on an upload, for the search index.
+func Text(upload model.LykedUploads) string {
+	parts := make([]string, 0, len(upload.Notes)+len(upload.Annotations))
+	for _, n := range upload.Notes {
+		parts = append(parts, n.Body)
+	}
+	for _, a := range upload.Annotations {
+		parts = append(parts, a.Text)
+	}
+	return strings.Join(parts, "\n")
+}
+
+func owned(userID string, uploadID bson.ObjectID) bson.M {
+	return bson.M{"_id": uploadID, "user_id": userID, "deleted_at": nil}
+}
+
+// update applies the change and returns the upload as it is afterwards.
+// When filter matches nothing, the upload is looked up again to tell a
+// missing upload from the more specific miss.
+func update(ctx context.Context, uploads *mongo.Collection, userID string, uploadID bson.ObjectID, filter, change bson.M, miss error) (*model.LykedUploads, error) {
+	var upload model.LykedUploads
+	err := uploads.FindOneAndUpdate(ctx, filter, change, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&upload)
+	if err == nil {
+		return &upload, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to update notes: %w", err)
+	}
+	n, err := uploads.CountDocuments(ctx, owned(userID, uploadID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch upload: %w", err)
+	}
+	if n == 0 {
+		return nil, ErrUploadNotFound
+	}
+	return nil, miss
+}
+
+func clean(text string, limit int) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", ErrEmpty
+	}
+	if utf8.RuneCountInString(text) > limit {
+		return "", ErrTooLong
+	}
+	return text, nil
+}
@@ -0,0 +1,65 @@
+package notes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidTimestamp = errors.New(`invalid timestamp, expected seconds or "m:ss" / "h:mm:ss"`)
+
+// Timestamp is a position in a video. In JSON it is either a number of
+// seconds or a clock string such as "2:31" or "1:02:31".
+type Timestamp float64
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		seconds, err := ParseTimestamp(s)
+		if err != nil {
+			return err
+		}
+		*t = Timestamp(seconds)
+		return nil
+	}
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err != nil || seconds < 0 {
+		return ErrInvalidTimestamp
+	}
+	*t = Timestamp(seconds)
+	return nil
+}
+
+// ParseTimestamp reads "151", "151.5", "2:31" or "1:02:31" as seconds.
+func ParseTimestamp(s string) (float64, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) > 3 {
+		return 0, ErrInvalidTimestamp
+	}
+	var seconds float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+			return 0, ErrInvalidTimestamp
+		}
+		// Only the first part may run past 59, and only the last may have a fraction.
+		if i > 0 && v >= 60 || i < len(parts)-1 && v != math.Trunc(v) {
+			return 0, ErrInvalidTimestamp
+		}
+		seconds = seconds*60 + v
+	}
+	return seconds, nil
+}
+
+// FormatTimestamp writes seconds the way video players show them.
+func FormatTimestamp(seconds float64) string {
+	total := int(seconds)
+	h, m, s := total/3600, total/60%60, total%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
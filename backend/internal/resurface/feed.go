@@ -0,0 +1,180 @@
+package resurface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	model "lyked-backend/internal/models/mongodb"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var ErrUploadNotFound = errors.New("upload not found")
+
+// Item is a feed entry with the upload it points at.
+type Item struct {
+	Pick
+	Upload model.LykedUploads `json:"upload"`
+}
+
+// Feed builds the user's feed for day. Archived items and items that were
+// dismissed or are snoozed past day are left out.
+func Feed(ctx context.Context, uploads, feedback *mongo.Collection, userID string, day time.Time, seed uint64, size int) ([]Item, error) {
+	excluded, err := excludedIDs(ctx, feedback, userID, day)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := loadCandidates(ctx, uploads, userID, excluded)
+	if err != nil {
+		return nil, err
+	}
+	picks := Choose(candidates, day, seed, size)
+	if len(picks) == 0 {
+		return []Item{}, nil
+	}
+
+	ids := make([]bson.ObjectID, 0, len(picks))
+	for _, p := range picks {
+		id, _ := bson.ObjectIDFromHex(p.ID)
+		ids = append(ids, id)
+	}
+	cursor, err := uploads.Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch uploads: %w", err)
+	}
+	var found []model.LykedUploads
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, fmt.Errorf("failed to parse uploads: %w", err)
+	}
+	byID := make(map[string]model.LykedUploads, len(found))
+	for _, u := range found {
+		byID[u.ID.Hex()] = u
+	}
+
+	items := make([]Item, 0, len(picks))
+	for _, p := range picks {
+		if u, ok := byID[p.ID]; ok {
+			items = append(items, Item{Pick: p, Upload: u})
+		}
+	}
+	return items, nil
+}
+
+func loadCandidates(ctx context.Context, uploads *mongo.Collection, userID string, excluded []bson.ObjectID) ([]Candidate, error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"deleted_at": nil,
+		"status":     bson.M{"$ne": model.WatchStatusArchived},
+	}
+	if len(excluded) > 0 {
+		filter["_id"] = bson.M{"$nin": excluded}
+	}
+	cursor, err := uploads.Find(ctx, filter, options.Find().SetProjection(bson.M{
+		"saved_at": 1, "rating": 1, "favorite": 1,
+		"favorited_at": 1, "rated_at": 1, "status_changed_at": 1, "watched_at": 1,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch uploads: %w", err)
+	}
+	var docs []model.LykedUploads
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to parse uploads: %w", err)
+	}
+
+	candidates := make([]Candidate, len(docs))
+	for i, u := range docs {
+		touched := u.SavedAt
+		for _, t := range []*time.Time{u.FavoritedAt, u.RatedAt, u.StatusChangedAt, u.WatchedAt} {
+			if t != nil && t.After(touched) {
+				touched = *t
+			}
+		}
+		candidates[i] = Candidate{
+			ID:          u.ID.Hex(),
+			SavedAt:     u.SavedAt,
+			LastTouched: touched,
+			Rating:      u.Rating,
+			Favorite:    u.Favorite,
+		}
+	}
+	return candidates, nil
+}
+
+func excludedIDs(ctx context.Context, feedback *mongo.Collection, userID string, day time.Time) ([]bson.ObjectID, error) {
+	cursor, err := feedback.Find(ctx, bson.M{
+		"user_id": userID,
+		"$or": bson.A{
+			bson.M{"action": model.ResurfaceDismissed},
+			bson.M{"action": model.ResurfaceSnoozed, "until": bson.M{"$gt": day}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch resurfacing feedback: %w", err)
+	}
+	var entries []model.ResurfaceFeedback
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse resurfacing feedback: %w", err)
+	}
+	ids := make([]bson.ObjectID, 0, len(entries))
+	for _, e := range entries {
+		if id, err := bson.ObjectIDFromHex(e.UploadID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Snooze hides an item from the feed until the given time; Dismiss hides
+// it for good. Either replaces earlier feedback on the item.
+func Snooze(ctx context.Context, uploads, feedback *mongo.Collection, userID string, uploadID bson.ObjectID, until, now time.Time) (*model.ResurfaceFeedback, error) {
+	return record(ctx, uploads, feedback, userID, uploadID, model.ResurfaceSnoozed, &until, now)
+}
+
+func Dismiss(ctx context.Context, uploads, feedback *mongo.Collection, userID string, uploadID bson.ObjectID, now time.Time) (*model.ResurfaceFeedback, error) {
+	return record(ctx, uploads, feedback, userID, uploadID, model.ResurfaceDismissed, nil, now)
+}
+
+// Clear removes any feedback, letting the item resurface again. It
+// reports whether there was any.
+func Clear(ctx context.Context, feedback *mongo.Collection, userID string, uploadID bson.ObjectID) (bool, error) {
+	res, err := feedback.DeleteOne(ctx, bson.M{"user_id": userID, "upload_id": uploadID.Hex()})
+	if err != nil {
+		return false, fmt.Errorf("failed to clear resurfacing feedback: %w", err)
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func record(ctx context.Context, uploads, feedback *mongo.Collection, userID string, uploadID bson.ObjectID, action string, until *time.Time, now time.Time) (*model.ResurfaceFeedback, error) {
+	n, err := uploads.CountDocuments(ctx, bson.M{"_id": uploadID, "user_id": userID, "deleted_at": nil})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch upload: %w", err)
+	}
+	if n == 0 {
+		return nil, ErrUploadNotFound
+	}
+
+	entry := model.ResurfaceFeedback{
+		UserID:    userID,
+		UploadID:  uploadID.Hex(),
+		Action:    action,
+		Until:     until,
+		CreatedAt: now,
+	}
+	filter := bson.M{"user_id": userID, "upload_id": entry.UploadID}
+	update := bson.M{"$set": bson.M{"action": entry.Action, "until": entry.Until, "created_at": entry.CreatedAt}}
+	if until == nil {
+		update = bson.M{
+			"$set":   bson.M{"action": entry.Action, "created_at": entry.CreatedAt},
+			"$unset": bson.M{"until": ""},
+		}
+	}
+	err = feedback.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save resurfacing feedback: %w", err)
+	}
+	return &entry, nil
+}
@@ -0,0 +1,145 @@
+// Package resurface brings old saves back: a small daily feed per user,
+// picked by rules such as "saved on this day" and "untouched for a while".
+package resurface
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+// Reasons an item was picked.
+const (
+	ReasonOnThisDay = "on_this_day"
+	ReasonUntouched = "untouched"
+	ReasonRandom    = "random"
+)
+
+const (
+	DefaultSize    = 10
+	MaxSize        = 50
+	UntouchedAfter = 90 * 24 * time.Hour
+	maxOnThisDay   = 3
+)
+
+// Candidate is what the rules need to know about an item.
+type Candidate struct {
+	ID          string
+	SavedAt     time.Time
+	LastTouched time.Time // latest of saving, rating, favoriting or a status change
+	Rating      int
+	Favorite    bool
+}
+
+// Pick is one item of the feed.
+type Pick struct {
+	ID       string `json:"id"`
+	Reason   string `json:"reason"`
+	YearsAgo int    `json:"years_ago,omitempty"` // on_this_day only
+}
+
+// Seed derives the feed's random seed from the user and day, so a feed
+// stays the same all day and changes the next.
+func Seed(userID string, day time.Time) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(userID))
+	h.Write([]byte(day.Format("2006-01-02")))
+	return h.Sum64()
+}
+
+// Choose fills a feed of up to size items: first saves from this day in
+// earlier years, then items untouched for UntouchedAfter, then the rest,
+// each stage drawn at random with higher-rated and favorite items more
+// likely. The same candidates, day and seed always give the same feed.
+func Choose(candidates []Candidate, day time.Time, seed uint64, size int) []Pick {
+	sorted := append([]Candidate(nil), candidates...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+
+	picks := []Pick{}
+	taken := map[string]bool{}
+	take := func(pool []Candidate, n int, reason string) {
+		for _, c := range weightedSample(pool, n, rng) {
+			taken[c.ID] = true
+			pick := Pick{ID: c.ID, Reason: reason}
+			if reason == ReasonOnThisDay {
+				pick.YearsAgo = day.Year() - c.SavedAt.Year()
+			}
+			picks = append(picks, pick)
+		}
+	}
+
+	var onThisDay, untouched, rest []Candidate
+	for _, c := range sorted {
+		switch {
+		case isOnThisDay(c.SavedAt, day):
+			onThisDay = append(onThisDay, c)
+		case day.Sub(c.LastTouched) >= UntouchedAfter:
+			untouched = append(untouched, c)
+		default:
+			rest = append(rest, c)
+		}
+	}
+	take(onThisDay, min(maxOnThisDay, size), ReasonOnThisDay)
+	// Untouched items get most of what is left; recent ones only fill the gap.
+	take(untouched, size-len(picks), ReasonUntouched)
+
+	var remaining []Candidate
+	for _, c := range append(onThisDay, rest...) {
+		if !taken[c.ID] {
+			remaining = append(remaining, c)
+		}
+	}
+	take(remaining, size-len(picks), ReasonRandom)
+	return picks
+}
+
+// isOnThisDay reports whether saved falls on day's month and day in an
+// earlier year. Saves from 29 February show up on the 28th in other years.
+func isOnThisDay(saved, day time.Time) bool {
+	saved, day = saved.UTC(), day.UTC()
+	if saved.Year() >= day.Year() {
+		return false
+	}
+	if saved.Month() == day.Month() && saved.Day() == day.Day() {
+		return true
+	}
+	leap := time.Date(day.Year(), 2, 29, 0, 0, 0, 0, time.UTC).Month() == time.February
+	return !leap && saved.Month() == time.February && saved.Day() == 29 &&
+		day.Month() == time.February && day.Day() == 28
+}
+
+// weight makes a 5-star favorite about twelve times as likely as an
+// unrated item.
+func weight(c Candidate) float64 {
+	w := 1 + float64(c.Rating)
+	if c.Favorite {
+		w *= 2
+	}
+	return w
+}
+
+// weightedSample draws n items without replacement, each with probability
+// proportional to its weight (Efraimidis-Spirakis: keep the n largest
+// u^(1/w)).
+func weightedSample(pool []Candidate, n int, rng *rand.Rand) []Candidate {
+	if n <= 0 || len(pool) == 0 {
+		return nil
+	}
+	type keyed struct {
+		c   Candidate
+		key float64
+	}
+	keys := make([]keyed, len(pool))
+	for i, c := range pool {
+		keys[i] = keyed{c, math.Pow(rng.Float64(), 1/weight(c))}
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].key > keys[j].key })
+	out := make([]Candidate, 0, min(n, len(keys)))
+	for _, k := range keys[:min(n, len(keys))] {
+		out = append(out, k.c)
+	}
+	return out
+}
@@ -0,0 +1,190 @@
+package resurface
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+var today = time.Date(2026, 6, 15, 9, 0, 0, 0, time.UTC)
+
+// library has three saves from this day in earlier years, one from this
+// day this year, old untouched items and recently touched ones.
+func library() []Candidate {
+	var cs []Candidate
+	add := func(id string, saved, touched time.Time) {
+		cs = append(cs, Candidate{ID: id, SavedAt: saved, LastTouched: touched})
+	}
+	for i, years := range []int{1, 2, 3, 4} {
+		saved := today.AddDate(-years, 0, 0)
+		add(fmt.Sprintf("otd-%d", i), saved, today.AddDate(0, 0, -1)) // touched recently
+	}
+	add("same-year", today.AddDate(0, -1, 0), today.AddDate(0, -1, 0))
+	for i := range 5 {
+		saved := today.AddDate(-1, -1, -i)
+		add(fmt.Sprintf("old-%d", i), saved, saved)
+	}
+	for i := range 5 {
+		add(fmt.Sprintf("recent-%d", i), today.AddDate(0, 0, -10-i), today.AddDate(0, 0, -1))
+	}
+	return cs
+}
+
+func ids(picks []Pick) []string {
+	out := make([]string, len(picks))
+	for i, p := range picks {
+		out[i] = p.ID
+	}
+	return out
+}
+
+func TestSeed(t *testing.T) {
+	morning := Seed("u1", time.Date(2026, 6, 15, 0, 0, 0, 0, time.UTC))
+	if got := Seed("u1", time.Date(2026, 6, 15, 0, 0, 0, 0, time.UTC)); got != morning {
+		t.Errorf("Seed changed within the day: %d, %d", morning, got)
+	}
+	if Seed("u1", today.AddDate(0, 0, 1)) == morning {
+		t.Error("Seed should change with the day")
+	}
+	if Seed("u2", today) == Seed("u1", today) {
+		t.Error("Seed should differ between users")
+	}
+}
+
+func TestChooseIsStable(t *testing.T) {
+	cs := library()
+	seed := Seed("u1", today)
+	first := Choose(cs, today, seed, 8)
+
+	// The order candidates come back from the database in doesn't matter.
+	shuffled := slices.Clone(cs)
+	rand.New(rand.NewPCG(1, 2)).Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	for range 5 {
+		if got := Choose(shuffled, today, seed, 8); !slices.Equal(got, first) {
+			t.Fatalf("Choose gave %v, then %v", ids(first), ids(got))
+		}
+	}
+
+	differs := false
+	for s := range uint64(20) {
+		if !slices.Equal(ids(Choose(cs, today, s, 8)), ids(first)) {
+			differs = true
+			break
+		}
+	}
+	if !differs {
+		t.Error("every seed gave the same feed")
+	}
+}
+
+func TestChooseRules(t *testing.T) {
+	cs := library()
+	for seed := range uint64(50) {
+		picks := Choose(cs, today, seed, 8)
+		if len(picks) != 8 {
+			t.Fatalf("seed %d: got %d picks, want 8", seed, len(picks))
+		}
+		seen := map[string]bool{}
+		counts := map[string]int{}
+		for i, p := range picks {
+			if seen[p.ID] {
+				t.Errorf("seed %d: %s picked twice", seed, p.ID)
+			}
+			seen[p.ID] = true
+			counts[p.Reason]++
+
+			switch p.Reason {
+			case ReasonOnThisDay:
+				if p.ID[:4] != "otd-" || p.YearsAgo < 1 {
+					t.Errorf("seed %d: %+v is not from this day in an earlier year", seed, p)
+				}
+			case ReasonUntouched:
+				if p.ID[:4] != "old-" {
+					t.Errorf("seed %d: %+v was touched recently", seed, p)
+				}
+			case ReasonRandom:
+				if p.ID[:4] == "old-" {
+					t.Errorf("seed %d: untouched %s should not be a random pick", seed, p.ID)
+				}
+				if p.YearsAgo != 0 {
+					t.Errorf("seed %d: %+v should not have years_ago", seed, p)
+				}
+			}
+			// Stages come in order: on this day, untouched, the rest.
+			if i > 0 && stage(picks[i-1].Reason) > stage(p.Reason) {
+				t.Errorf("seed %d: %v are out of order", seed, picks)
+			}
+		}
+		if counts[ReasonOnThisDay] != maxOnThisDay {
+			t.Errorf("seed %d: %d on this day picks, want %d", seed, counts[ReasonOnThisDay], maxOnThisDay)
+		}
+		// Every untouched item beats recent ones to the remaining slots.
+		if counts[ReasonUntouched] != 5 {
+			t.Errorf("seed %d: %d untouched picks, want 5", seed, counts[ReasonUntouched])
+		}
+	}
+}
+
+func stage(reason string) int {
+	return map[string]int{ReasonOnThisDay: 0, ReasonUntouched: 1, ReasonRandom: 2}[reason]
+}
+
+func TestChooseSmallFeeds(t *testing.T) {
+	cs := library()
+	if got := Choose(cs, today, 1, 2); len(got) != 2 || got[0].Reason != ReasonOnThisDay || got[1].Reason != ReasonOnThisDay {
+		t.Errorf("a feed of 2 should be all on this day, got %+v", got)
+	}
+	if got := Choose(cs, today, 1, 0); len(got) != 0 {
+		t.Errorf("a feed of 0 got %+v", got)
+	}
+	if got := Choose(nil, today, 1, 10); got == nil || len(got) != 0 {
+		t.Errorf("no candidates should give an empty feed, got %#v", got)
+	}
+	// Asking for more than there is returns everything once.
+	if got := Choose(cs, today, 1, 100); len(got) != len(cs) {
+		t.Errorf("got %d picks, want all %d candidates", len(got), len(cs))
+	}
+}
+
+func TestChooseWeighsRatingsAndFavorites(t *testing.T) {
+	old := today.AddDate(-1, -1, 0)
+	cs := []Candidate{
+		{ID: "plain", SavedAt: old, LastTouched: old},
+		{ID: "loved", SavedAt: old, LastTouched: old, Rating: 5, Favorite: true},
+	}
+	loved := 0
+	for seed := range uint64(500) {
+		if Choose(cs, today, seed, 1)[0].ID == "loved" {
+			loved++
+		}
+	}
+	// A 5-star favorite weighs 12 against 1, so it should win about 92%
+	// of the draws.
+	if loved < 420 || loved > 490 {
+		t.Errorf("the 5-star favorite won %d of 500 draws, want about 460", loved)
+	}
+}
+
+func TestIsOnThisDay(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 12, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		saved, day time.Time
+		want       bool
+	}{
+		{date(2024, 6, 15), date(2026, 6, 15), true},
+		{date(2026, 6, 15), date(2026, 6, 15), false}, // same year
+		{date(2027, 6, 15), date(2026, 6, 15), false},
+		{date(2024, 6, 14), date(2026, 6, 15), false},
+		{date(2024, 2, 29), date(2025, 2, 28), true}, // leap day in a common year
+		{date(2024, 2, 29), date(2028, 2, 28), false},
+		{date(2024, 2, 29), date(2028, 2, 29), true},
+		{time.Date(2024, 6, 15, 23, 30, 0, 0, time.FixedZone("", -3*3600)), date(2026, 6, 16), true}, // compared in UTC
+	}
+	for _, tt := range tests {
+		if got := isOnThisDay(tt.saved, tt.day); got != tt.want {
+			t.Errorf("isOnThisDay(%s, %s) = %v, want %v", tt.saved, tt.day, got, tt.want)
+		}
+	}
+}
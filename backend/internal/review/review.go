@@ -0,0 +1,291 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DefaultNewPerDay = 10
+	MaxNewPerDay     = 100
+	maxDue           = 200
+)
+
+var (
+	ErrNotEnabled     = errors.New("review mode is not enabled")
+	ErrNotInReview    = errors.New("item is not in review")
+	ErrFolderNotFound = errors.New("folder not found")
+)
+
+// Store bundles the collections review mode works with.
+type Store struct {
+	Uploads  *mongo.Collection
+	Folders  *mongo.Collection
+	Settings *mongo.Collection
+	Cards    *mongo.Collection
+	Log      *mongo.Collection
+}
+
+// DueItem is an item to review now, with its schedule.
+type DueItem struct {
+	Card   model.ReviewCard   `json:"card"`
+	New    bool               `json:"new"`
+	Upload model.LykedUploads `json:"upload"`
+}
+
+// GetSettings returns the user's settings; review mode is off by default.
+func (s *Store) GetSettings(ctx context.Context, userID string) (*model.ReviewSettings, error) {
+	settings := &model.ReviewSettings{UserID: userID, Folders: []string{}, Tags: []string{}, NewPerDay: DefaultNewPerDay}
+	err := s.Settings.FindOne(ctx, bson.M{"_id": userID}).Decode(settings)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to fetch review settings: %w", err)
+	}
+	return settings, nil
+}
+
+// SaveSettings checks that the folders are the user's and stores the settings.
+func (s *Store) SaveSettings(ctx context.Context, settings *model.ReviewSettings) error {
+	for _, raw := range settings.Folders {
+		id, err := bson.ObjectIDFromHex(raw)
+		if err != nil {
+			return ErrFolderNotFound
+		}
+		n, err := s.Folders.CountDocuments(ctx, bson.M{"_id": id, "user_id": settings.UserID, "deleted_at": nil})
+		if err != nil {
+			return fmt.Errorf("failed to fetch folder: %w", err)
+		}
+		if n == 0 {
+			return ErrFolderNotFound
+		}
+	}
+	_, err := s.Settings.ReplaceOne(ctx, bson.M{"_id": settings.UserID}, settings, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save review settings: %w", err)
+	}
+	return nil
+}
+
+// scopeFilter matches the user's uploads that are in review: in one of
+// the chosen folders (smart folders by their filter) or carrying one of
+// the chosen tags. It returns nil when nothing is chosen.
+func (s *Store) scopeFilter(ctx context.Context, settings *model.ReviewSettings, now time.Time) (bson.M, error) {
+	or := bson.A{}
+	if len(settings.Tags) > 0 {
+		or = append(or, bson.M{"tags": bson.M{"$in": library.TagPatterns(settings.Tags)}})
+	}
+	for _, raw := range settings.Folders {
+		id, err := bson.ObjectIDFromHex(raw)
+		if err != nil {
+			continue
+		}
+		var folder model.Folder
+		err = s.Folders.FindOne(ctx, bson.M{"_id": id, "user_id": settings.UserID, "deleted_at": nil}).Decode(&folder)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch folder: %w", err)
+		}
+		if !folder.IsSmart() {
+			or = append(or, bson.M{"folders": raw})
+			continue
+		}
+		filter, err := library.SmartFolderFilter(folder.Filter, now)
+		if err != nil {
+			continue
+		}
+		or = append(or, filter)
+	}
+	if len(or) == 0 {
+		return nil, nil
+	}
+	return bson.M{"user_id": settings.UserID, "deleted_at": nil, "$or": or}, nil
+}
+
+// scopedIDs returns the hex ids of the uploads in review.
+func (s *Store) scopedIDs(ctx context.Context, userID string, now time.Time) ([]string, error) {
+	settings, err := s.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled {
+		return nil, ErrNotEnabled
+	}
+	filter, err := s.scopeFilter(ctx, settings, now)
+	if err != nil || filter == nil {
+		return []string{}, err
+	}
+	cursor, err := s.Uploads.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch review items: %w", err)
+	}
+	var docs []struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to parse review items: %w", err)
+	}
+	ids := make([]string, len(docs))
+	for i, d := range docs {
+		ids[i] = d.ID.Hex()
+	}
+	return ids, nil
+}
+
+// Due returns what to review today: every scheduled item whose day has
+// come, plus new items up to the daily allowance. Items that joined the
+// review scope get their card here.
+func (s *Store) Due(ctx context.Context, userID string, now time.Time) ([]DueItem, error) {
+	settings, err := s.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.scopedIDs(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureCards(ctx, userID, ids, now); err != nil {
+		return nil, err
+	}
+
+	today := startOfDay(now)
+	tomorrow := today.AddDate(0, 0, 1)
+	introduced, err := s.Cards.CountDocuments(ctx, bson.M{"user_id": userID, "first_reviewed_at": bson.M{"$gte": today}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count new reviews: %w", err)
+	}
+	newAllowance := max(0, int64(settings.NewPerDay)-introduced)
+
+	inScope := bson.M{"user_id": userID, "upload_id": bson.M{"$in": ids}}
+	var cards []model.ReviewCard
+	cursor, err := s.Cards.Find(ctx,
+		bson.M{"$and": bson.A{inScope, bson.M{"last_reviewed_at": bson.M{"$ne": nil}, "due_at": bson.M{"$lt": tomorrow}}}},
+		options.Find().SetSort(bson.D{{Key: "due_at", Value: 1}}).SetLimit(maxDue))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch due items: %w", err)
+	}
+	if err := cursor.All(ctx, &cards); err != nil {
+		return nil, fmt.Errorf("failed to parse due items: %w", err)
+	}
+	if newAllowance > 0 {
+		var fresh []model.ReviewCard
+		cursor, err := s.Cards.Find(ctx,
+			bson.M{"$and": bson.A{inScope, bson.M{"last_reviewed_at": nil}}},
+			options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "upload_id", Value: 1}}).SetLimit(newAllowance))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch new items: %w", err)
+		}
+		if err := cursor.All(ctx, &fresh); err != nil {
+			return nil, fmt.Errorf("failed to parse new items: %w", err)
+		}
+		cards = append(cards, fresh...)
+	}
+	return s.withUploads(ctx, userID, cards)
+}
+
+// ensureCards creates cards for scoped uploads that have none yet.
+func (s *Store) ensureCards(ctx context.Context, userID string, ids []string, now time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	existing, err := s.Cards.Distinct(ctx, "upload_id", bson.M{"user_id": userID, "upload_id": bson.M{"$in": ids}}).Raw()
+	if err != nil {
+		return fmt.Errorf("failed to fetch review cards: %w", err)
+	}
+	have := map[string]bool{}
+	values, _ := existing.Values()
+	for _, v := range values {
+		have[v.StringValue()] = true
+	}
+	var missing []model.ReviewCard
+	for _, id := range ids {
+		if !have[id] {
+			missing = append(missing, model.ReviewCard{
+				UserID: userID, UploadID: id, Ease: InitialEase, DueAt: startOfDay(now), CreatedAt: now,
+			})
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	// Unordered so a card created concurrently only fails its own insert.
+	_, err = s.Cards.InsertMany(ctx, missing, options.InsertMany().SetOrdered(false))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to create review cards: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) withUploads(ctx context.Context, userID string, cards []model.ReviewCard) ([]DueItem, error) {
+	items := []DueItem{}
+	if len(cards) == 0 {
+		return items, nil
+	}
+	ids := make([]bson.ObjectID, 0, len(cards))
+	for _, c := range cards {
+		if id, err := bson.ObjectIDFromHex(c.UploadID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	cursor, err := s.Uploads.Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch uploads: %w", err)
+	}
+	var uploads []model.LykedUploads
+	if err := cursor.All(ctx, &uploads); err != nil {
+		return nil, fmt.Errorf("failed to parse uploads: %w", err)
+	}
+	byID := make(map[string]model.LykedUploads, len(uploads))
+	for _, u := range uploads {
+		byID[u.ID.Hex()] = u
+	}
+	for _, c := range cards {
+		if u, ok := byID[c.UploadID]; ok {
+			items = append(items, DueItem{Card: c, New: c.LastReviewedAt == nil, Upload: u})
+		}
+	}
+	return items, nil
+}
+
+// Grade records a review of an item and reschedules it. Items can be
+// graded early; the schedule simply starts from now.
+func (s *Store) Grade(ctx context.Context, userID string, uploadID bson.ObjectID, grade int, now time.Time) (*model.ReviewCard, error) {
+	if grade < MinGrade || grade > MaxGrade {
+		return nil, fmt.Errorf("grade must be between %d and %d", MinGrade, MaxGrade)
+	}
+	settings, err := s.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled {
+		return nil, ErrNotEnabled
+	}
+
+	var card model.ReviewCard
+	filter := bson.M{"user_id": userID, "upload_id": uploadID.Hex()}
+	err = s.Cards.FindOne(ctx, filter).Decode(&card)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotInReview
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch review card: %w", err)
+	}
+
+	Schedule(&card, grade, now)
+	if _, err := s.Cards.ReplaceOne(ctx, filter, card); err != nil {
+		return nil, fmt.Errorf("failed to save review card: %w", err)
+	}
+	entry := model.ReviewLog{UserID: userID, UploadID: card.UploadID, Grade: grade, Interval: card.Interval, ReviewedAt: now}
+	if _, err := s.Log.InsertOne(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to log review: %w", err)
+	}
+	return &card, nil
+}
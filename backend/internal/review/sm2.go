@@ -0,0 +1,55 @@
+// Package review schedules saved tutorials and lectures for spaced
+// repetition with the SM-2 algorithm.
+package review
+
+import (
+	model "lyked-backend/internal/models/mongodb"
+	"math"
+	"time"
+)
+
+const (
+	MinGrade    = 0
+	MaxGrade    = 5
+	PassGrade   = 3 // grades below this mean the item was forgotten
+	InitialEase = 2.5
+	MinEase     = 1.3
+	MatureAfter = 21 // days of interval after which an item counts as mature
+)
+
+// Schedule applies a grade (0-5) to the card as SM-2 does: a failed
+// review starts the item over, a passed one grows the interval by the
+// ease factor, and the ease moves with how hard recall was.
+func Schedule(card *model.ReviewCard, grade int, now time.Time) {
+	if grade < PassGrade {
+		if card.Repetitions > 0 {
+			card.Lapses++
+		}
+		card.Repetitions = 0
+		card.Interval = 1
+	} else {
+		switch card.Repetitions {
+		case 0:
+			card.Interval = 1
+		case 1:
+			card.Interval = 6
+		default:
+			card.Interval = int(math.Round(float64(card.Interval) * card.Ease))
+		}
+		card.Repetitions++
+	}
+
+	q := float64(MaxGrade - grade)
+	card.Ease = math.Max(MinEase, card.Ease+0.1-q*(0.08+q*0.02))
+
+	if card.FirstReviewedAt == nil {
+		card.FirstReviewedAt = &now
+	}
+	card.LastReviewedAt = &now
+	card.DueAt = startOfDay(now).AddDate(0, 0, card.Interval)
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,88 @@
+package review
+
+import (
+	model "lyked-backend/internal/models/mongodb"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	newCard := model.ReviewCard{Ease: InitialEase}
+	second := model.ReviewCard{Ease: InitialEase, Interval: 1, Repetitions: 1}
+	nth := model.ReviewCard{Ease: 2.2, Interval: 10, Repetitions: 4, Lapses: 1}
+	tests := []struct {
+		name     string
+		card     model.ReviewCard
+		grade    int
+		interval int
+		reps     int
+		lapses   int
+		ease     float64
+	}{
+		// A new card fails without counting a lapse, and passes to a day.
+		{"new", newCard, 0, 1, 0, 0, 1.7},
+		{"new", newCard, 1, 1, 0, 0, 1.96},
+		{"new", newCard, 2, 1, 0, 0, 2.18},
+		{"new", newCard, 3, 1, 1, 0, 2.36},
+		{"new", newCard, 4, 1, 1, 0, 2.5},
+		{"new", newCard, 5, 1, 1, 0, 2.6},
+
+		// The second pass is six days; a fail is a lapse.
+		{"second", second, 0, 1, 0, 1, 1.7},
+		{"second", second, 1, 1, 0, 1, 1.96},
+		{"second", second, 2, 1, 0, 1, 2.18},
+		{"second", second, 3, 6, 2, 0, 2.36},
+		{"second", second, 4, 6, 2, 0, 2.5},
+		{"second", second, 5, 6, 2, 0, 2.6},
+
+		// Later passes grow the interval by the ease before this review.
+		{"nth", nth, 0, 1, 0, 2, 1.4},
+		{"nth", nth, 1, 1, 0, 2, 1.66},
+		{"nth", nth, 2, 1, 0, 2, 1.88},
+		{"nth", nth, 3, 22, 5, 1, 2.06},
+		{"nth", nth, 4, 22, 5, 1, 2.2},
+		{"nth", nth, 5, 22, 5, 1, 2.3},
+		{"rounded", model.ReviewCard{Ease: 2.5, Interval: 7, Repetitions: 2}, 4, 18, 3, 0, 2.5},
+
+		// The ease never drops below MinEase.
+		{"floor", model.ReviewCard{Ease: MinEase, Interval: 3, Repetitions: 2}, 0, 1, 0, 1, MinEase},
+		{"floor", model.ReviewCard{Ease: 1.5, Interval: 3, Repetitions: 2}, 2, 1, 0, 1, MinEase},
+		{"floor", model.ReviewCard{Ease: MinEase, Interval: 3, Repetitions: 2}, 3, 4, 3, 0, MinEase},
+		{"floor", model.ReviewCard{Ease: MinEase, Interval: 3, Repetitions: 2}, 5, 4, 3, 0, 1.4},
+	}
+	now := time.Date(2026, 3, 11, 15, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		card := tt.card
+		Schedule(&card, tt.grade, now)
+		if card.Interval != tt.interval || card.Repetitions != tt.reps || card.Lapses != tt.lapses || math.Abs(card.Ease-tt.ease) > 1e-9 {
+			t.Errorf("%s, grade %d: interval %d, repetitions %d, lapses %d, ease %v; want %d, %d, %d, %v",
+				tt.name, tt.grade, card.Interval, card.Repetitions, card.Lapses, card.Ease, tt.interval, tt.reps, tt.lapses, tt.ease)
+		}
+		if want := time.Date(2026, 3, 11+tt.interval, 0, 0, 0, 0, time.UTC); !card.DueAt.Equal(want) {
+			t.Errorf("%s, grade %d: due %s, want %s", tt.name, tt.grade, card.DueAt, want)
+		}
+	}
+}
+
+func TestScheduleTimes(t *testing.T) {
+	// 22:30 on the 10th in New York is already the 11th in UTC.
+	first := time.Date(2026, 3, 10, 22, 30, 0, 0, time.FixedZone("", -5*3600))
+	card := model.ReviewCard{Ease: InitialEase}
+	Schedule(&card, 4, first)
+	if !card.FirstReviewedAt.Equal(first) || !card.LastReviewedAt.Equal(first) {
+		t.Errorf("reviewed at %v, %v, want %v", card.FirstReviewedAt, card.LastReviewedAt, first)
+	}
+	if want := time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC); !card.DueAt.Equal(want) {
+		t.Errorf("due %s, want %s", card.DueAt, want)
+	}
+
+	later := first.Add(26 * time.Hour)
+	Schedule(&card, 4, later)
+	if !card.FirstReviewedAt.Equal(first) || !card.LastReviewedAt.Equal(later) {
+		t.Errorf("reviewed at %v, %v, want %v, %v", card.FirstReviewedAt, card.LastReviewedAt, first, later)
+	}
+	if want := time.Date(2026, 3, 18, 0, 0, 0, 0, time.UTC); !card.DueAt.Equal(want) {
+		t.Errorf("due %s, want %s", card.DueAt, want)
+	}
+}
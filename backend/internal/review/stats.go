@@ -0,0 +1,117 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const statsDays = 30
+
+// Stats summarises a user's review progress.
+type Stats struct {
+	Cards         int64          `json:"cards"`
+	New           int64          `json:"new"`      // never reviewed
+	Learning      int64          `json:"learning"` // reviewed, interval under MatureAfter days
+	Mature        int64          `json:"mature"`
+	DueToday      int64          `json:"due_today"` // reviewed items due by the end of today
+	ReviewedToday int64          `json:"reviewed_today"`
+	Streak        int            `json:"streak"`    // consecutive days with a review, up to today
+	Retention     *float64       `json:"retention"` // share of passing grades over the last 30 days
+	Daily         map[string]int `json:"daily"`     // reviews per day over the last 30 days
+}
+
+// Stats counts cards by stage and summarises the review log.
+func (s *Store) Stats(ctx context.Context, userID string, now time.Time) (*Stats, error) {
+	today := startOfDay(now)
+	tomorrow := today.AddDate(0, 0, 1)
+	stats := &Stats{Daily: map[string]int{}}
+
+	counts := []struct {
+		dst    *int64
+		filter bson.M
+	}{
+		{&stats.Cards, bson.M{"user_id": userID}},
+		{&stats.New, bson.M{"user_id": userID, "last_reviewed_at": nil}},
+		{&stats.Learning, bson.M{"user_id": userID, "last_reviewed_at": bson.M{"$ne": nil}, "interval": bson.M{"$lt": MatureAfter}}},
+		{&stats.Mature, bson.M{"user_id": userID, "interval": bson.M{"$gte": MatureAfter}}},
+		{&stats.DueToday, bson.M{"user_id": userID, "last_reviewed_at": bson.M{"$ne": nil}, "due_at": bson.M{"$lt": tomorrow}}},
+		{&stats.ReviewedToday, bson.M{"user_id": userID, "last_reviewed_at": bson.M{"$gte": today}}},
+	}
+	for _, c := range counts {
+		n, err := s.Cards.CountDocuments(ctx, c.filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count review cards: %w", err)
+		}
+		*c.dst = n
+	}
+
+	since := today.AddDate(0, 0, -(statsDays - 1))
+	days, passed, total, err := dailyReviews(ctx, s.Log, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	for day, n := range days {
+		stats.Daily[day] = n
+	}
+	if total > 0 {
+		retention := float64(passed) / float64(total)
+		stats.Retention = &retention
+	}
+
+	// A streak still counts if today's reviews have not happened yet.
+	day := today
+	if days[day.Format("2006-01-02")] == 0 {
+		day = day.AddDate(0, 0, -1)
+	}
+	for days[day.Format("2006-01-02")] > 0 {
+		stats.Streak++
+		day = day.AddDate(0, 0, -1)
+		if day.Before(since) {
+			// Long streaks run past the window; fetch the one before it.
+			more, _, _, err := dailyReviews(ctx, s.Log, userID, day.AddDate(0, 0, -(statsDays-1)))
+			if err != nil {
+				return nil, err
+			}
+			days, since = more, day.AddDate(0, 0, -(statsDays-1))
+		}
+	}
+	return stats, nil
+}
+
+// dailyReviews groups the log by UTC day from since onwards, and counts
+// passing and total grades.
+func dailyReviews(ctx context.Context, log *mongo.Collection, userID string, since time.Time) (map[string]int, int, int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID, "reviewed_at": bson.M{"$gte": since}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$reviewed_at"}},
+			"count":  bson.M{"$sum": 1},
+			"passed": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gte": bson.A{"$grade", PassGrade}}, 1, 0}}},
+		}}},
+	}
+	cursor, err := log.Aggregate(ctx, pipeline, options.Aggregate())
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to summarise reviews: %w", err)
+	}
+	var rows []struct {
+		Day    string `bson:"_id"`
+		Count  int    `bson:"count"`
+		Passed int    `bson:"passed"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to parse review summary: %w", err)
+	}
+	days := map[string]int{}
+	passed, total := 0, 0
+	for _, r := range rows {
+		days[r.Day] = r.Count
+		passed += r.Passed
+		total += r.Count
+	}
+	return days, passed, total, nil
+}
@@ -0,0 +1,190 @@
+package rules
+
+import (
+	"fmt"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/utils"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const maxPatternLength = 500
+
+var platforms = map[string]bool{
+	utils.PlatformTikTok:    true,
+	utils.PlatformInstagram: true,
+	utils.PlatformYouTube:   true,
+	utils.PlatformPinterest: true,
+	utils.PlatformWeb:       true,
+}
+
+// matcher is a rule whose conditions are parsed and ready to test items.
+type matcher struct {
+	rule       model.Rule
+	any        bool
+	conditions []func(u *model.LykedUploads) bool
+}
+
+// compile checks the rule's conditions, normalizes them in place and
+// returns a matcher for them.
+func compile(rule *model.Rule) (*matcher, error) {
+	switch rule.Match {
+	case "":
+		rule.Match = model.RuleMatchAll
+	case model.RuleMatchAll, model.RuleMatchAny:
+	default:
+		return nil, fmt.Errorf("%w: match must be %q or %q", ErrInvalidRule, model.RuleMatchAll, model.RuleMatchAny)
+	}
+	if len(rule.Conditions) == 0 {
+		return nil, fmt.Errorf("%w: at least one condition is required", ErrInvalidRule)
+	}
+	if len(rule.Conditions) > maxConditions {
+		return nil, fmt.Errorf("%w: a rule can have at most %d conditions", ErrInvalidRule, maxConditions)
+	}
+
+	m := &matcher{rule: *rule, any: rule.Match == model.RuleMatchAny}
+	for i := range rule.Conditions {
+		cond := &rule.Conditions[i]
+		cond.Type = strings.ToLower(strings.TrimSpace(cond.Type))
+		cond.Value = strings.TrimSpace(cond.Value)
+		if cond.Value == "" {
+			return nil, fmt.Errorf("%w: condition %d needs a value", ErrInvalidRule, i+1)
+		}
+		if cond.Type != model.RuleCondRegex && cond.Field != "" {
+			return nil, fmt.Errorf("%w: only regex conditions take a field", ErrInvalidRule)
+		}
+		test, err := condition(cond)
+		if err != nil {
+			return nil, fmt.Errorf("%w: condition %d: %v", ErrInvalidRule, i+1, err)
+		}
+		m.conditions = append(m.conditions, test)
+	}
+	m.rule.Conditions = rule.Conditions
+	return m, nil
+}
+
+func condition(cond *model.RuleCondition) (func(u *model.LykedUploads) bool, error) {
+	switch cond.Type {
+	case model.RuleCondDomain:
+		host, path, err := parseDomain(cond.Value)
+		if err != nil {
+			return nil, err
+		}
+		cond.Value = host + path
+		return func(u *model.LykedUploads) bool { return matchDomain(u.VideoLink, host, path) }, nil
+
+	case model.RuleCondPlatform:
+		cond.Value = strings.ToLower(cond.Value)
+		if !platforms[cond.Value] {
+			return nil, fmt.Errorf("unknown platform %q", cond.Value)
+		}
+		platform := cond.Value
+		return func(u *model.LykedUploads) bool { return u.Platform == platform }, nil
+
+	case model.RuleCondAuthor:
+		author := normalizeAuthor(cond.Value)
+		if author == "" {
+			return nil, fmt.Errorf("author is empty")
+		}
+		return func(u *model.LykedUploads) bool { return normalizeAuthor(u.Author) == author }, nil
+
+	case model.RuleCondKeywords:
+		keywords := strings.Fields(strings.ToLower(strings.ReplaceAll(cond.Value, ",", " ")))
+		if len(keywords) == 0 {
+			return nil, fmt.Errorf("no keywords given")
+		}
+		cond.Value = strings.Join(keywords, " ")
+		return func(u *model.LykedUploads) bool {
+			title := strings.ToLower(u.Title)
+			for _, k := range keywords {
+				if !strings.Contains(title, k) {
+					return false
+				}
+			}
+			return true
+		}, nil
+
+	case model.RuleCondRegex:
+		if len(cond.Value) > maxPatternLength {
+			return nil, fmt.Errorf("pattern must be at most %d characters", maxPatternLength)
+		}
+		re, err := regexp.Compile(cond.Value)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern: %v", err)
+		}
+		if cond.Field == "" {
+			cond.Field = model.RuleFieldLink
+		}
+		field, err := regexField(cond.Field)
+		if err != nil {
+			return nil, err
+		}
+		return func(u *model.LykedUploads) bool { return re.MatchString(field(u)) }, nil
+
+	case "":
+		return nil, fmt.Errorf("type is required")
+	}
+	return nil, fmt.Errorf("unsupported type %q", cond.Type)
+}
+
+func regexField(name string) (func(u *model.LykedUploads) string, error) {
+	switch name {
+	case model.RuleFieldLink:
+		return func(u *model.LykedUploads) string { return u.VideoLink }, nil
+	case model.RuleFieldTitle:
+		return func(u *model.LykedUploads) string { return u.Title }, nil
+	case model.RuleFieldDescription:
+		return func(u *model.LykedUploads) string { return u.Description }, nil
+	case model.RuleFieldAuthor:
+		return func(u *model.LykedUploads) string { return u.Author }, nil
+	}
+	return nil, fmt.Errorf("unsupported field %q", name)
+}
+
+// matches reports whether the item meets the rule's conditions.
+func (m *matcher) matches(u *model.LykedUploads) bool {
+	for _, test := range m.conditions {
+		if test(u) == m.any {
+			return m.any
+		}
+	}
+	return !m.any
+}
+
+// parseDomain splits "https://www.YouTube.com/@babish/" into the host
+// "youtube.com" and the path prefix "/@babish".
+func parseDomain(value string) (string, string, error) {
+	raw := strings.ToLower(value)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return "", "", fmt.Errorf("%q is not a domain", value)
+	}
+	return strings.TrimPrefix(u.Hostname(), "www."), strings.TrimRight(u.EscapedPath(), "/"), nil
+}
+
+// matchDomain reports whether the link is on the host or one of its
+// subdomains and, with a path, at or below it: "youtube.com/@babish"
+// matches youtube.com/@babish/videos but not youtube.com/@babishfan.
+func matchDomain(link, host, path string) bool {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return false
+	}
+	linkHost := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if linkHost != host && !strings.HasSuffix(linkHost, "."+host) {
+		return false
+	}
+	if path == "" {
+		return true
+	}
+	linkPath := strings.ToLower(u.EscapedPath())
+	return linkPath == path || strings.HasPrefix(linkPath, path+"/")
+}
+
+func normalizeAuthor(author string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(author)), "@")
+}
@@ -0,0 +1,198 @@
+package rules
+
+import (
+	"errors"
+	model "lyked-backend/internal/models/mongodb"
+	"strings"
+	"testing"
+)
+
+func TestParseDomain(t *testing.T) {
+	tests := []struct {
+		value, host, path string
+	}{
+		{"youtube.com", "youtube.com", ""},
+		{"https://www.YouTube.com/@babish/", "youtube.com", "/@babish"},
+		{"www.tiktok.com/@chef", "tiktok.com", "/@chef"},
+		{"m.youtube.com", "m.youtube.com", ""},
+		{"http://example.com/recipes/pasta", "example.com", "/recipes/pasta"},
+		{"example.com:8080/a", "example.com", "/a"},
+	}
+	for _, tt := range tests {
+		host, path, err := parseDomain(tt.value)
+		if err != nil || host != tt.host || path != tt.path {
+			t.Errorf("parseDomain(%q) = %q, %q, %v, want %q, %q", tt.value, host, path, err, tt.host, tt.path)
+		}
+	}
+	for _, value := range []string{"://", "https:///path", "https://%zz"} {
+		if _, _, err := parseDomain(value); err == nil {
+			t.Errorf("parseDomain(%q) should fail", value)
+		}
+	}
+}
+
+func TestMatchDomain(t *testing.T) {
+	tests := []struct {
+		link, host, path string
+		want             bool
+	}{
+		{"https://youtube.com/watch?v=1", "youtube.com", "", true},
+		{"https://www.youtube.com/watch?v=1", "youtube.com", "", true},
+		{"https://music.youtube.com/watch?v=1", "youtube.com", "", true},
+		{"https://WWW.YouTube.com/watch?v=1", "youtube.com", "", true},
+		{"https://notyoutube.com/watch?v=1", "youtube.com", "", false},
+		{"https://youtube.com.example.net/", "youtube.com", "", false},
+		{"https://youtu.be/1", "youtube.com", "", false},
+		{"https://m.youtube.com/", "m.youtube.com", "", true},
+		{"https://youtube.com/", "m.youtube.com", "", false},
+
+		{"https://www.youtube.com/@babish", "youtube.com", "/@babish", true},
+		{"https://youtube.com/@babish/videos", "youtube.com", "/@babish", true},
+		{"https://youtube.com/@Babish/", "youtube.com", "/@babish", true},
+		{"https://youtube.com/@babishfan", "youtube.com", "/@babish", false},
+		{"https://youtube.com/watch?v=1", "youtube.com", "/@babish", false},
+		{"https://example.com/youtube.com/@babish", "youtube.com", "/@babish", false},
+
+		{"", "youtube.com", "", false},
+		{"not a link", "youtube.com", "", false},
+		{"https://%zz", "youtube.com", "", false},
+	}
+	for _, tt := range tests {
+		if got := matchDomain(tt.link, tt.host, tt.path); got != tt.want {
+			t.Errorf("matchDomain(%q, %q, %q) = %v, want %v", tt.link, tt.host, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCompileNormalizes(t *testing.T) {
+	rule := model.Rule{Conditions: []model.RuleCondition{
+		{Type: " Domain ", Value: " https://www.YouTube.com/@babish/ "},
+		{Type: model.RuleCondPlatform, Value: "YouTube"},
+		{Type: model.RuleCondAuthor, Value: "@Babish"},
+		{Type: model.RuleCondKeywords, Value: "Air,  Fryer, "},
+		{Type: model.RuleCondRegex, Value: `^https://`},
+	}}
+	if _, err := compile(&rule); err != nil {
+		t.Fatal(err)
+	}
+	if rule.Match != model.RuleMatchAll {
+		t.Errorf("match = %q, want %q", rule.Match, model.RuleMatchAll)
+	}
+	want := []model.RuleCondition{
+		{Type: model.RuleCondDomain, Value: "youtube.com/@babish"},
+		{Type: model.RuleCondPlatform, Value: "youtube"},
+		{Type: model.RuleCondAuthor, Value: "@Babish"},
+		{Type: model.RuleCondKeywords, Value: "air fryer"},
+		{Type: model.RuleCondRegex, Value: `^https://`, Field: model.RuleFieldLink},
+	}
+	for i, cond := range rule.Conditions {
+		if cond != want[i] {
+			t.Errorf("condition %d = %+v, want %+v", i+1, cond, want[i])
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	cond := func(typ, value string) []model.RuleCondition {
+		return []model.RuleCondition{{Type: typ, Value: value}}
+	}
+	tooMany := make([]model.RuleCondition, maxConditions+1)
+	for i := range tooMany {
+		tooMany[i] = model.RuleCondition{Type: model.RuleCondKeywords, Value: "pasta"}
+	}
+	tests := []struct {
+		name string
+		rule model.Rule
+	}{
+		{"bad match", model.Rule{Match: "most", Conditions: cond(model.RuleCondKeywords, "pasta")}},
+		{"no conditions", model.Rule{}},
+		{"too many conditions", model.Rule{Conditions: tooMany}},
+		{"empty value", model.Rule{Conditions: cond(model.RuleCondKeywords, "  ")}},
+		{"missing type", model.Rule{Conditions: cond("", "pasta")}},
+		{"unknown type", model.Rule{Conditions: cond("color", "red")}},
+		{"bad domain", model.Rule{Conditions: cond(model.RuleCondDomain, "https:///path")}},
+		{"unknown platform", model.Rule{Conditions: cond(model.RuleCondPlatform, "myspace")}},
+		{"bare @ author", model.Rule{Conditions: cond(model.RuleCondAuthor, "@")}},
+		{"only commas", model.Rule{Conditions: cond(model.RuleCondKeywords, ", ,")}},
+		{"bad pattern", model.Rule{Conditions: cond(model.RuleCondRegex, "(")}},
+		{"long pattern", model.Rule{Conditions: cond(model.RuleCondRegex, strings.Repeat("a", maxPatternLength+1))}},
+		{"unknown field", model.Rule{Conditions: []model.RuleCondition{{Type: model.RuleCondRegex, Value: "a", Field: "tags"}}}},
+		{"field on a domain", model.Rule{Conditions: []model.RuleCondition{{Type: model.RuleCondDomain, Value: "youtube.com", Field: model.RuleFieldTitle}}}},
+	}
+	for _, tt := range tests {
+		if _, err := compile(&tt.rule); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("%s: compile = %v, want ErrInvalidRule", tt.name, err)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	conditions := []model.RuleCondition{
+		{Type: model.RuleCondDomain, Value: "youtube.com/@babish"},
+		{Type: model.RuleCondKeywords, Value: "pasta"},
+	}
+	babishPasta := &model.LykedUploads{VideoLink: "https://www.youtube.com/@babish/shorts/1", Title: "Pasta aglio e olio"}
+	babishBurger := &model.LykedUploads{VideoLink: "https://www.youtube.com/@babish/shorts/2", Title: "Burger"}
+	fanPasta := &model.LykedUploads{VideoLink: "https://www.youtube.com/@babishfan/shorts/3", Title: "Fan pasta"}
+	neither := &model.LykedUploads{VideoLink: "https://tiktok.com/@chef/video/4", Title: "Tacos"}
+
+	tests := []struct {
+		match string
+		item  *model.LykedUploads
+		want  bool
+	}{
+		{model.RuleMatchAll, babishPasta, true},
+		{model.RuleMatchAll, babishBurger, false},
+		{model.RuleMatchAll, fanPasta, false},
+		{model.RuleMatchAll, neither, false},
+		{model.RuleMatchAny, babishPasta, true},
+		{model.RuleMatchAny, babishBurger, true},
+		{model.RuleMatchAny, fanPasta, true},
+		{model.RuleMatchAny, neither, false},
+	}
+	for _, tt := range tests {
+		rule := model.Rule{Match: tt.match, Conditions: append([]model.RuleCondition(nil), conditions...)}
+		m, err := compile(&rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.matches(tt.item); got != tt.want {
+			t.Errorf("%s: matches(%q, %q) = %v, want %v", tt.match, tt.item.VideoLink, tt.item.Title, got, tt.want)
+		}
+	}
+}
+
+func TestConditions(t *testing.T) {
+	item := &model.LykedUploads{
+		VideoLink:   "https://www.tiktok.com/@chef.jo/video/1",
+		Platform:    "tiktok",
+		Author:      "@Chef.Jo",
+		Title:       "Crispy Air Fryer Chickpeas",
+		Description: "high protein snack",
+	}
+	tests := []struct {
+		cond model.RuleCondition
+		want bool
+	}{
+		{model.RuleCondition{Type: model.RuleCondPlatform, Value: "TikTok"}, true},
+		{model.RuleCondition{Type: model.RuleCondPlatform, Value: "youtube"}, false},
+		{model.RuleCondition{Type: model.RuleCondAuthor, Value: "chef.jo"}, true},
+		{model.RuleCondition{Type: model.RuleCondAuthor, Value: "chef"}, false},
+		{model.RuleCondition{Type: model.RuleCondKeywords, Value: "fryer, crispy"}, true},
+		{model.RuleCondition{Type: model.RuleCondKeywords, Value: "fryer oven"}, false},
+		{model.RuleCondition{Type: model.RuleCondRegex, Value: `/video/\d+$`}, true},
+		{model.RuleCondition{Type: model.RuleCondRegex, Value: `protein`, Field: model.RuleFieldDescription}, true},
+		{model.RuleCondition{Type: model.RuleCondRegex, Value: `protein`, Field: model.RuleFieldTitle}, false},
+		{model.RuleCondition{Type: model.RuleCondRegex, Value: `^@chef`, Field: model.RuleFieldAuthor}, false}, // case sensitive
+	}
+	for _, tt := range tests {
+		rule := model.Rule{Conditions: []model.RuleCondition{tt.cond}}
+		m, err := compile(&rule)
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.cond, err)
+		}
+		if got := m.matches(item); got != tt.want {
+			t.Errorf("%+v: matches = %v, want %v", tt.cond, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Package rules runs user-defined rules that tag, file and set the status
+// of items matching conditions on their link, platform, author and title.
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	MaxRules      = 100
+	maxConditions = 20
+	maxNameLength = 100
+)
+
+var (
+	ErrRuleNotFound = errors.New("rule not found")
+	ErrInvalidRule  = errors.New("invalid rule")
+	ErrTooManyRules = fmt.Errorf("a user can have at most %d rules", MaxRules)
+)
+
+// Store bundles the collections rules work with.
+type Store struct {
+	Rules   *mongo.Collection
+	Uploads *mongo.Collection
+	Folders *mongo.Collection
+}
+
+// RuleRequest creates a rule or replaces one. Rules are enabled unless
+// enabled is false, and need every condition to hold unless match is "any".
+type RuleRequest struct {
+	Name       string                `json:"name"`
+	Enabled    *bool                 `json:"enabled"`
+	Match      string                `json:"match"`
+	Conditions []model.RuleCondition `json:"conditions"`
+	Actions    model.RuleActions     `json:"actions"`
+}
+
+// List returns the user's rules in the order they run.
+func (s *Store) List(ctx context.Context, userID string) ([]model.Rule, error) {
+	cursor, err := s.Rules.Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rules: %w", err)
+	}
+	rules := []model.Rule{}
+	if err := cursor.All(ctx, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Create checks and saves a new rule. It only affects items saved from now
+// on; Run applies it to the rest.
+func (s *Store) Create(ctx context.Context, userID string, req RuleRequest, now time.Time) (*model.Rule, error) {
+	count, err := s.Rules.CountDocuments(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rules: %w", err)
+	}
+	if count >= MaxRules {
+		return nil, ErrTooManyRules
+	}
+	rule := &model.Rule{ID: bson.NewObjectID(), UserID: userID, CreatedAt: now}
+	if err := s.define(ctx, rule, req, now); err != nil {
+		return nil, err
+	}
+	if _, err := s.Rules.InsertOne(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to save rule: %w", err)
+	}
+	return rule, nil
+}
+
+// Update replaces a rule's definition. It keeps its place in the order.
+func (s *Store) Update(ctx context.Context, userID, rawID string, req RuleRequest, now time.Time) (*model.Rule, error) {
+	rule, err := s.Get(ctx, userID, rawID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.define(ctx, rule, req, now); err != nil {
+		return nil, err
+	}
+	res, err := s.Rules.ReplaceOne(ctx, bson.M{"_id": rule.ID, "user_id": userID}, rule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save rule: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, ErrRuleNotFound
+	}
+	return rule, nil
+}
+
+// Delete removes a rule. What it already did to items stays.
+func (s *Store) Delete(ctx context.Context, userID, rawID string) error {
+	id, err := bson.ObjectIDFromHex(rawID)
+	if err != nil {
+		return ErrRuleNotFound
+	}
+	res, err := s.Rules.DeleteOne(ctx, bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete rule: %w", err)
+	}
+	if res.DeletedCount == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// Get returns one of the user's rules.
+func (s *Store) Get(ctx context.Context, userID, rawID string) (*model.Rule, error) {
+	id, err := bson.ObjectIDFromHex(rawID)
+	if err != nil {
+		return nil, ErrRuleNotFound
+	}
+	var rule model.Rule
+	err = s.Rules.FindOne(ctx, bson.M{"_id": id, "user_id": userID}).Decode(&rule)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrRuleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// define checks the request and copies it onto the rule.
+func (s *Store) define(ctx context.Context, rule *model.Rule, req RuleRequest, now time.Time) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+	if len(name) > maxNameLength {
+		return fmt.Errorf("%w: name must be at most %d characters", ErrInvalidRule, maxNameLength)
+	}
+	candidate := model.Rule{Match: req.Match, Conditions: req.Conditions}
+	if _, err := compile(&candidate); err != nil {
+		return err
+	}
+	actions, err := s.checkActions(ctx, rule.UserID, req.Actions)
+	if err != nil {
+		return err
+	}
+	if len(actions.AddTags) == 0 && actions.FolderID == "" && actions.Status == "" {
+		return fmt.Errorf("%w: add a tag, a folder or a status for the rule to set", ErrInvalidRule)
+	}
+
+	rule.Name = name
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	rule.Match = candidate.Match
+	rule.Conditions = candidate.Conditions
+	rule.Actions = actions
+	rule.UpdatedAt = now
+	return nil
+}
+
+// checkActions resolves the tags through the user's aliases and checks
+// that the folder is a static folder the user can file into and that the
+// status exists.
+func (s *Store) checkActions(ctx context.Context, userID string, actions model.RuleActions) (model.RuleActions, error) {
+	tags, err := library.ResolveTags(ctx, userID, actions.AddTags)
+	if err != nil {
+		return actions, err
+	}
+	actions.AddTags = tags
+	if actions.FolderID != "" {
+		ids, err := library.StaticFolderIDs(ctx, s.Folders, userID, []string{actions.FolderID})
+		if err != nil {
+			return actions, err
+		}
+		actions.FolderID = ids[0]
+	}
+	if actions.Status != "" {
+		if err := library.ValidateStatus(actions.Status); err != nil {
+			return actions, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	}
+	return actions, nil
+}
@@ -0,0 +1,305 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DefaultPreviewLimit = 20
+	MaxPreviewLimit     = 100
+)
+
+// Set is a user's enabled rules, ready to run on items as they are saved.
+type Set struct {
+	matchers []*matcher
+}
+
+// RunResult is what running one rule over the library did.
+type RunResult struct {
+	RuleID  string `json:"rule_id"`
+	Name    string `json:"name"`
+	Matched int    `json:"matched"`
+	Updated int    `json:"updated"`
+	Error   string `json:"error,omitempty"` // set when an action could not run, e.g. its folder is gone
+}
+
+// Preview is what a rule would do to the library, without doing it.
+type Preview struct {
+	Matched     int           `json:"matched"`
+	WouldChange int           `json:"would_change"`
+	Items       []PreviewItem `json:"items"` // the most recently saved matches
+}
+
+// PreviewItem is a matching item and the changes the rule would make to it
+// when run on demand.
+type PreviewItem struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	VideoLink   string   `json:"video_link"`
+	Platform    string   `json:"platform"`
+	Author      string   `json:"author"`
+	AddTags     []string `json:"add_tags,omitempty"`
+	AddToFolder bool     `json:"add_to_folder,omitempty"`
+	SetStatus   string   `json:"set_status,omitempty"`
+}
+
+// candidate holds the fields rules test and change.
+var candidate = bson.M{
+	"title": 1, "description": 1, "author": 1, "video_link": 1, "platform": 1,
+	"tags": 1, "folders": 1, "status": 1, "saved_at": 1,
+}
+
+// Active loads the user's enabled rules. Folder actions whose folder was
+// deleted, or is no longer shared with the user, are left out.
+func (s *Store) Active(ctx context.Context, userID string) (*Set, error) {
+	rules, err := s.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	set := &Set{}
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Enabled {
+			continue
+		}
+		m, err := compile(rule)
+		if err != nil {
+			continue
+		}
+		if rule.Actions.FolderID != "" {
+			_, err := library.StaticFolderIDs(ctx, s.Folders, userID, []string{rule.Actions.FolderID})
+			if unusableFolder(err) {
+				m.rule.Actions.FolderID = ""
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		set.matchers = append(set.matchers, m)
+	}
+	return set, nil
+}
+
+// Apply runs the rules on an item about to be saved. Tags and folders are
+// added; the status is only set when the item has none, by the first rule
+// that sets one. It returns how many rules matched.
+func (set *Set) Apply(u *model.LykedUploads, now time.Time) int {
+	matched := 0
+	for _, m := range set.matchers {
+		if !m.matches(u) {
+			continue
+		}
+		matched++
+		actions := m.rule.Actions
+		if len(actions.AddTags) > 0 {
+			u.Tags = library.NormalizeTags(append(u.Tags, actions.AddTags...))
+		}
+		if actions.FolderID != "" && !slices.Contains(u.Folders, actions.FolderID) {
+			u.Folders = append(u.Folders, actions.FolderID)
+		}
+		if actions.Status != "" && u.Status == "" {
+			u.Status = actions.Status
+			u.StatusChangedAt = &now
+			if u.Status == model.WatchStatusWatched {
+				u.WatchedAt = &now
+			}
+		}
+	}
+	return matched
+}
+
+// RunAll applies every enabled rule to the items already saved, in order.
+// It returns a result per rule and the ids of the items that changed.
+func (s *Store) RunAll(ctx context.Context, userID string, now time.Time) ([]RunResult, []bson.ObjectID, error) {
+	rules, err := s.List(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	enabled := []model.Rule{}
+	for _, rule := range rules {
+		if rule.Enabled {
+			enabled = append(enabled, rule)
+		}
+	}
+	return s.run(ctx, userID, enabled, now)
+}
+
+// RunOne applies one rule to the items already saved, even if it is
+// disabled.
+func (s *Store) RunOne(ctx context.Context, userID, rawID string, now time.Time) (*RunResult, []bson.ObjectID, error) {
+	rule, err := s.Get(ctx, userID, rawID)
+	if err != nil {
+		return nil, nil, err
+	}
+	results, changed, err := s.run(ctx, userID, []model.Rule{*rule}, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &results[0], changed, nil
+}
+
+// run matches every rule against the user's live items, then applies each
+// rule's actions with the batch operations. Unlike at save time, a rule's
+// status replaces the one items have.
+func (s *Store) run(ctx context.Context, userID string, rules []model.Rule, now time.Time) ([]RunResult, []bson.ObjectID, error) {
+	results := make([]RunResult, len(rules))
+	matchers := make([]*matcher, len(rules))
+	for i := range rules {
+		results[i] = RunResult{RuleID: rules[i].ID.Hex(), Name: rules[i].Name}
+		m, err := compile(&rules[i])
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		matchers[i] = m
+	}
+
+	matched := make([][]string, len(rules))
+	err := s.scan(ctx, userID, func(u *model.LykedUploads) {
+		for i, m := range matchers {
+			if m != nil && m.matches(u) {
+				matched[i] = append(matched[i], u.ID.Hex())
+			}
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changed := []bson.ObjectID{}
+	seen := map[bson.ObjectID]bool{}
+	for i, rule := range rules {
+		results[i].Matched = len(matched[i])
+		updated := map[string]bool{}
+		for _, req := range batches(rule.Actions, matched[i]) {
+			if err := req.Validate(); err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			res, err := library.ApplyBatch(ctx, s.Uploads, s.Folders, userID, req, now)
+			if unusableFolder(err) {
+				results[i].Error = err.Error()
+				continue
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, item := range res.Results {
+				if item.Status != library.BatchUpdated {
+					continue
+				}
+				updated[item.ID] = true
+				if id, err := bson.ObjectIDFromHex(item.ID); err == nil && !seen[id] {
+					seen[id] = true
+					changed = append(changed, id)
+				}
+			}
+		}
+		results[i].Updated = len(updated)
+	}
+	return results, changed, nil
+}
+
+// Test previews a rule that need not be saved: how many items match, how
+// many running it would change, and the most recent matches.
+func (s *Store) Test(ctx context.Context, userID string, req RuleRequest, limit int) (*Preview, error) {
+	rule := model.Rule{Match: req.Match, Conditions: req.Conditions}
+	m, err := compile(&rule)
+	if err != nil {
+		return nil, err
+	}
+	actions, err := s.checkActions(ctx, userID, req.Actions)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultPreviewLimit
+	}
+	limit = min(limit, MaxPreviewLimit)
+
+	preview := &Preview{Items: []PreviewItem{}}
+	err = s.scan(ctx, userID, func(u *model.LykedUploads) {
+		if !m.matches(u) {
+			return
+		}
+		preview.Matched++
+		item := PreviewItem{ID: u.ID.Hex(), Title: u.Title, VideoLink: u.VideoLink, Platform: u.Platform, Author: u.Author}
+		for _, tag := range actions.AddTags {
+			if !slices.Contains(u.Tags, tag) {
+				item.AddTags = append(item.AddTags, tag)
+			}
+		}
+		item.AddToFolder = actions.FolderID != "" && !slices.Contains(u.Folders, actions.FolderID)
+		if actions.Status != "" && actions.Status != library.StatusOf(*u) {
+			item.SetStatus = actions.Status
+		}
+		if len(item.AddTags) > 0 || item.AddToFolder || item.SetStatus != "" {
+			preview.WouldChange++
+		}
+		if len(preview.Items) < limit {
+			preview.Items = append(preview.Items, item)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// scan calls fn with each of the user's live items, newest first.
+func (s *Store) scan(ctx context.Context, userID string, fn func(u *model.LykedUploads)) error {
+	opts := options.Find().SetProjection(candidate).SetSort(bson.D{{Key: "saved_at", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := s.Uploads.Find(ctx, bson.M{"user_id": userID, "deleted_at": nil}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to fetch uploads: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var u model.LykedUploads
+		if err := cursor.Decode(&u); err != nil {
+			return fmt.Errorf("failed to parse upload: %w", err)
+		}
+		fn(&u)
+	}
+	return cursor.Err()
+}
+
+// batches turns a rule's actions into batch requests over the matched ids,
+// each within the batch size limit.
+func batches(actions model.RuleActions, ids []string) []library.BatchRequest {
+	var ops []library.BatchRequest
+	if len(actions.AddTags) > 0 {
+		ops = append(ops, library.BatchRequest{Operation: library.OpAddTags, Tags: actions.AddTags})
+	}
+	if actions.FolderID != "" {
+		ops = append(ops, library.BatchRequest{Operation: library.OpAddToFolder, FolderID: actions.FolderID})
+	}
+	if actions.Status != "" {
+		ops = append(ops, library.BatchRequest{Operation: library.OpSetStatus, Status: actions.Status})
+	}
+
+	var reqs []library.BatchRequest
+	for _, op := range ops {
+		for chunk := range slices.Chunk(ids, library.MaxBatchSize) {
+			req := op
+			req.IDs = chunk
+			reqs = append(reqs, req)
+		}
+	}
+	return reqs
+}
+
+// unusableFolder reports whether err means a rule's folder can no longer
+// be filed into.
+func unusableFolder(err error) bool {
+	return errors.Is(err, library.ErrFolderNotFound) || errors.Is(err, library.ErrSmartFolder) || errors.Is(err, library.ErrForbidden)
+}
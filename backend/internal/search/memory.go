@@ -0,0 +1,277 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	bm25K1      = 1.2
+	prefixBoost = 0.6 // prefix matches rank below exact ones
+)
+
+// Loader returns every document a user owns. MemoryIndex calls it the first
+// time a user searches so the index never has to be persisted.
+type Loader func(ctx context.Context, userID string) ([]Document, error)
+
+// MemoryIndex is a pure-Go inverted index, sharded per user.
+type MemoryIndex struct {
+	mu     sync.RWMutex
+	shards map[string]*shard
+	load   Loader
+}
+
+type shard struct {
+	docs map[string]*Document
+	// postings maps term -> doc id -> field -> term frequency
+	postings map[string]map[string]map[string]int
+	terms    []string // sorted vocabulary for prefix lookups, rebuilt lazily
+	dirty    bool
+}
+
+func NewMemoryIndex(load Loader) *MemoryIndex {
+	return &MemoryIndex{shards: map[string]*shard{}, load: load}
+}
+
+func newShard() *shard {
+	return &shard{docs: map[string]*Document{}, postings: map[string]map[string]map[string]int{}}
+}
+
+func (m *MemoryIndex) Index(ctx context.Context, doc Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Users who haven't searched yet get their whole vault loaded on first search.
+	if s, ok := m.shards[doc.UserID]; ok {
+		s.add(&doc)
+	}
+	return nil
+}
+
+func (m *MemoryIndex) Remove(ctx context.Context, userID, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.shards[userID]; ok {
+		s.remove(id)
+	}
+	return nil
+}
+
+func (m *MemoryIndex) Search(ctx context.Context, q Query) (*Result, error) {
+	q.normalize()
+	if err := m.ensureLoaded(ctx, q.UserID); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	s := m.shards[q.UserID]
+	if s.dirty {
+		s.rebuildTerms()
+	}
+	m.mu.Unlock()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	terms := queryTerms(q.Text)
+	scores := s.score(terms)
+
+	result := &Result{Facets: newFacets()}
+	var hits []Hit
+	for id, score := range scores {
+		doc := s.docs[id]
+		if !q.matchesFacets(doc) {
+			continue
+		}
+		result.Facets.add(doc)
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		a, b := s.docs[hits[i].ID], s.docs[hits[j].ID]
+		if !a.SavedAt.Equal(b.SavedAt) {
+			return a.SavedAt.After(b.SavedAt)
+		}
+		return a.ID > b.ID
+	})
+
+	result.Total = len(hits)
+	if q.Offset >= len(hits) {
+		hits = nil
+	} else {
+		hits = hits[q.Offset:]
+		if len(hits) > q.Limit {
+			hits = hits[:q.Limit]
+		}
+	}
+	for i := range hits {
+		hits[i].Highlights = highlights(s.docs[hits[i].ID], terms)
+	}
+	result.Hits = hits
+	if result.Hits == nil {
+		result.Hits = []Hit{}
+	}
+	return result, nil
+}
+
+func (m *MemoryIndex) ensureLoaded(ctx context.Context, userID string) error {
+	m.mu.RLock()
+	_, ok := m.shards[userID]
+	m.mu.RUnlock()
+	if ok {
+		return nil
+	}
+
+	docs, err := m.load(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load search documents: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.shards[userID]; ok {
+		return nil
+	}
+	s := newShard()
+	for i := range docs {
+		s.add(&docs[i])
+	}
+	m.shards[userID] = s
+	return nil
+}
+
+func (s *shard) add(doc *Document) {
+	s.remove(doc.ID)
+	s.docs[doc.ID] = doc
+	for field, text := range doc.fields() {
+		for _, tok := range tokenize(text) {
+			if stopWords[tok.term] {
+				continue
+			}
+			byDoc, ok := s.postings[tok.term]
+			if !ok {
+				byDoc = map[string]map[string]int{}
+				s.postings[tok.term] = byDoc
+				s.dirty = true
+			}
+			if byDoc[doc.ID] == nil {
+				byDoc[doc.ID] = map[string]int{}
+			}
+			byDoc[doc.ID][field]++
+		}
+	}
+}
+
+func (s *shard) remove(id string) {
+	doc, ok := s.docs[id]
+	if !ok {
+		return
+	}
+	delete(s.docs, id)
+	for _, text := range doc.fields() {
+		for _, tok := range tokenize(text) {
+			byDoc, ok := s.postings[tok.term]
+			if !ok {
+				continue
+			}
+			delete(byDoc, id)
+			if len(byDoc) == 0 {
+				delete(s.postings, tok.term)
+				s.dirty = true
+			}
+		}
+	}
+}
+
+func (s *shard) rebuildTerms() {
+	s.terms = s.terms[:0]
+	for term := range s.postings {
+		s.terms = append(s.terms, term)
+	}
+	sort.Strings(s.terms)
+	s.dirty = false
+}
+
+// expand returns every indexed term a query term matches, exact match first.
+func (s *shard) expand(term string) []string {
+	var out []string
+	if _, ok := s.postings[term]; ok {
+		out = append(out, term)
+	}
+	if len(term) < minPrefixLen {
+		return out
+	}
+	i := sort.SearchStrings(s.terms, term)
+	for ; i < len(s.terms) && strings.HasPrefix(s.terms[i], term); i++ {
+		if s.terms[i] != term {
+			out = append(out, s.terms[i])
+		}
+	}
+	return out
+}
+
+// score ranks documents matching every term with a field-weighted BM25
+// variant. With no terms, every document matches with a zero score.
+func (s *shard) score(terms []string) map[string]float64 {
+	scores := map[string]float64{}
+	if len(terms) == 0 {
+		for id := range s.docs {
+			scores[id] = 0
+		}
+		return scores
+	}
+
+	n := float64(len(s.docs))
+	for i, term := range terms {
+		words := s.expand(term)
+		// idf is taken over every document the term reaches, so a rare
+		// completion can't outrank an exact match of a common word.
+		reached := map[string]bool{}
+		for _, word := range words {
+			for id := range s.postings[word] {
+				reached[id] = true
+			}
+		}
+		df := float64(len(reached))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+		termScores := map[string]float64{}
+		for _, word := range words {
+			byDoc := s.postings[word]
+			boost := 1.0
+			if word != term {
+				boost = prefixBoost
+			}
+			for id, freqs := range byDoc {
+				var v float64
+				for field, tf := range freqs {
+					f := float64(tf)
+					v += fieldWeights[field] * f * (bm25K1 + 1) / (f + bm25K1)
+				}
+				v *= idf * boost
+				if v > termScores[id] {
+					termScores[id] = v
+				}
+			}
+		}
+
+		if i == 0 {
+			scores = termScores
+			continue
+		}
+		for id := range scores {
+			if v, ok := termScores[id]; ok {
+				scores[id] += v
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+	return scores
+}
@@ -0,0 +1,269 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+var day = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+// fixture is a small vault for one user.
+var fixture = []Document{
+	{ID: "a", UserID: "u1", Title: "Pasta carbonara", Description: "A Roman classic", Tags: []string{"food/pasta"}, Platform: "youtube", Folders: []string{"f1"}, SavedAt: day},
+	{ID: "b", UserID: "u1", Title: "Bread basics", Description: "Sourdough and pasta dough", Tags: []string{"food/baking"}, Platform: "instagram", SavedAt: day.Add(time.Hour)},
+	{ID: "c", UserID: "u1", Title: "Go concurrency", Author: "Pasta Lover", Tags: []string{"dev/go"}, Platform: "web", SavedAt: day.Add(2 * time.Hour)},
+	{ID: "d", UserID: "u1", Title: "Pastry cream", Tags: []string{"food/baking"}, Platform: "youtube", Folders: []string{"f1"}, SavedAt: day.Add(3 * time.Hour)},
+	{ID: "e", UserID: "u1", Title: "Notes only", Notes: "remember the pasta water", Platform: "web", SavedAt: day.Add(4 * time.Hour)},
+}
+
+func newTestIndex(t *testing.T, docs []Document) (*MemoryIndex, *int) {
+	t.Helper()
+	loads := 0
+	idx := NewMemoryIndex(func(ctx context.Context, userID string) ([]Document, error) {
+		loads++
+		var out []Document
+		for _, d := range docs {
+			if d.UserID == userID {
+				out = append(out, d)
+			}
+		}
+		return out, nil
+	})
+	return idx, &loads
+}
+
+func hitIDs(r *Result) []string {
+	ids := make([]string, len(r.Hits))
+	for i, h := range r.Hits {
+		ids[i] = h.ID
+	}
+	return ids
+}
+
+func TestMemoryIndexSearch(t *testing.T) {
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+		total int
+	}{
+		{
+			// A title match outweighs the author, which outweighs the
+			// description and notes.
+			name:  "field weights",
+			query: Query{Text: "pasta"},
+			want:  []string{"a", "c", "e", "b"},
+			total: 4,
+		},
+		{
+			name:  "prefix",
+			query: Query{Text: "pas"},
+			want:  []string{"a", "d", "c", "e", "b"},
+			total: 5,
+		},
+		{
+			name:  "every term must match",
+			query: Query{Text: "pasta sourdough"},
+			want:  []string{"b"},
+			total: 1,
+		},
+		{
+			name:  "stop words are ignored",
+			query: Query{Text: "the carbonara"},
+			want:  []string{"a"},
+			total: 1,
+		},
+		{
+			name:  "no match",
+			query: Query{Text: "risotto"},
+			want:  []string{},
+			total: 0,
+		},
+		{
+			name:  "empty text lists everything newest first",
+			query: Query{},
+			want:  []string{"e", "d", "c", "b", "a"},
+			total: 5,
+		},
+		{
+			name:  "tag facet matches tags below it",
+			query: Query{Text: "pas", Tags: []string{"Food"}},
+			want:  []string{"a", "d", "b"},
+			total: 3,
+		},
+		{
+			name:  "platform facet",
+			query: Query{Text: "pas", Platform: "youtube"},
+			want:  []string{"a", "d"},
+			total: 2,
+		},
+		{
+			name:  "folder facet",
+			query: Query{Folder: "f1"},
+			want:  []string{"d", "a"},
+			total: 2,
+		},
+		{
+			name:  "ids",
+			query: Query{Text: "pasta", IDs: []string{"b", "c"}},
+			want:  []string{"c", "b"},
+			total: 2,
+		},
+		{
+			name:  "paging",
+			query: Query{Limit: 2, Offset: 1},
+			want:  []string{"d", "c"},
+			total: 5,
+		},
+		{
+			name:  "offset past the end",
+			query: Query{Offset: 10},
+			want:  []string{},
+			total: 5,
+		},
+		{
+			name:  "other users' documents are invisible",
+			query: Query{UserID: "u2", Text: "pasta"},
+			want:  []string{},
+			total: 0,
+		},
+	}
+	idx, _ := newTestIndex(t, fixture)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.query
+			if q.UserID == "" {
+				q.UserID = "u1"
+			}
+			r, err := idx.Search(context.Background(), q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hitIDs(r); !slices.Equal(got, tt.want) {
+				t.Errorf("hits = %v, want %v", got, tt.want)
+			}
+			if r.Total != tt.total {
+				t.Errorf("total = %d, want %d", r.Total, tt.total)
+			}
+		})
+	}
+}
+
+func TestMemoryIndexPrefixRanksBelowExact(t *testing.T) {
+	idx, _ := newTestIndex(t, []Document{
+		{ID: "older", UserID: "u1", Title: "Pastas", SavedAt: day},
+		{ID: "newer", UserID: "u1", Title: "Pastas", SavedAt: day.Add(time.Hour)},
+		{ID: "exact", UserID: "u1", Title: "Pasta"},
+	})
+	r, err := idx.Search(context.Background(), Query{UserID: "u1", Text: "pasta"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Equal scores fall back to the newest first.
+	if got, want := hitIDs(r), []string{"exact", "newer", "older"}; !slices.Equal(got, want) {
+		t.Errorf("hits = %v, want %v", got, want)
+	}
+	if r.Hits[0].Score <= r.Hits[1].Score {
+		t.Errorf("exact score %v should beat prefix score %v", r.Hits[0].Score, r.Hits[1].Score)
+	}
+}
+
+func TestMemoryIndexFacets(t *testing.T) {
+	idx, _ := newTestIndex(t, fixture)
+	r, err := idx.Search(context.Background(), Query{UserID: "u1", Text: "pas", Platform: "youtube", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Facets count every match, not just the page.
+	wantTags := map[string]int{"food/pasta": 1, "food/baking": 1}
+	wantPlatforms := map[string]int{"youtube": 2}
+	if !mapsEqual(r.Facets.Tags, wantTags) {
+		t.Errorf("tag facets = %v, want %v", r.Facets.Tags, wantTags)
+	}
+	if !mapsEqual(r.Facets.Platforms, wantPlatforms) {
+		t.Errorf("platform facets = %v, want %v", r.Facets.Platforms, wantPlatforms)
+	}
+}
+
+func TestMemoryIndexHighlights(t *testing.T) {
+	idx, _ := newTestIndex(t, fixture)
+	r, err := idx.Search(context.Background(), Query{UserID: "u1", Text: "carbonara"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Hits) != 1 {
+		t.Fatalf("got %d hits, want 1", len(r.Hits))
+	}
+	want := map[string]string{FieldTitle: "Pasta <mark>carbonara</mark>"}
+	if !mapsEqual(r.Hits[0].Highlights, want) {
+		t.Errorf("highlights = %v, want %v", r.Hits[0].Highlights, want)
+	}
+}
+
+func TestMemoryIndexUpdates(t *testing.T) {
+	idx, loads := newTestIndex(t, fixture)
+	ctx := context.Background()
+	search := func(text string) []string {
+		t.Helper()
+		r, err := idx.Search(ctx, Query{UserID: "u1", Text: text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hitIDs(r)
+	}
+
+	// Documents indexed before the first search are picked up by the load.
+	if got := search("carbonara"); !slices.Equal(got, []string{"a"}) {
+		t.Fatalf("hits = %v, want [a]", got)
+	}
+
+	updated := fixture[0]
+	updated.Title = "Pasta amatriciana"
+	if err := idx.Index(ctx, updated); err != nil {
+		t.Fatal(err)
+	}
+	if got := search("carbonara"); len(got) != 0 {
+		t.Errorf("replaced document still matches its old title: %v", got)
+	}
+	if got := search("amatri"); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("hits = %v, want [a]", got)
+	}
+
+	if err := idx.Remove(ctx, "u1", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if got := search("amatriciana"); len(got) != 0 {
+		t.Errorf("removed document still matches: %v", got)
+	}
+	if err := idx.Remove(ctx, "u1", "missing"); err != nil {
+		t.Errorf("removing an unknown id: %v", err)
+	}
+	if *loads != 1 {
+		t.Errorf("loader ran %d times, want once", *loads)
+	}
+}
+
+func TestMemoryIndexLoadError(t *testing.T) {
+	failure := errors.New("boom")
+	idx := NewMemoryIndex(func(ctx context.Context, userID string) ([]Document, error) {
+		return nil, failure
+	})
+	if _, err := idx.Search(context.Background(), Query{UserID: "u1", Text: "pasta"}); !errors.Is(err, failure) {
+		t.Errorf("err = %v, want %v", err, failure)
+	}
+}
+
+func mapsEqual[V comparable](a, b map[string]V) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
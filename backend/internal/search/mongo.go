@@ -0,0 +1,250 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	DB "lyked-backend/internal/database/mongodb"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const textIndexName = "uploads_text"
+
+// LoadFromMongo is the Loader used in production: it reads a user's uploads.
+func LoadFromMongo(ctx context.Context, userID string) ([]Document, error) {
+	collection, err := DB.GetCollection("uploads")
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID, "deleted_at": nil})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var uploads []model.LykedUploads
+	if err := cursor.All(ctx, &uploads); err != nil {
+		return nil, err
+	}
+	docs := make([]Document, 0, len(uploads))
+	for _, u := range uploads {
+		docs = append(docs, DocumentFromUpload(u))
+	}
+	return docs, nil
+}
+
+// Reindex reloads the given uploads and puts them back into the default
+// index, for changes made outside the upload handlers. Trashed uploads are
+// taken out of it instead. An empty userID reindexes them whoever owns
+// them, for changes made in shared folders.
+func Reindex(ctx context.Context, userID string, ids []bson.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	collection, err := DB.GetCollection("uploads")
+	if err != nil {
+		return err
+	}
+	filter := bson.M{"_id": bson.M{"$in": ids}}
+	if userID != "" {
+		filter["user_id"] = userID
+	}
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var uploads []model.LykedUploads
+	if err := cursor.All(ctx, &uploads); err != nil {
+		return err
+	}
+	for _, u := range uploads {
+		if u.DeletedAt != nil {
+			if err := Default.Remove(ctx, u.UserID, u.ID.Hex()); err != nil {
+				return fmt.Errorf("failed to remove upload %s from the index: %w", u.ID.Hex(), err)
+			}
+			continue
+		}
+		if err := Default.Index(ctx, DocumentFromUpload(u)); err != nil {
+			return fmt.Errorf("failed to index upload %s: %w", u.ID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// MongoTextIndex delegates to a MongoDB text index on the uploads collection.
+// Documents live in Mongo already, so Index and Remove have nothing to do.
+// Mongo's text search has no prefix matching, only whole (stemmed) words.
+type MongoTextIndex struct {
+	collection *mongo.Collection
+}
+
+func NewMongoTextIndex(ctx context.Context) (*MongoTextIndex, error) {
+	collection, err := DB.GetCollection("uploads")
+	if err != nil {
+		return nil, err
+	}
+	index := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "title", Value: "text"},
+			{Key: "description", Value: "text"},
+			{Key: "tags", Value: "text"},
+			{Key: "author", Value: "text"},
+			{Key: "notes.body", Value: "text"},
+			{Key: "annotations.text", Value: "text"},
+		},
+		Options: options.Index().SetName(textIndexName).SetWeights(bson.M{
+			"title":            int(fieldWeights[FieldTitle] * 10),
+			"tags":             int(fieldWeights[FieldTags] * 10),
+			"author":           int(fieldWeights[FieldAuthor] * 10),
+			"description":      int(fieldWeights[FieldDescription] * 10),
+			"notes.body":       int(fieldWeights[FieldNotes] * 10),
+			"annotations.text": int(fieldWeights[FieldNotes] * 10),
+		}),
+	}
+	_, err = collection.Indexes().CreateOne(ctx, index)
+	if isIndexConflict(err) {
+		// A collection has at most one text index, so an older definition
+		// has to go before the current one can be built.
+		if err := collection.Indexes().DropOne(ctx, textIndexName); err != nil {
+			return nil, fmt.Errorf("failed to drop outdated text index: %w", err)
+		}
+		_, err = collection.Indexes().CreateOne(ctx, index)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create text index: %w", err)
+	}
+	return &MongoTextIndex{collection: collection}, nil
+}
+
+func (m *MongoTextIndex) Index(ctx context.Context, doc Document) error { return nil }
+
+func (m *MongoTextIndex) Remove(ctx context.Context, userID, id string) error { return nil }
+
+func (m *MongoTextIndex) Search(ctx context.Context, q Query) (*Result, error) {
+	q.normalize()
+	filter := bson.M{"user_id": q.UserID, "deleted_at": nil}
+	if q.Text != "" {
+		filter["$text"] = bson.M{"$search": q.Text}
+	}
+	if q.Platform != "" {
+		filter["platform"] = q.Platform
+	}
+	if q.Folder != "" {
+		filter["folders"] = q.Folder
+	}
+	if len(q.Tags) > 0 {
+		patterns := make(bson.A, len(q.Tags))
+		for i, tag := range q.Tags {
+			patterns[i] = bson.Regex{Pattern: utils.TagPattern(tag)}
+		}
+		filter["tags"] = bson.M{"$all": patterns}
+	}
+	if q.IDs != nil {
+		ids := make([]bson.ObjectID, 0, len(q.IDs))
+		for _, id := range q.IDs {
+			if oid, err := bson.ObjectIDFromHex(id); err == nil {
+				ids = append(ids, oid)
+			}
+		}
+		filter["_id"] = bson.M{"$in": ids}
+	}
+
+	findOpts := options.Find().SetSkip(int64(q.Offset)).SetLimit(int64(q.Limit))
+	if q.Text != "" {
+		findOpts.SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+			SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "_id", Value: -1}})
+	} else {
+		findOpts.SetSort(bson.D{{Key: "saved_at", Value: -1}, {Key: "_id", Value: -1}})
+	}
+
+	cursor, err := m.collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, fmt.Errorf("text search failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var scored []struct {
+		model.LykedUploads `bson:",inline"`
+		Score              float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &scored); err != nil {
+		return nil, fmt.Errorf("failed to parse search results: %w", err)
+	}
+
+	terms := queryTerms(q.Text)
+	result := &Result{Hits: []Hit{}, Facets: newFacets()}
+	for _, s := range scored {
+		doc := DocumentFromUpload(s.LykedUploads)
+		result.Hits = append(result.Hits, Hit{ID: doc.ID, Score: s.Score, Highlights: highlights(&doc, terms)})
+	}
+
+	total, err := m.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count search results: %w", err)
+	}
+	result.Total = int(total)
+
+	if err := m.facets(ctx, filter, &result.Facets); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (m *MongoTextIndex) facets(ctx context.Context, filter bson.M, facets *Facets) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$facet", Value: bson.M{
+			"tags": bson.A{
+				bson.M{"$unwind": "$tags"},
+				bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+			},
+			"platforms": bson.A{
+				bson.M{"$group": bson.M{"_id": "$platform", "count": bson.M{"$sum": 1}}},
+			},
+		}}},
+	}
+	cursor, err := m.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to compute facets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	type bucket struct {
+		Value string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+	var out []struct {
+		Tags      []bucket `bson:"tags"`
+		Platforms []bucket `bson:"platforms"`
+	}
+	if err := cursor.All(ctx, &out); err != nil {
+		return fmt.Errorf("failed to parse facets: %w", err)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	for _, b := range out[0].Tags {
+		facets.Tags[b.Value] = b.Count
+	}
+	for _, b := range out[0].Platforms {
+		if b.Value != "" {
+			facets.Platforms[b.Value] = b.Count
+		}
+	}
+	return nil
+}
+
+// isIndexConflict reports whether an index with the same name but a
+// different definition already exists (IndexOptionsConflict and
+// IndexKeySpecsConflict).
+func isIndexConflict(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && (cmdErr.Code == 85 || cmdErr.Code == 86)
+}
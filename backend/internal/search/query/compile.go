@@ -0,0 +1,268 @@
+package query
+
+import (
+	"fmt"
+	"lyked-backend/internal/utils"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// textFields are matched by free-text words and phrases.
+var textFields = []string{"title", "description", "author", "tags", "notes.body", "annotations.text"}
+
+var relativeDate = regexp.MustCompile(`^(\d+)([dwmy])$`)
+
+// Compile converts a parsed query into a MongoDB filter over LykedUploads.
+// now anchors relative dates such as "30d" or "this-month". The caller is
+// responsible for adding the user_id scope.
+func Compile(n Node, now time.Time) (bson.M, error) {
+	switch v := n.(type) {
+	case And:
+		if len(v.Children) == 0 {
+			return bson.M{}, nil
+		}
+		parts, err := compileAll(v.Children, now)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$and": parts}, nil
+	case Or:
+		parts, err := compileAll(v.Children, now)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$or": parts}, nil
+	case Not:
+		inner, err := Compile(v.Child, now)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$nor": bson.A{inner}}, nil
+	case Term:
+		return compileTerm(v), nil
+	case Field:
+		return compileField(v, now)
+	}
+	return nil, fmt.Errorf("unsupported query node %T", n)
+}
+
+func compileAll(nodes []Node, now time.Time) (bson.A, error) {
+	parts := make(bson.A, 0, len(nodes))
+	for _, c := range nodes {
+		f, err := Compile(c, now)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, f)
+	}
+	return parts, nil
+}
+
+// compileTerm matches a word at the start of any word in the text fields,
+// or a phrase anywhere in them, ignoring case.
+func compileTerm(t Term) bson.M {
+	pattern := regexp.QuoteMeta(t.Text)
+	if first, _ := utf8.DecodeRuneInString(t.Text); !t.Phrase && (unicode.IsLetter(first) || unicode.IsDigit(first)) {
+		pattern = `\b` + pattern
+	}
+	regex := bson.Regex{Pattern: pattern, Options: "i"}
+	or := make(bson.A, 0, len(textFields))
+	for _, field := range textFields {
+		or = append(or, bson.M{field: regex})
+	}
+	return bson.M{"$or": or}
+}
+
+func compileField(f Field, now time.Time) (bson.M, error) {
+	switch f.Name {
+	case "tag":
+		return bson.M{"tags": bson.Regex{Pattern: utils.TagPattern(utils.NormalizeTag(f.Value))}}, nil
+	case "platform":
+		return bson.M{"platform": strings.ToLower(f.Value)}, nil
+	case "folder":
+		return bson.M{"folders": f.Value}, nil
+	case "author", "title":
+		return bson.M{f.Name: bson.Regex{Pattern: regexp.QuoteMeta(f.Value), Options: "i"}}, nil
+	case "is":
+		switch strings.ToLower(f.Value) {
+		case "favorite":
+			return bson.M{"favorite": true}, nil
+		case "rated":
+			return bson.M{"rating": bson.M{"$gte": 1}}, nil
+		}
+		return nil, fmt.Errorf("unknown value %q for is:, expected favorite or rated", f.Value)
+	case "status":
+		switch status := strings.ToLower(f.Value); status {
+		case "unwatched":
+			return bson.M{"status": bson.M{"$in": bson.A{nil, status}}}, nil
+		case "in_progress", "watched", "archived":
+			return bson.M{"status": status}, nil
+		}
+		return nil, fmt.Errorf("unknown status %q, expected unwatched, in_progress, watched or archived", f.Value)
+	case "rating":
+		return compileRating(f.Value)
+	case "before":
+		start, _, err := period(f.Value, now)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"saved_at": bson.M{"$lt": start}}, nil
+	case "after":
+		_, end, err := period(f.Value, now)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"saved_at": bson.M{"$gt": end}}, nil
+	case "on", "saved":
+		from, to, err := dateRange(f.Value, now)
+		if err != nil {
+			return nil, err
+		}
+		savedAt := bson.M{}
+		if !from.IsZero() {
+			savedAt["$gte"] = from
+		}
+		if !to.IsZero() {
+			savedAt["$lte"] = to
+		}
+		return bson.M{"saved_at": savedAt}, nil
+	}
+	return nil, fmt.Errorf("unknown field %q", f.Name)
+}
+
+// compileRating accepts an exact rating ("4") or a range with either end
+// optional ("4..", "..2", "2..4").
+func compileRating(value string) (bson.M, error) {
+	invalid := fmt.Errorf("invalid rating %q, expected 1-5 or a range such as 4.. or 2..4", value)
+	parse := func(s string) (int, error) {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 5 {
+			return 0, invalid
+		}
+		return n, nil
+	}
+	if !strings.Contains(value, "..") {
+		n, err := parse(value)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"rating": n}, nil
+	}
+	parts := strings.SplitN(value, "..", 2)
+	if parts[0] == "" && parts[1] == "" {
+		return nil, invalid
+	}
+	from, to := 1, 5
+	var err error
+	if parts[0] != "" {
+		if from, err = parse(parts[0]); err != nil {
+			return nil, err
+		}
+	}
+	if parts[1] != "" {
+		if to, err = parse(parts[1]); err != nil {
+			return nil, err
+		}
+	}
+	if to < from {
+		return nil, fmt.Errorf("rating range %q ends before it starts", value)
+	}
+	return bson.M{"rating": bson.M{"$gte": from, "$lte": to}}, nil
+}
+
+// dateRange resolves a date or "from..to" range to an inclusive range on
+// saved_at. A zero bound means that end is open. A single value naming an
+// instant, such as "30d", covers the whole day it falls on.
+func dateRange(value string, now time.Time) (time.Time, time.Time, error) {
+	if !strings.Contains(value, "..") {
+		from, to, err := period(value, now)
+		if err != nil || !from.Equal(to) {
+			return from, to, err
+		}
+		from = from.UTC()
+		day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+		return day, day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	parts := strings.SplitN(value, "..", 2)
+	var from, to time.Time
+	if parts[0] != "" {
+		start, _, err := period(parts[0], now)
+		if err != nil {
+			return from, to, err
+		}
+		from = start
+	}
+	if parts[1] != "" {
+		_, end, err := period(parts[1], now)
+		if err != nil {
+			return from, to, err
+		}
+		to = end
+	}
+	if from.IsZero() && to.IsZero() {
+		return from, to, fmt.Errorf("date range needs at least one end")
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return from, to, fmt.Errorf("date range %q ends before it starts", value)
+	}
+	return from, to, nil
+}
+
+// period resolves a date value to the span of time it names: a day, month
+// or year for calendar dates, or an instant for RFC 3339 and relative values.
+func period(value string, now time.Time) (time.Time, time.Time, error) {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch value {
+	case "today":
+		return day, day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	case "yesterday":
+		y := day.AddDate(0, 0, -1)
+		return y, day.Add(-time.Nanosecond), nil
+	case "this-week":
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7)) // weeks start on Monday
+		return start, start.AddDate(0, 0, 7).Add(-time.Nanosecond), nil
+	case "this-month":
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0).Add(-time.Nanosecond), nil
+	case "this-year":
+		start := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, 0).Add(-time.Nanosecond), nil
+	}
+
+	if m := relativeDate.FindStringSubmatch(value); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		var t time.Time
+		switch m[2] {
+		case "d":
+			t = now.AddDate(0, 0, -n)
+		case "w":
+			t = now.AddDate(0, 0, -7*n)
+		case "m":
+			t = now.AddDate(0, -n, 0)
+		case "y":
+			t = now.AddDate(-n, 0, 0)
+		}
+		return t, t, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	if t, err := time.Parse("2006-01", value); err == nil {
+		return t, t.AddDate(0, 1, 0).Add(-time.Nanosecond), nil
+	}
+	if t, err := time.Parse("2006", value); err == nil {
+		return t, t.AddDate(1, 0, 0).Add(-time.Nanosecond), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD, YYYY-MM, YYYY, today, this-week, this-month, this-year or a relative value like 30d", value)
+}
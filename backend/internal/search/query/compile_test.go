@@ -0,0 +1,57 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestCompileSavedDates(t *testing.T) {
+	now := time.Date(2026, 3, 15, 13, 30, 0, 0, time.UTC)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	endOf := func(t time.Time) time.Time { return t.AddDate(0, 0, 1).Add(-time.Nanosecond) }
+
+	tests := []struct {
+		query    string
+		from, to time.Time // zero means open
+	}{
+		{"on:today", day(2026, 3, 15), endOf(day(2026, 3, 15))},
+		{"on:2026-01-02", day(2026, 1, 2), endOf(day(2026, 1, 2))},
+		{"on:30d", day(2026, 2, 13), endOf(day(2026, 2, 13))},
+		{"saved:1w", day(2026, 3, 8), endOf(day(2026, 3, 8))},
+		{"on:2026-01-02T10:00:00Z", day(2026, 1, 2), endOf(day(2026, 1, 2))},
+		{"saved:30d..", now.AddDate(0, 0, -30), time.Time{}},
+		{"saved:..2026-01", time.Time{}, day(2026, 2, 1).Add(-time.Nanosecond)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			n, err := Parse(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filter, err := Compile(n, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			savedAt, ok := filter["saved_at"].(bson.M)
+			if !ok {
+				t.Fatalf("filter = %v, want a saved_at range", filter)
+			}
+			check := func(op string, want time.Time) {
+				got, ok := savedAt[op].(time.Time)
+				if want.IsZero() {
+					if ok {
+						t.Errorf("%s = %v, want none", op, got)
+					}
+					return
+				}
+				if !got.Equal(want) {
+					t.Errorf("%s = %v, want %v", op, got, want)
+				}
+			}
+			check("$gte", tt.from)
+			check("$lte", tt.to)
+		})
+	}
+}
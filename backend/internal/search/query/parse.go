@@ -0,0 +1,399 @@
+// Package query implements the search box language, e.g.
+//
+//	tag:recipes platform:tiktok before:2026-01-01 -tag:watched "air fryer"
+//
+// Terms are ANDed, OR (upper case) separates alternatives, a leading "-"
+// negates a term or group, and parentheses group.
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Node is a parsed query expression.
+type Node interface{ node() }
+
+type And struct{ Children []Node }
+type Or struct{ Children []Node }
+type Not struct{ Child Node }
+
+// Term is free text: a single word (prefix matched) or a quoted phrase.
+type Term struct {
+	Text   string
+	Phrase bool
+}
+
+// Field is a field operator such as tag:recipes or before:2026-01-01.
+type Field struct {
+	Name  string
+	Value string
+}
+
+func (And) node()   {}
+func (Or) node()    {}
+func (Not) node()   {}
+func (Term) node()  {}
+func (Field) node() {}
+
+// SyntaxError points at the offending character of the query (0-based, in runes).
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// Fields lists the supported operators.
+var Fields = map[string]bool{
+	"tag":      true,
+	"platform": true,
+	"folder":   true,
+	"author":   true,
+	"title":    true,
+	"before":   true,
+	"after":    true,
+	"on":       true,
+	"saved":    true,
+	"is":       true,
+	"status":   true,
+	"rating":   true,
+}
+
+var dateFields = map[string]bool{"before": true, "after": true, "on": true, "saved": true}
+
+// stateFields have a fixed set of values, checked while parsing.
+var stateFields = map[string]bool{"is": true, "status": true, "rating": true}
+
+// Parse turns a query string into an expression tree. An empty query
+// parses to an empty And, which matches everything.
+func Parse(input string) (Node, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, end: len([]rune(input))}
+	if len(tokens) == 0 {
+		return And{}, nil
+	}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t != nil {
+		if t.kind == tokRParen {
+			return nil, &SyntaxError{t.pos, "unexpected ')' without matching '('"}
+		}
+		return nil, &SyntaxError{t.pos, fmt.Sprintf("unexpected %q", t.text)}
+	}
+	return n, nil
+}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokPhrase
+	tokField
+	tokNot
+	tokOr
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind  tokenKind
+	text  string // word/phrase text, or field value
+	field string
+	pos   int
+}
+
+func lex(input string) ([]token, error) {
+	runes := []rune(input)
+	var tokens []token
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case r == '"':
+			text, next, err := readPhrase(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokPhrase, text: text, pos: i})
+			i = next
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			tokens = append(tokens, token{kind: tokNot, text: "-", pos: i})
+			i++
+		default:
+			start := i
+			for i < len(runes) && !isDelimiter(runes[i]) && runes[i] != ':' {
+				i++
+			}
+			if i < len(runes) && runes[i] == ':' && isFieldName(runes[start:i]) && !strings.HasPrefix(string(runes[i+1:]), "//") {
+				tok, next, err := readField(runes, start, i)
+				if err != nil {
+					return nil, err
+				}
+				tokens = append(tokens, tok)
+				i = next
+				continue
+			}
+			for i < len(runes) && !isDelimiter(runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+			if word == "OR" {
+				tokens = append(tokens, token{kind: tokOr, text: word, pos: start})
+			} else {
+				tokens = append(tokens, token{kind: tokWord, text: word, pos: start})
+			}
+		}
+	}
+	return tokens, nil
+}
+
+func isDelimiter(r rune) bool {
+	return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"'
+}
+
+func isFieldName(runes []rune) bool {
+	if len(runes) == 0 {
+		return false
+	}
+	for _, r := range runes {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func readPhrase(runes []rune, start int) (string, int, error) {
+	end := start + 1
+	for end < len(runes) && runes[end] != '"' {
+		end++
+	}
+	if end >= len(runes) {
+		return "", 0, &SyntaxError{start, "unterminated quoted phrase"}
+	}
+	text := strings.TrimSpace(string(runes[start+1 : end]))
+	if text == "" {
+		return "", 0, &SyntaxError{start, "empty quoted phrase"}
+	}
+	return text, end + 1, nil
+}
+
+// readField reads the value after "name:" where colon is the index of ':'.
+func readField(runes []rune, start, colon int) (token, int, error) {
+	name := string(runes[start:colon])
+	if !Fields[name] {
+		return token{}, 0, &SyntaxError{start, fmt.Sprintf("unknown field %q", name)}
+	}
+	i := colon + 1
+	var value string
+	if i < len(runes) && runes[i] == '"' {
+		text, next, err := readPhrase(runes, i)
+		if err != nil {
+			return token{}, 0, err
+		}
+		value, i = text, next
+	} else {
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+			i++
+		}
+		value = string(runes[colon+1 : i])
+	}
+	if value == "" {
+		return token{}, 0, &SyntaxError{colon + 1, fmt.Sprintf("missing value for %s:", name)}
+	}
+	if dateFields[name] {
+		if name != "saved" && strings.Contains(value, "..") {
+			return token{}, 0, &SyntaxError{colon + 1, fmt.Sprintf("date ranges are written saved:from..to, not %s:", name)}
+		}
+		if _, _, err := dateRange(value, time.Now()); err != nil {
+			return token{}, 0, &SyntaxError{colon + 1, err.Error()}
+		}
+	}
+	if stateFields[name] {
+		if _, err := compileField(Field{Name: name, Value: value}, time.Now()); err != nil {
+			return token{}, 0, &SyntaxError{colon + 1, err.Error()}
+		}
+	}
+	return token{kind: tokField, field: name, text: value, pos: start}, i, nil
+}
+
+type parser struct {
+	tokens []token
+	i      int
+	end    int
+}
+
+func (p *parser) peek() *token {
+	if p.i >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.i]
+}
+
+func (p *parser) parseOr() (Node, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []Node{first}
+	for t := p.peek(); t != nil && t.kind == tokOr; t = p.peek() {
+		p.i++
+		if next := p.peek(); next == nil || next.kind == tokOr || next.kind == tokRParen {
+			return nil, &SyntaxError{t.pos, "OR must be followed by a term"}
+		}
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, n)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return Or{Children: children}, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	var children []Node
+	for t := p.peek(); t != nil && t.kind != tokOr && t.kind != tokRParen; t = p.peek() {
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, n)
+	}
+	if len(children) == 0 {
+		pos := p.end
+		if t := p.peek(); t != nil {
+			pos = t.pos
+		}
+		return nil, &SyntaxError{pos, "expected a term"}
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return And{Children: children}, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	t := p.peek()
+	if t.kind != tokNot {
+		return p.parsePrimary()
+	}
+	p.i++
+	if next := p.peek(); next == nil || next.kind == tokOr || next.kind == tokNot {
+		return nil, &SyntaxError{t.pos, "'-' must be followed by a term"}
+	}
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return Not{Child: n}, nil
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	t := p.peek()
+	p.i++
+	switch t.kind {
+	case tokWord:
+		return Term{Text: t.text}, nil
+	case tokPhrase:
+		return Term{Text: t.text, Phrase: true}, nil
+	case tokField:
+		return Field{Name: t.field, Value: t.text}, nil
+	case tokLParen:
+		if next := p.peek(); next != nil && next.kind == tokRParen {
+			return nil, &SyntaxError{t.pos, "empty parentheses"}
+		}
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing == nil || closing.kind != tokRParen {
+			return nil, &SyntaxError{t.pos, "missing closing ')'"}
+		}
+		p.i++
+		return n, nil
+	}
+	return nil, &SyntaxError{t.pos, fmt.Sprintf("unexpected %q", t.text)}
+}
+
+// MapField returns the expression with fn applied to the value of every
+// field operator with the given name, e.g. to resolve tag aliases.
+func MapField(n Node, name string, fn func(string) string) Node {
+	switch v := n.(type) {
+	case And:
+		children := make([]Node, len(v.Children))
+		for i, c := range v.Children {
+			children[i] = MapField(c, name, fn)
+		}
+		return And{Children: children}
+	case Or:
+		children := make([]Node, len(v.Children))
+		for i, c := range v.Children {
+			children[i] = MapField(c, name, fn)
+		}
+		return Or{Children: children}
+	case Not:
+		return Not{Child: MapField(v.Child, name, fn)}
+	case Field:
+		if v.Name == name {
+			v.Value = fn(v.Value)
+		}
+		return v
+	}
+	return n
+}
+
+// FreeText returns the words and phrases that every match must contain,
+// i.e. the positive terms not nested under OR or negation. Search uses
+// them for ranking and highlighting.
+func FreeText(n Node) string {
+	var parts []string
+	switch v := n.(type) {
+	case Term:
+		parts = append(parts, v.Text)
+	case And:
+		for _, c := range v.Children {
+			if t, ok := c.(Term); ok {
+				parts = append(parts, t.Text)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// IsPlainText reports whether the query is only bare words, which the
+// search index can answer on its own.
+func IsPlainText(n Node) bool {
+	switch v := n.(type) {
+	case Term:
+		return !v.Phrase
+	case And:
+		for _, c := range v.Children {
+			if t, ok := c.(Term); !ok || t.Phrase {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
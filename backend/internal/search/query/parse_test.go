@@ -0,0 +1,154 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	word := func(s string) Term { return Term{Text: s} }
+	tests := []struct {
+		query string
+		want  Node
+	}{
+		{"", And{}},
+		{"   ", And{}},
+		{"pasta", word("pasta")},
+		{"air fryer", And{Children: []Node{word("air"), word("fryer")}}},
+		{`"air fryer"`, Term{Text: "air fryer", Phrase: true}},
+		{`"  air fryer  " chips`, And{Children: []Node{Term{Text: "air fryer", Phrase: true}, word("chips")}}},
+		{"tag:recipes", Field{Name: "tag", Value: "recipes"}},
+		{`title:"air fryer"`, Field{Name: "title", Value: "air fryer"}},
+
+		// AND binds tighter than OR.
+		{"a b OR c", Or{Children: []Node{And{Children: []Node{word("a"), word("b")}}, word("c")}}},
+		{"a OR b c", Or{Children: []Node{word("a"), And{Children: []Node{word("b"), word("c")}}}}},
+		{"a OR b OR c", Or{Children: []Node{word("a"), word("b"), word("c")}}},
+		{"a or b", And{Children: []Node{word("a"), word("or"), word("b")}}}, // only upper-case OR
+
+		// Negation applies to the next term or group.
+		{"-tag:watched", Not{Child: Field{Name: "tag", Value: "watched"}}},
+		{"pasta -tag:watched", And{Children: []Node{word("pasta"), Not{Child: Field{Name: "tag", Value: "watched"}}}}},
+		{`-"deep fried"`, Not{Child: Term{Text: "deep fried", Phrase: true}}},
+		{"-(a OR b)", Not{Child: Or{Children: []Node{word("a"), word("b")}}}},
+		{"a - b", And{Children: []Node{word("a"), word("-"), word("b")}}}, // a lone dash is a word
+		{"well-known", word("well-known")},
+
+		// Parentheses group.
+		{"(a OR b) c", And{Children: []Node{Or{Children: []Node{word("a"), word("b")}}, word("c")}}},
+		{"a (b OR (c d))", And{Children: []Node{word("a"), Or{Children: []Node{word("b"), And{Children: []Node{word("c"), word("d")}}}}}}},
+		{"(tag:a)", Field{Name: "tag", Value: "a"}},
+		{"(tag:a)(tag:b)", And{Children: []Node{Field{Name: "tag", Value: "a"}, Field{Name: "tag", Value: "b"}}}},
+
+		// Words that only look like fields.
+		{"https://example.com/x", word("https://example.com/x")},
+		{"Tag:food", word("Tag:food")},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) =\n %#v\nwant\n %#v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{`"air fryer`, 0, "unterminated quoted phrase"},
+		{`pasta ""`, 6, "empty quoted phrase"},
+		{"flavor:sweet", 0, `unknown field "flavor"`},
+		{"pasta tag:", 10, "missing value for tag:"},
+		{`title:"oops`, 6, "unterminated quoted phrase"},
+		{"before:someday", 7, ""},
+		{"on:2026-01-01..2026-02-01", 3, "date ranges are written saved:from..to"},
+		{"is:sleepy", 3, ""},
+		{"rating:9", 7, ""},
+		{"(a OR b", 0, "missing closing ')'"},
+		{"a b)", 3, "unexpected ')' without matching '('"},
+		{"()", 0, "empty parentheses"},
+		{"a OR", 2, "OR must be followed by a term"},
+		{"a OR OR b", 2, "OR must be followed by a term"},
+		{"(a OR) b", 3, "OR must be followed by a term"},
+		{"OR a", 0, "expected a term"},
+		{"--a", 0, "'-' must be followed by a term"},
+		{"-OR a", 0, "'-' must be followed by a term"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Parse(%q) = %v, want a syntax error", tt.query, err)
+			continue
+		}
+		if syntaxErr.Pos != tt.pos || !strings.Contains(syntaxErr.Msg, tt.msg) {
+			t.Errorf("Parse(%q) = %v, want position %d and %q", tt.query, syntaxErr, tt.pos, tt.msg)
+		}
+	}
+}
+
+// TestParseErrorPositionsInRunes checks positions count characters, not
+// bytes.
+func TestParseErrorPositionsInRunes(t *testing.T) {
+	_, err := Parse("crème brûlée)")
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Pos != 12 {
+		t.Errorf("Parse = %v, want a syntax error at 12", err)
+	}
+	if got := syntaxErr.Error(); got != `syntax error at position 12: unexpected ')' without matching '('` {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestMapField(t *testing.T) {
+	n, err := Parse("tag:Food (tag:bake OR -tag:fry) title:tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := MapField(n, "tag", strings.ToUpper)
+	want := And{Children: []Node{
+		Field{Name: "tag", Value: "FOOD"},
+		Or{Children: []Node{Field{Name: "tag", Value: "BAKE"}, Not{Child: Field{Name: "tag", Value: "FRY"}}}},
+		Field{Name: "title", Value: "tag"},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MapField = %#v", got)
+	}
+}
+
+func TestFreeTextAndPlainText(t *testing.T) {
+	tests := []struct {
+		query string
+		free  string
+		plain bool
+	}{
+		{"pasta", "pasta", true},
+		{"air fryer", "air fryer", true},
+		{`"air fryer" chips`, "air fryer chips", false},
+		{"pasta tag:dinner -spicy", "pasta", false},
+		{"pasta OR pizza", "", false},
+		{"-pasta", "", false},
+		{"tag:dinner", "", false},
+	}
+	for _, tt := range tests {
+		n, err := Parse(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := FreeText(n); got != tt.free {
+			t.Errorf("FreeText(%q) = %q, want %q", tt.query, got, tt.free)
+		}
+		if got := IsPlainText(n); got != tt.plain {
+			t.Errorf("IsPlainText(%q) = %v, want %v", tt.query, got, tt.plain)
+		}
+	}
+}
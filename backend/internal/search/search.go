@@ -0,0 +1,198 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/notes"
+	"lyked-backend/internal/utils"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Searchable fields, also used as keys in Hit.Highlights.
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldTags        = "tags"
+	FieldAuthor      = "author"
+	FieldNotes       = "notes"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// fieldWeights decides how much a match in each field counts towards relevance.
+var fieldWeights = map[string]float64{
+	FieldTitle:       3,
+	FieldTags:        2.5,
+	FieldAuthor:      2,
+	FieldDescription: 1,
+	FieldNotes:       1,
+}
+
+// Document is the searchable view of a saved item.
+type Document struct {
+	ID          string
+	UserID      string
+	Title       string
+	Description string
+	Author      string
+	Notes       string
+	Tags        []string
+	Platform    string
+	Folders     []string
+	SavedAt     time.Time
+}
+
+// Query is a free-text search within one user's vault, optionally narrowed by facets.
+type Query struct {
+	UserID   string
+	Text     string
+	Tags     []string
+	Platform string
+	Folder   string
+	// IDs, when non-nil, restricts results to these documents. The search
+	// language uses it to apply filters the index can't evaluate itself.
+	IDs    []string
+	Limit  int
+	Offset int
+
+	idSet map[string]bool
+}
+
+type Hit struct {
+	ID         string            `json:"id"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+type Facets struct {
+	Tags      map[string]int `json:"tags"`
+	Platforms map[string]int `json:"platforms"`
+}
+
+type Result struct {
+	Hits   []Hit  `json:"hits"`
+	Total  int    `json:"total"`
+	Facets Facets `json:"facets"`
+}
+
+// SearchIndex is implemented by every search backend.
+type SearchIndex interface {
+	// Index adds or replaces a document.
+	Index(ctx context.Context, doc Document) error
+	// Remove drops a document; removing an unknown id is not an error.
+	Remove(ctx context.Context, userID, id string) error
+	Search(ctx context.Context, q Query) (*Result, error)
+}
+
+// Default is the index used by the HTTP handlers. It is set up by InitServer.
+var Default SearchIndex
+
+// Init sets Default to the backend named by SEARCH_BACKEND ("memory" or "mongo").
+func Init(ctx context.Context, backend string) error {
+	switch backend {
+	case "", "memory":
+		Default = NewMemoryIndex(LoadFromMongo)
+	case "mongo":
+		idx, err := NewMongoTextIndex(ctx)
+		if err != nil {
+			return err
+		}
+		Default = idx
+	default:
+		return fmt.Errorf("unknown search backend %q", backend)
+	}
+	return nil
+}
+
+func DocumentFromUpload(u model.LykedUploads) Document {
+	return Document{
+		ID:          u.ID.Hex(),
+		UserID:      u.UserID,
+		Title:       u.Title,
+		Description: u.Description,
+		Author:      u.Author,
+		Notes:       notes.Text(u),
+		Tags:        u.Tags,
+		Platform:    u.Platform,
+		Folders:     u.Folders,
+		SavedAt:     u.SavedAt,
+	}
+}
+
+func (d *Document) fields() map[string]string {
+	return map[string]string{
+		FieldTitle:       d.Title,
+		FieldDescription: d.Description,
+		FieldAuthor:      d.Author,
+		FieldNotes:       d.Notes,
+		FieldTags:        strings.Join(d.Tags, " "),
+	}
+}
+
+func (q *Query) normalize() {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	for i, tag := range q.Tags {
+		q.Tags[i] = utils.NormalizeTag(tag)
+	}
+	if q.IDs != nil {
+		q.idSet = make(map[string]bool, len(q.IDs))
+		for _, id := range q.IDs {
+			q.idSet[id] = true
+		}
+	}
+}
+
+// matchesFacets reports whether doc passes the query's facet filters.
+func (q *Query) matchesFacets(d *Document) bool {
+	if q.idSet != nil && !q.idSet[d.ID] {
+		return false
+	}
+	if q.Platform != "" && d.Platform != q.Platform {
+		return false
+	}
+	if q.Folder != "" && !contains(d.Folders, q.Folder) {
+		return false
+	}
+	for _, tag := range q.Tags {
+		if !slices.ContainsFunc(d.Tags, func(t string) bool { return utils.TagWithin(t, tag) }) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *Facets) add(d *Document) {
+	for _, tag := range d.Tags {
+		f.Tags[tag]++
+	}
+	if d.Platform != "" {
+		f.Platforms[d.Platform]++
+	}
+}
+
+func newFacets() Facets {
+	return Facets{Tags: map[string]int{}, Platforms: map[string]int{}}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	minPrefixLen = 2
+	snippetWidth = 160
+	markOpen     = "<mark>"
+	markClose    = "</mark>"
+)
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "is": true,
+	"it": true, "of": true, "on": true, "or": true, "the": true, "to": true,
+	"with": true,
+}
+
+type token struct {
+	term       string
+	start, end int // byte offsets into the original text
+}
+
+// tokenize splits text into lower-cased words, keeping their offsets so
+// matches can be highlighted in the original string.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return tokens
+}
+
+// queryTerms returns the distinct, non-stop-word terms of a search string.
+func queryTerms(text string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, t := range tokenize(text) {
+		if stopWords[t.term] || seen[t.term] {
+			continue
+		}
+		seen[t.term] = true
+		terms = append(terms, t.term)
+	}
+	return terms
+}
+
+// matchesTerm reports whether an indexed word satisfies a query term,
+// either exactly or as a prefix of the word.
+func matchesTerm(word, term string) bool {
+	if word == term {
+		return true
+	}
+	return len(term) >= minPrefixLen && strings.HasPrefix(word, term)
+}
+
+// Highlight returns a snippet of text around the first matched term with
+// every match wrapped in <mark> tags. The text itself is HTML-escaped, so
+// the marks are the only markup in the snippet. It returns "" when nothing
+// matches.
+func Highlight(text string, terms []string) string {
+	tokens := tokenize(text)
+	var matched []token
+	for _, tok := range tokens {
+		for _, term := range terms {
+			if matchesTerm(tok.term, term) {
+				matched = append(matched, tok)
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return ""
+	}
+
+	from, to := snippetBounds(text, matched[0].start)
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, m := range matched {
+		if m.start < from || m.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:m.start]))
+		b.WriteString(markOpen)
+		b.WriteString(html.EscapeString(text[m.start:m.end]))
+		b.WriteString(markClose)
+		pos = m.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// snippetBounds picks a window of roughly snippetWidth bytes around offset,
+// widened to whole words so the snippet never cuts a word or rune in half.
+func snippetBounds(text string, offset int) (int, int) {
+	if len(text) <= snippetWidth {
+		return 0, len(text)
+	}
+	from := offset - snippetWidth/4
+	if from < 0 {
+		from = 0
+	}
+	to := from + snippetWidth
+	if to > len(text) {
+		to = len(text)
+	}
+	for from > 0 && !isBoundary(text, from) {
+		from--
+	}
+	for to < len(text) && !isBoundary(text, to) {
+		to++
+	}
+	return from, to
+}
+
+func isBoundary(text string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(text[i:])
+	return unicode.IsSpace(r)
+}
+
+// highlights builds the per-field snippets for a document.
+func highlights(d *Document, terms []string) map[string]string {
+	out := map[string]string{}
+	for field, text := range d.fields() {
+		if snippet := Highlight(text, terms); snippet != "" {
+			out[field] = snippet
+		}
+	}
+	return out
+}
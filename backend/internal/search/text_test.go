@@ -0,0 +1,107 @@
+package search
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []token
+	}{
+		{"", nil},
+		{"Hello", []token{{"hello", 0, 5}}},
+		{"  Sourdough, bread!", []token{{"sourdough", 2, 11}, {"bread", 13, 18}}},
+		{"v2 of GO-lang", []token{{"v2", 0, 2}, {"of", 3, 5}, {"go", 6, 8}, {"lang", 9, 13}}},
+		{"Crème brûlée", []token{{"crème", 0, 6}, {"brûlée", 7, 15}}},
+	}
+	for _, tt := range tests {
+		if got := tokenize(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("tokenize(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestQueryTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"the best of the pasta", []string{"best", "pasta"}},
+		{"Pasta pasta PASTA", []string{"pasta"}},
+		{"a an the", nil},
+	}
+	for _, tt := range tests {
+		if got := queryTerms(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("queryTerms(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesTerm(t *testing.T) {
+	tests := []struct {
+		word, term string
+		want       bool
+	}{
+		{"pasta", "pasta", true},
+		{"pasta", "pas", true},
+		{"pasta", "p", false}, // too short to be a prefix
+		{"p", "p", true},
+		{"pasta", "pastas", false},
+		{"pasta", "asta", false},
+	}
+	for _, tt := range tests {
+		if got := matchesTerm(tt.word, tt.term); got != tt.want {
+			t.Errorf("matchesTerm(%q, %q) = %v, want %v", tt.word, tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{"no match", "Fresh pasta", []string{"bread"}, ""},
+		{"exact", "Fresh pasta", []string{"pasta"}, "Fresh <mark>pasta</mark>"},
+		{"prefix", "Fresh pasta", []string{"pas"}, "Fresh <mark>pasta</mark>"},
+		{"keeps case", "PASTA night", []string{"pasta"}, "<mark>PASTA</mark> night"},
+		{"every match", "pasta and more pasta", []string{"pasta"}, "<mark>pasta</mark> and more <mark>pasta</mark>"},
+		{
+			"escapes markup",
+			`<script>alert("pasta")</script> & pasta`,
+			[]string{"pasta"},
+			`&lt;script&gt;alert(&#34;<mark>pasta</mark>&#34;)&lt;/script&gt; &amp; <mark>pasta</mark>`,
+		},
+		{"escapes a matched word's neighbours", "<b>pasta</b>", []string{"pasta"}, "&lt;b&gt;<mark>pasta</mark>&lt;/b&gt;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Highlight(tt.text, tt.terms); got != tt.want {
+				t.Errorf("Highlight(%q, %v) = %q, want %q", tt.text, tt.terms, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	long := strings.Repeat("filler ", 40)
+	text := long + "pasta " + long
+	got := Highlight(text, []string{"pasta"})
+	if got == "" || len(got) > snippetWidth+64 {
+		t.Fatalf("Highlight returned a %d byte snippet, want about %d", len(got), snippetWidth)
+	}
+	for _, want := range []string{"…", "<mark>pasta</mark>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("snippet %q is missing %q", got, want)
+		}
+	}
+	if !strings.HasSuffix(got, "…") {
+		t.Errorf("snippet %q should end with an ellipsis", got)
+	}
+}
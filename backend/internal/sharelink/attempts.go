@@ -0,0 +1,84 @@
+package sharelink
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// attemptWindow is how long wrong passwords are remembered.
+	attemptWindow = 15 * time.Minute
+	// maxTokenAttempts wrong passwords for one link lock it for the rest
+	// of the window, from any address.
+	maxTokenAttempts = 10
+	// maxClientAttempts wrong passwords from one address lock it out of
+	// every link for the rest of the window.
+	maxClientAttempts = 30
+	// sweepSize is how many keys attempts holds before it drops the
+	// expired ones.
+	sweepSize = 10000
+)
+
+// attempts counts wrong passwords per link and per client address, so a
+// protected link can't be brute-forced through the bcrypt check.
+type attempts struct {
+	mu      sync.Mutex
+	windows map[string]*attemptCount // "token:" or "client:" key -> failures
+}
+
+type attemptCount struct {
+	failures int
+	start    time.Time
+}
+
+var passwordAttempts = &attempts{windows: map[string]*attemptCount{}}
+
+// blocked reports whether the link or the client used up its attempts.
+func (a *attempts) blocked(token, client string, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.count("token:"+token, now) >= maxTokenAttempts ||
+		(client != "" && a.count("client:"+client, now) >= maxClientAttempts)
+}
+
+// fail records a wrong password for the link from the client.
+func (a *attempts) fail(token, client string, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.windows) >= sweepSize {
+		for key, w := range a.windows {
+			if now.Sub(w.start) >= attemptWindow {
+				delete(a.windows, key)
+			}
+		}
+	}
+	keys := []string{"token:" + token}
+	if client != "" {
+		keys = append(keys, "client:"+client)
+	}
+	for _, key := range keys {
+		w := a.windows[key]
+		if w == nil || now.Sub(w.start) >= attemptWindow {
+			w = &attemptCount{start: now}
+			a.windows[key] = w
+		}
+		w.failures++
+	}
+}
+
+// succeed forgets the link's wrong passwords once the right one is given.
+func (a *attempts) succeed(token string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.windows, "token:"+token)
+}
+
+// count returns the failures for key in the current window. The caller
+// holds the lock.
+func (a *attempts) count(key string, now time.Time) int {
+	w := a.windows[key]
+	if w == nil || now.Sub(w.start) >= attemptWindow {
+		return 0
+	}
+	return w.failures
+}
@@ -0,0 +1,66 @@
+package sharelink
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAttemptsPerToken(t *testing.T) {
+	a := &attempts{windows: map[string]*attemptCount{}}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i := range maxTokenAttempts {
+		if a.blocked("tok", "", now) {
+			t.Fatalf("blocked after %d failures", i)
+		}
+		// Spread over addresses, so only the per-link limit applies.
+		a.fail("tok", string(rune('a'+i)), now)
+	}
+	if !a.blocked("tok", "z", now) {
+		t.Fatal("link not blocked after the limit")
+	}
+	if a.blocked("other", "z", now) {
+		t.Fatal("another link is blocked")
+	}
+	if a.blocked("tok", "z", now.Add(attemptWindow)) {
+		t.Fatal("link still blocked after the window")
+	}
+}
+
+func TestAttemptsPerClient(t *testing.T) {
+	a := &attempts{windows: map[string]*attemptCount{}}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i := range maxClientAttempts {
+		a.fail(string(rune('A'+i)), "1.2.3.4", now)
+	}
+	if !a.blocked("fresh", "1.2.3.4", now) {
+		t.Fatal("client not blocked after the limit")
+	}
+	if a.blocked("fresh", "5.6.7.8", now) {
+		t.Fatal("another client is blocked")
+	}
+}
+
+func TestAttemptsSucceedResetsToken(t *testing.T) {
+	a := &attempts{windows: map[string]*attemptCount{}}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for range maxTokenAttempts - 1 {
+		a.fail("tok", "", now)
+	}
+	a.succeed("tok")
+	a.fail("tok", "", now)
+	if a.blocked("tok", "", now) {
+		t.Fatal("failures before a success still count")
+	}
+}
+
+func TestAttemptsSweep(t *testing.T) {
+	a := &attempts{windows: map[string]*attemptCount{}}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i := range sweepSize {
+		a.windows[string(rune(i))] = &attemptCount{failures: 1, start: now}
+	}
+	a.fail("tok", "", now.Add(attemptWindow))
+	if len(a.windows) != 1 {
+		t.Fatalf("got %d keys after the sweep, want 1", len(a.windows))
+	}
+}
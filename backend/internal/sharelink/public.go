@@ -0,0 +1,219 @@
+package sharelink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// PublicItem is what a share link shows of an item: the saved link and
+// what describes it, without who saved it or anything they added privately.
+type PublicItem struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	VideoLink   string    `json:"video_link"`
+	Platform    string    `json:"platform"`
+	Author      string    `json:"author"`
+	Thumbnail   string    `json:"thumbnail,omitempty"`
+	Tags        []string  `json:"tags"`
+	SavedAt     time.Time `json:"saved_at"`
+}
+
+type PublicFolder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// FolderView is one page of a shared folder, or of one of its subfolders.
+type FolderView struct {
+	Folder      PublicFolder   `json:"folder"`
+	Breadcrumbs []PublicFolder `json:"breadcrumbs"` // from the shared folder down to this one
+	Subfolders  []PublicFolder `json:"subfolders"`
+	Items       []PublicItem   `json:"items"`
+	NextCursor  string         `json:"next_cursor,omitempty"`
+	HasMore     bool           `json:"has_more"`
+}
+
+// Item returns the item a link points at. Items that were trashed since
+// are reported as not found.
+func (s *Store) Item(ctx context.Context, link *model.ShareLink) (*PublicItem, error) {
+	var upload model.LykedUploads
+	err := s.Uploads.FindOne(ctx, bson.M{"_id": link.TargetID, "user_id": link.UserID, "deleted_at": nil}).Decode(&upload)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch upload: %w", err)
+	}
+	item := publicItem(upload)
+	return &item, nil
+}
+
+// Folder returns a page of the shared folder in its manual order, or of
+// the subfolder rawFolderID below it. Smart subfolders aren't shown. The
+// link stops working once its creator no longer owns the folder.
+func (s *Store) Folder(ctx context.Context, link *model.ShareLink, rawFolderID, rawCursor string, limit int) (*FolderView, error) {
+	var root model.Folder
+	err := s.Folders.FindOne(ctx, bson.M{"_id": link.TargetID, "deleted_at": nil}).Decode(&root)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch folder: %w", err)
+	}
+	role, err := library.FolderRole(ctx, s.Folders, &root, link.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if role != model.RoleOwner {
+		return nil, ErrLinkNotFound
+	}
+
+	current := &root
+	if rawFolderID != "" && rawFolderID != root.ID.Hex() {
+		if current, err = s.subfolder(ctx, &root, rawFolderID); err != nil {
+			return nil, err
+		}
+	}
+	crumbs, err := s.crumbs(ctx, &root, current)
+	if err != nil {
+		return nil, err
+	}
+	subfolders, err := s.children(ctx, current)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := library.ListOptions{
+		AnyOwner:  true,
+		Folder:    current.ID.Hex(),
+		Sort:      library.SortPosition,
+		Ascending: true,
+		Limit:     limit,
+	}
+	if rawCursor != "" {
+		cursor, err := library.DecodeCursor(rawCursor)
+		if err != nil {
+			return nil, err
+		}
+		opts.Cursor = &cursor
+	}
+	page, err := library.List(ctx, s.Uploads, opts)
+	if err != nil {
+		return nil, err
+	}
+	view := &FolderView{
+		Folder:      publicFolder(current),
+		Breadcrumbs: crumbs,
+		Subfolders:  subfolders,
+		Items:       make([]PublicItem, 0, len(page.Uploads)),
+		NextCursor:  page.NextCursor,
+		HasMore:     page.HasMore,
+	}
+	for _, u := range page.Uploads {
+		view.Items = append(view.Items, publicItem(u))
+	}
+	return view, nil
+}
+
+// subfolder finds a live static folder under root.
+func (s *Store) subfolder(ctx context.Context, root *model.Folder, rawID string) (*model.Folder, error) {
+	id, err := bson.ObjectIDFromHex(rawID)
+	if err != nil {
+		return nil, library.ErrFolderNotFound
+	}
+	var folder model.Folder
+	err = s.Folders.FindOne(ctx, bson.M{"$and": bson.A{
+		library.SubtreeMatch(root),
+		bson.M{"_id": id, "kind": bson.M{"$ne": model.FolderKindSmart}, "deleted_at": nil},
+	}}).Decode(&folder)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, library.ErrFolderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch folder: %w", err)
+	}
+	return &folder, nil
+}
+
+// crumbs returns the folders from root down to current.
+func (s *Store) crumbs(ctx context.Context, root, current *model.Folder) ([]PublicFolder, error) {
+	crumbs := []PublicFolder{publicFolder(root)}
+	if current.ID == root.ID {
+		return crumbs, nil
+	}
+	between := strings.TrimPrefix(current.AncestorPath(), root.ChildPath())
+	var ids []bson.ObjectID
+	for _, raw := range strings.Split(strings.Trim(between, "/"), "/") {
+		if id, err := bson.ObjectIDFromHex(raw); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > 0 {
+		cursor, err := s.Folders.Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "user_id": root.UserID},
+			options.Find().SetProjection(bson.M{"name": 1, "path": 1}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch parent folders: %w", err)
+		}
+		var ancestors []model.Folder
+		if err := cursor.All(ctx, &ancestors); err != nil {
+			return nil, fmt.Errorf("failed to parse parent folders: %w", err)
+		}
+		sort.Slice(ancestors, func(i, j int) bool { return len(ancestors[i].Path) < len(ancestors[j].Path) })
+		for i := range ancestors {
+			crumbs = append(crumbs, publicFolder(&ancestors[i]))
+		}
+	}
+	return append(crumbs, publicFolder(current)), nil
+}
+
+func (s *Store) children(ctx context.Context, folder *model.Folder) ([]PublicFolder, error) {
+	cursor, err := s.Folders.Find(ctx,
+		bson.M{"parent_id": folder.ID.Hex(), "user_id": folder.UserID, "kind": bson.M{"$ne": model.FolderKindSmart}, "deleted_at": nil},
+		options.Find().SetSort(bson.D{{Key: "name", Value: 1}}).SetProjection(bson.M{"name": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subfolders: %w", err)
+	}
+	var found []model.Folder
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, fmt.Errorf("failed to parse subfolders: %w", err)
+	}
+	subfolders := make([]PublicFolder, len(found))
+	for i := range found {
+		subfolders[i] = publicFolder(&found[i])
+	}
+	return subfolders, nil
+}
+
+func publicFolder(f *model.Folder) PublicFolder {
+	return PublicFolder{ID: f.ID.Hex(), Name: f.Name}
+}
+
+func publicItem(u model.LykedUploads) PublicItem {
+	tags := u.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return PublicItem{
+		ID:          u.ID.Hex(),
+		Title:       u.Title,
+		Description: u.Description,
+		VideoLink:   u.VideoLink,
+		Platform:    u.Platform,
+		Author:      u.Author,
+		Thumbnail:   utils.ThumbnailURL(u.VideoLink),
+		Tags:        tags,
+		SavedAt:     u.SavedAt,
+	}
+}
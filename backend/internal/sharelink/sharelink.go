@@ -0,0 +1,226 @@
+// Package sharelink creates public, read-only links to folders and items
+// and serves what they point at to people without an account.
+package sharelink
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// tokenBytes of randomness make a token unguessable.
+const tokenBytes = 24
+
+var (
+	ErrLinkNotFound     = errors.New("share link not found")
+	ErrLinkGone         = errors.New("this share link has expired or was revoked")
+	ErrPasswordRequired = errors.New("this share link needs a password")
+	ErrWrongPassword    = errors.New("wrong password")
+	ErrTooManyAttempts  = errors.New("too many wrong passwords, try again later")
+	ErrNoTarget         = errors.New("exactly one of folder_id and upload_id is required")
+	ErrExpiryPast       = errors.New("expires_at must be in the future")
+	ErrUploadNotFound   = errors.New("upload not found")
+)
+
+// Store bundles the collections share links work with.
+type Store struct {
+	Links   *mongo.Collection
+	Uploads *mongo.Collection
+	Folders *mongo.Collection
+}
+
+// CreateRequest describes a new link to a folder or to a single item.
+type CreateRequest struct {
+	FolderID  string     `json:"folder_id"`
+	UploadID  string     `json:"upload_id"`
+	Password  string     `json:"password"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Create makes a link. Folders can be shared by their owners, items by
+// whoever saved them.
+func (s *Store) Create(ctx context.Context, userID string, req CreateRequest, now time.Time) (*model.ShareLink, error) {
+	if (req.FolderID == "") == (req.UploadID == "") {
+		return nil, ErrNoTarget
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, ErrExpiryPast
+	}
+	link := &model.ShareLink{ID: bson.NewObjectID(), UserID: userID, ExpiresAt: req.ExpiresAt, CreatedAt: now}
+	if req.FolderID != "" {
+		folder, _, err := library.AccessFolder(ctx, s.Folders, userID, req.FolderID, model.RoleOwner)
+		if err != nil {
+			return nil, err
+		}
+		if folder.IsSmart() {
+			return nil, library.ErrShareSmart
+		}
+		link.Kind, link.TargetID = model.ShareKindFolder, folder.ID
+	} else {
+		upload, err := s.ownUpload(ctx, userID, req.UploadID)
+		if err != nil {
+			return nil, err
+		}
+		link.Kind, link.TargetID = model.ShareKindUpload, upload.ID
+	}
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		link.PasswordHash, link.Protected = string(hash), true
+	}
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	link.Token = token
+	if _, err := s.Links.InsertOne(ctx, link); err != nil {
+		return nil, fmt.Errorf("failed to save share link: %w", err)
+	}
+	return link, nil
+}
+
+// List returns the links the user created, newest first. With a folder the
+// user owns, it returns every link to that folder instead; with an upload,
+// the user's links to it.
+func (s *Store) List(ctx context.Context, userID, rawFolderID, rawUploadID string) ([]model.ShareLink, error) {
+	filter := bson.M{"user_id": userID}
+	if rawFolderID != "" {
+		folder, _, err := library.AccessFolder(ctx, s.Folders, userID, rawFolderID, model.RoleOwner)
+		if err != nil {
+			return nil, err
+		}
+		filter = bson.M{"kind": model.ShareKindFolder, "target_id": folder.ID}
+	} else if rawUploadID != "" {
+		id, err := bson.ObjectIDFromHex(rawUploadID)
+		if err != nil {
+			return nil, ErrUploadNotFound
+		}
+		filter["kind"], filter["target_id"] = model.ShareKindUpload, id
+	}
+	cursor, err := s.Links.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch share links: %w", err)
+	}
+	links := []model.ShareLink{}
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, fmt.Errorf("failed to parse share links: %w", err)
+	}
+	return links, nil
+}
+
+// Revoke turns a link off for good. Its creator can, and so can the owners
+// of the folder it points at.
+func (s *Store) Revoke(ctx context.Context, userID, rawID string, now time.Time) (*model.ShareLink, error) {
+	id, err := bson.ObjectIDFromHex(rawID)
+	if err != nil {
+		return nil, ErrLinkNotFound
+	}
+	var link model.ShareLink
+	err = s.Links.FindOne(ctx, bson.M{"_id": id}).Decode(&link)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch share link: %w", err)
+	}
+	if link.UserID != userID {
+		if link.Kind != model.ShareKindFolder {
+			return nil, ErrLinkNotFound
+		}
+		if _, _, err := library.AccessFolder(ctx, s.Folders, userID, link.TargetID.Hex(), model.RoleOwner); err != nil {
+			if errors.Is(err, library.ErrFolderNotFound) || errors.Is(err, library.ErrForbidden) {
+				return nil, ErrLinkNotFound
+			}
+			return nil, err
+		}
+	}
+	if link.RevokedAt == nil {
+		if _, err := s.Links.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"revoked_at": now}}); err != nil {
+			return nil, fmt.Errorf("failed to revoke share link: %w", err)
+		}
+		link.RevokedAt = &now
+	}
+	return &link, nil
+}
+
+// Open finds the live link for a token and checks its password. Wrong
+// passwords are counted per link and per client address; past the limit,
+// passwords aren't checked until the window runs out.
+func (s *Store) Open(ctx context.Context, token, password, client string, now time.Time) (*model.ShareLink, error) {
+	if token == "" {
+		return nil, ErrLinkNotFound
+	}
+	var link model.ShareLink
+	err := s.Links.FindOne(ctx, bson.M{"token": token}).Decode(&link)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch share link: %w", err)
+	}
+	if link.RevokedAt != nil || (link.ExpiresAt != nil && !link.ExpiresAt.After(now)) {
+		return nil, ErrLinkGone
+	}
+	if link.Protected {
+		if password == "" {
+			return nil, ErrPasswordRequired
+		}
+		if passwordAttempts.blocked(token, client, now) {
+			return nil, ErrTooManyAttempts
+		}
+		if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+			passwordAttempts.fail(token, client, now)
+			return nil, ErrWrongPassword
+		}
+		passwordAttempts.succeed(token)
+	}
+	return &link, nil
+}
+
+// RecordView counts a visit to the link.
+func (s *Store) RecordView(ctx context.Context, link *model.ShareLink, now time.Time) error {
+	_, err := s.Links.UpdateOne(ctx, bson.M{"_id": link.ID},
+		bson.M{"$inc": bson.M{"views": 1}, "$set": bson.M{"last_viewed_at": now}})
+	if err != nil {
+		return fmt.Errorf("failed to count view: %w", err)
+	}
+	link.Views++
+	return nil
+}
+
+// ownUpload returns one of the user's live uploads.
+func (s *Store) ownUpload(ctx context.Context, userID, rawID string) (*model.LykedUploads, error) {
+	id, err := bson.ObjectIDFromHex(rawID)
+	if err != nil {
+		return nil, ErrUploadNotFound
+	}
+	var upload model.LykedUploads
+	err = s.Uploads.FindOne(ctx, bson.M{"_id": id, "user_id": userID, "deleted_at": nil}).Decode(&upload)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch upload: %w", err)
+	}
+	return &upload, nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
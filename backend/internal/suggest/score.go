@@ -0,0 +1,285 @@
+package suggest
+
+import (
+	model "lyked-backend/internal/models/mongodb"
+	"lyked-backend/internal/utils"
+	"math"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// neighbours is how many of the most similar items vote on tags.
+	neighbours = 20
+	// minScore drops suggestions too weak to be worth showing.
+	minScore = 0.05
+
+	weightText     = 0.5
+	weightDomain   = 0.25
+	weightAuthor   = 0.15
+	weightPlatform = 0.1
+)
+
+const (
+	ReasonSimilarItems = "similar_items"
+	ReasonDomain       = "domain"
+	ReasonAuthor       = "author"
+	ReasonPlatform     = "platform"
+)
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "is": true,
+	"it": true, "of": true, "on": true, "or": true, "the": true, "to": true,
+	"with": true, "this": true, "that": true, "you": true, "your": true,
+	"my": true, "how": true, "what": true, "www": true, "com": true,
+	"http": true, "https": true, "html": true, "watch": true,
+}
+
+// example is a tagged item from the user's library, reduced to what
+// suggestions are drawn from.
+type example struct {
+	terms map[string]float64 // term frequencies of title, description and link path
+	signals
+	tags []string
+}
+
+// Suggestion is a proposed tag, scored from 0 to 1, with the signals that
+// proposed it.
+type Suggestion struct {
+	Tag     string   `json:"tag"`
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// signals is where an item comes from: its domain, author and platform.
+type signals struct {
+	domain   string
+	author   string
+	platform string
+}
+
+func signalsOf(link, author string) signals {
+	return signals{domain: domainOf(link), author: normalizeAuthor(author), platform: utils.DetectPlatform(link)}
+}
+
+// contexts lists the feedback contexts an item with these signals falls
+// in, the empty one first.
+func (s signals) contexts() []string {
+	contexts := []string{""}
+	if s.domain != "" {
+		contexts = append(contexts, "domain:"+s.domain)
+	}
+	if s.author != "" {
+		contexts = append(contexts, "author:"+s.author)
+	}
+	if s.platform != "" {
+		contexts = append(contexts, "platform:"+s.platform)
+	}
+	return contexts
+}
+
+// newExample reduces an item to an example.
+func newExample(u model.LykedUploads) example {
+	return example{
+		terms:   termFrequencies(u.Title, u.Description, linkText(u.VideoLink)),
+		signals: signalsOf(u.VideoLink, u.Author),
+		tags:    u.Tags,
+	}
+}
+
+// score ranks tags for an item from the examples, then weighs each by how
+// often it was accepted or rejected before in the item's contexts. Tags in
+// exclude are left out.
+func score(in signals, terms map[string]float64, examples []example, feedback []model.TagFeedback, exclude []string, limit int) []Suggestion {
+	text := textScores(terms, examples)
+	domain := cooccurrence(examples, func(e example) bool { return in.domain != "" && e.domain == in.domain })
+	author := cooccurrence(examples, func(e example) bool { return in.author != "" && e.author == in.author })
+	platform := cooccurrence(examples, func(e example) bool { return e.platform == in.platform })
+
+	factors := map[string]float64{}
+	for _, f := range feedback {
+		if _, ok := factors[f.Tag]; !ok {
+			factors[f.Tag] = 1
+		}
+		// 1 with no feedback, towards 2 when always accepted and 0 when
+		// always rejected.
+		factors[f.Tag] *= 2 * float64(f.Accepted+1) / float64(f.Accepted+f.Rejected+2)
+	}
+
+	tags := map[string]bool{}
+	for _, scores := range []map[string]float64{text, domain, author, platform} {
+		for tag := range scores {
+			tags[tag] = true
+		}
+	}
+
+	suggestions := []Suggestion{}
+	for tag := range tags {
+		if slices.Contains(exclude, tag) {
+			continue
+		}
+		s := Suggestion{Tag: tag, Reasons: []string{}}
+		for _, signal := range []struct {
+			reason string
+			weight float64
+			scores map[string]float64
+		}{
+			{ReasonSimilarItems, weightText, text},
+			{ReasonDomain, weightDomain, domain},
+			{ReasonAuthor, weightAuthor, author},
+			{ReasonPlatform, weightPlatform, platform},
+		} {
+			if v := signal.scores[tag]; v > 0 {
+				s.Score += signal.weight * v
+				s.Reasons = append(s.Reasons, signal.reason)
+			}
+		}
+		if factor, ok := factors[tag]; ok {
+			s.Score *= factor
+		}
+		s.Score = math.Round(min(s.Score, 1)*1000) / 1000
+		if s.Score >= minScore {
+			suggestions = append(suggestions, s)
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Tag < suggestions[j].Tag
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// textScores compares the item's words with every example's by TF-IDF
+// cosine similarity. The most similar examples vote for their tags by
+// similarity; a tag's score is its share of the vote.
+func textScores(terms map[string]float64, examples []example) map[string]float64 {
+	scores := map[string]float64{}
+	if len(terms) == 0 || len(examples) == 0 {
+		return scores
+	}
+	df := map[string]int{}
+	for _, e := range examples {
+		for term := range e.terms {
+			df[term]++
+		}
+	}
+	idf := func(term string) float64 {
+		return math.Log(float64(len(examples)+1)/float64(df[term]+1)) + 1
+	}
+	query := weigh(terms, idf)
+
+	type neighbour struct {
+		example    *example
+		similarity float64
+	}
+	var similar []neighbour
+	for i := range examples {
+		doc := weigh(examples[i].terms, idf)
+		similarity := 0.0
+		for term, w := range query {
+			similarity += w * doc[term]
+		}
+		if similarity > 0 {
+			similar = append(similar, neighbour{&examples[i], similarity})
+		}
+	}
+	sort.Slice(similar, func(i, j int) bool { return similar[i].similarity > similar[j].similarity })
+	similar = similar[:min(len(similar), neighbours)]
+
+	total := 0.0
+	for _, n := range similar {
+		total += n.similarity
+		for _, tag := range n.example.tags {
+			scores[tag] += n.similarity
+		}
+	}
+	for tag := range scores {
+		scores[tag] /= total
+	}
+	return scores
+}
+
+// weigh turns term frequencies into a unit-length TF-IDF vector.
+func weigh(terms map[string]float64, idf func(string) float64) map[string]float64 {
+	vector := make(map[string]float64, len(terms))
+	norm := 0.0
+	for term, tf := range terms {
+		w := (1 + math.Log(tf)) * idf(term)
+		vector[term] = w
+		norm += w * w
+	}
+	norm = math.Sqrt(norm)
+	for term := range vector {
+		vector[term] /= norm
+	}
+	return vector
+}
+
+// cooccurrence scores tags by how many of the examples sharing a signal
+// carry them, damped so a handful of examples can't score a tag at 1.
+func cooccurrence(examples []example, shares func(example) bool) map[string]float64 {
+	counts := map[string]int{}
+	n := 0
+	for _, e := range examples {
+		if !shares(e) {
+			continue
+		}
+		n++
+		for _, tag := range e.tags {
+			counts[tag]++
+		}
+	}
+	scores := make(map[string]float64, len(counts))
+	for tag, count := range counts {
+		scores[tag] = float64(count) / float64(n+1)
+	}
+	return scores
+}
+
+// termFrequencies counts the words of the texts, without stop words,
+// single letters and bare numbers.
+func termFrequencies(texts ...string) map[string]float64 {
+	terms := map[string]float64{}
+	for _, text := range texts {
+		for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if len([]rune(word)) < 2 || stopWords[word] || strings.IndexFunc(word, unicode.IsLetter) < 0 {
+				continue
+			}
+			terms[word]++
+		}
+	}
+	return terms
+}
+
+// linkText is the path of a link, whose slugs often describe the item.
+func linkText(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return ""
+	}
+	return u.Path
+}
+
+// domainOf is a link's host without "www.".
+func domainOf(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+func normalizeAuthor(author string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(author)), "@")
+}
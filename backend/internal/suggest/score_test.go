@@ -0,0 +1,173 @@
+package suggest
+
+import (
+	"fmt"
+	model "lyked-backend/internal/models/mongodb"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTermFrequencies(t *testing.T) {
+	got := termFrequencies(
+		"How to make Crème Brûlée",
+		"The 10 best recipes: crème brûlée! a x b2 3d",
+		"/recipes/creme-brulee-2024",
+	)
+	want := map[string]float64{
+		"make": 1, "crème": 2, "brûlée": 2, "best": 1, "recipes": 2,
+		"b2": 1, "3d": 1, "creme": 1, "brulee": 1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("termFrequencies = %v, want %v", got, want)
+	}
+	if got := termFrequencies("", "the and 42 / -"); len(got) != 0 {
+		t.Errorf("termFrequencies of nothing = %v", got)
+	}
+}
+
+func TestCooccurrence(t *testing.T) {
+	examples := []example{
+		{signals: signals{domain: "youtube.com"}, tags: []string{"cooking", "italian"}},
+		{signals: signals{domain: "youtube.com"}, tags: []string{"cooking"}},
+		{signals: signals{domain: "youtube.com"}, tags: []string{"baking"}},
+		{signals: signals{domain: "tiktok.com"}, tags: []string{"cooking"}},
+	}
+	onYouTube := func(e example) bool { return e.domain == "youtube.com" }
+	// Three examples share the domain, so counts are over four.
+	want := map[string]float64{"cooking": 0.5, "italian": 0.25, "baking": 0.25}
+	if got := cooccurrence(examples, onYouTube); !reflect.DeepEqual(got, want) {
+		t.Errorf("cooccurrence = %v, want %v", got, want)
+	}
+	if got := cooccurrence(examples, func(example) bool { return false }); len(got) != 0 {
+		t.Errorf("cooccurrence with nothing shared = %v", got)
+	}
+}
+
+func TestTextScores(t *testing.T) {
+	examples := []example{
+		{terms: map[string]float64{"pasta": 1, "carbonara": 1}, tags: []string{"italian", "dinner"}},
+		{terms: map[string]float64{"pasta": 1, "pesto": 1}, tags: []string{"italian"}},
+		{terms: map[string]float64{"tacos": 1}, tags: []string{"mexican"}},
+	}
+	got := textScores(map[string]float64{"pasta": 1, "carbonara": 1}, examples)
+
+	// The first example is the query itself. The second shares only
+	// "pasta", in two of three examples, against its own rarer word.
+	pasta, rare := math.Log(4.0/3)+1, math.Log(4.0/2)+1
+	second := pasta * pasta / (pasta*pasta + rare*rare)
+	want := map[string]float64{"italian": 1, "dinner": 1 / (1 + second)}
+	if len(got) != len(want) {
+		t.Fatalf("textScores = %v, want %v", got, want)
+	}
+	for tag, score := range want {
+		if math.Abs(got[tag]-score) > 1e-9 {
+			t.Errorf("textScores[%s] = %v, want %v", tag, got[tag], score)
+		}
+	}
+
+	if got := textScores(nil, examples); len(got) != 0 {
+		t.Errorf("textScores without terms = %v", got)
+	}
+	if got := textScores(map[string]float64{"pasta": 1}, nil); len(got) != 0 {
+		t.Errorf("textScores without examples = %v", got)
+	}
+}
+
+func TestTextScoresVoteOfNeighbours(t *testing.T) {
+	var examples []example
+	for i := range neighbours + 5 {
+		examples = append(examples, example{terms: map[string]float64{"pasta": 1}, tags: []string{fmt.Sprint("tag-", i)}})
+	}
+	got := textScores(map[string]float64{"pasta": 1}, examples)
+	if len(got) != neighbours {
+		t.Fatalf("%d tags got votes, want %d", len(got), neighbours)
+	}
+	for tag, score := range got {
+		if math.Abs(score-1.0/neighbours) > 1e-9 {
+			t.Errorf("textScores[%s] = %v, want %v", tag, score, 1.0/neighbours)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	in := signals{domain: "youtube.com", author: "babish", platform: "youtube"}
+	// Four examples share the domain and platform, two the author.
+	// cooking: 0.25*3/5 + 0.15*2/3 + 0.1*3/5 = 0.31
+	// italian: 0.25*2/5 + 0.15*1/3 + 0.1*2/5 = 0.19
+	examples := []example{
+		{signals: signals{"youtube.com", "babish", "youtube"}, tags: []string{"cooking", "italian"}},
+		{signals: signals{"youtube.com", "other", "youtube"}, tags: []string{"cooking"}},
+		{signals: signals{"youtube.com", "babish", "youtube"}, tags: []string{"cooking"}},
+		{signals: signals{"youtube.com", "", "youtube"}, tags: []string{"italian"}},
+		{signals: signals{"tiktok.com", "charli", "tiktok"}, tags: []string{"dance"}}, // shares nothing
+	}
+	allReasons := []string{ReasonDomain, ReasonAuthor, ReasonPlatform}
+	cooking := Suggestion{Tag: "cooking", Score: 0.31, Reasons: allReasons}
+	italian := Suggestion{Tag: "italian", Score: 0.19, Reasons: allReasons}
+
+	tests := []struct {
+		name     string
+		feedback []model.TagFeedback
+		exclude  []string
+		limit    int
+		want     []Suggestion
+	}{
+		{"ranked", nil, nil, 5, []Suggestion{cooking, italian}},
+		{"limited", nil, nil, 1, []Suggestion{cooking}},
+		{"excluded", nil, []string{"cooking"}, 5, []Suggestion{italian}},
+		{
+			"accepted and rejected",
+			[]model.TagFeedback{{Tag: "italian", Accepted: 3}, {Tag: "cooking", Rejected: 3}},
+			nil, 5,
+			// italian *2*4/5, cooking *2*1/5
+			[]Suggestion{{"italian", 0.304, allReasons}, {"cooking", 0.124, allReasons}},
+		},
+		{
+			"feedback from several contexts",
+			[]model.TagFeedback{{Tag: "cooking", Context: "", Accepted: 1}, {Tag: "cooking", Context: "domain:youtube.com", Rejected: 1}},
+			nil, 5,
+			// 0.31 * 4/3 * 2/3
+			[]Suggestion{{"cooking", 0.276, allReasons}, italian},
+		},
+		{
+			"rejected below the minimum",
+			[]model.TagFeedback{{Tag: "italian", Rejected: 20}},
+			nil, 5,
+			[]Suggestion{cooking},
+		},
+		{"feedback for other tags", []model.TagFeedback{{Tag: "baking", Accepted: 9}}, nil, 5, []Suggestion{cooking, italian}},
+	}
+	for _, tt := range tests {
+		got := score(in, nil, examples, tt.feedback, tt.exclude, tt.limit)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: score =\n %+v\nwant\n %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScoreTextTiesAndCap(t *testing.T) {
+	in := signals{domain: "example.com", platform: "web"}
+	examples := []example{
+		{terms: map[string]float64{"pasta": 1}, signals: signals{domain: "example.com", platform: "web"}, tags: []string{"b", "a"}},
+	}
+	terms := map[string]float64{"pasta": 1}
+
+	// Equal scores come in tag order: 0.5*1 + 0.25*1/2 + 0.1*1/2.
+	reasons := []string{ReasonSimilarItems, ReasonDomain, ReasonPlatform}
+	want := []Suggestion{{"a", 0.675, reasons}, {"b", 0.675, reasons}}
+	if got := score(in, terms, examples, nil, nil, 5); !reflect.DeepEqual(got, want) {
+		t.Errorf("score = %+v, want %+v", got, want)
+	}
+
+	// Feedback can't push a score past 1.
+	feedback := []model.TagFeedback{{Tag: "b", Accepted: 100}}
+	want = []Suggestion{{"b", 1, reasons}, {"a", 0.675, reasons}}
+	if got := score(in, terms, examples, feedback, nil, 5); !reflect.DeepEqual(got, want) {
+		t.Errorf("score = %+v, want %+v", got, want)
+	}
+
+	if got := score(in, terms, nil, nil, nil, 5); got == nil || len(got) != 0 {
+		t.Errorf("score without examples = %#v, want an empty list", got)
+	}
+}
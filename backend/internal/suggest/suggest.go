@@ -0,0 +1,155 @@
+// Package suggest proposes tags for an item from the user's own library:
+// the words of similar items, and the tags usually given to links from the
+// same domain, author and platform. Nothing leaves the server; accepted
+// and rejected suggestions feed back into later scores.
+package suggest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DefaultLimit = 5
+	MaxLimit     = 20
+
+	// maxExamples caps how many of the most recently saved tagged items
+	// suggestions are drawn from.
+	maxExamples = 5000
+)
+
+var (
+	ErrNothingToSuggestFor = errors.New("a url, title or description is required")
+	ErrNoFeedback          = errors.New("accept or reject at least one tag")
+)
+
+// Store bundles the collections suggestions work with.
+type Store struct {
+	Uploads  *mongo.Collection
+	Feedback *mongo.Collection
+}
+
+// Input is the item tags are suggested for. Tags it already has are not
+// suggested again.
+type Input struct {
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Author      string   `json:"author"`
+	Tags        []string `json:"tags"`
+}
+
+// FeedbackRequest records which suggestions for an item were taken and
+// which were turned down.
+type FeedbackRequest struct {
+	URL      string   `json:"url"`
+	Author   string   `json:"author"`
+	Accepted []string `json:"accepted"`
+	Rejected []string `json:"rejected"`
+}
+
+// Suggest proposes up to limit tags for the item, best first.
+func (s *Store) Suggest(ctx context.Context, userID string, in Input, limit int) ([]Suggestion, error) {
+	terms := termFrequencies(in.Title, in.Description, linkText(in.URL))
+	if len(terms) == 0 && strings.TrimSpace(in.URL) == "" {
+		return nil, ErrNothingToSuggestFor
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+
+	aliases, err := library.LoadTagAliases(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	examples, err := s.examples(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sig := signalsOf(in.URL, in.Author)
+	feedback, err := s.feedback(ctx, userID, sig.contexts())
+	if err != nil {
+		return nil, err
+	}
+	return score(sig, terms, examples, feedback, aliases.ResolveAll(in.Tags), limit), nil
+}
+
+// Record counts the accepted and rejected tags, anywhere and for the
+// item's domain, author and platform. A tag both accepted and rejected
+// counts as accepted.
+func (s *Store) Record(ctx context.Context, userID string, req FeedbackRequest, now time.Time) error {
+	aliases, err := library.LoadTagAliases(ctx, userID)
+	if err != nil {
+		return err
+	}
+	accepted := aliases.ResolveAll(req.Accepted)
+	rejected := slices.DeleteFunc(aliases.ResolveAll(req.Rejected), func(tag string) bool {
+		return slices.Contains(accepted, tag)
+	})
+	if len(accepted) == 0 && len(rejected) == 0 {
+		return ErrNoFeedback
+	}
+
+	var writes []mongo.WriteModel
+	for _, key := range signalsOf(req.URL, req.Author).contexts() {
+		for field, tags := range map[string][]string{"accepted": accepted, "rejected": rejected} {
+			for _, tag := range tags {
+				writes = append(writes, mongo.NewUpdateOneModel().
+					SetFilter(bson.M{"user_id": userID, "context": key, "tag": tag}).
+					SetUpdate(bson.M{"$inc": bson.M{field: 1}, "$set": bson.M{"updated_at": now}}).
+					SetUpsert(true))
+			}
+		}
+	}
+	if _, err := s.Feedback.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to save tag feedback: %w", err)
+	}
+	return nil
+}
+
+// examples loads the user's most recently saved live items that have tags.
+func (s *Store) examples(ctx context.Context, userID string) ([]example, error) {
+	opts := options.Find().
+		SetProjection(bson.M{"title": 1, "description": 1, "author": 1, "video_link": 1, "tags": 1}).
+		SetSort(bson.D{{Key: "saved_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(maxExamples)
+	cursor, err := s.Uploads.Find(ctx, bson.M{"user_id": userID, "deleted_at": nil, "tags.0": bson.M{"$exists": true}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch uploads: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var examples []example
+	for cursor.Next(ctx) {
+		var u model.LykedUploads
+		if err := cursor.Decode(&u); err != nil {
+			return nil, fmt.Errorf("failed to parse upload: %w", err)
+		}
+		examples = append(examples, newExample(u))
+	}
+	return examples, cursor.Err()
+}
+
+// feedback loads what the user accepted and rejected in the contexts.
+func (s *Store) feedback(ctx context.Context, userID string, contexts []string) ([]model.TagFeedback, error) {
+	cursor, err := s.Feedback.Find(ctx, bson.M{"user_id": userID, "context": bson.M{"$in": contexts}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tag feedback: %w", err)
+	}
+	var feedback []model.TagFeedback
+	if err := cursor.All(ctx, &feedback); err != nil {
+		return nil, fmt.Errorf("failed to parse tag feedback: %w", err)
+	}
+	return feedback, nil
+}
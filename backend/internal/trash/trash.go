@@ -0,0 +1,350 @@
+// Package trash soft-deletes uploads and folders and purges them once
+// they have been in the trash longer than the retention period.
+package trash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lyked-backend/internal/archive"
+	DB "lyked-backend/internal/database/mongodb"
+	"lyked-backend/internal/library"
+	model "lyked-backend/internal/models/mongodb"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DefaultRetention = 30 * 24 * time.Hour
+	purgeChunk       = 1000
+)
+
+// Purged counts what a purge removed for good.
+type Purged struct {
+	Uploads int `json:"uploads"`
+	Folders int `json:"folders"`
+}
+
+// inTrash matches documents that are currently trashed.
+var inTrash = bson.M{"$ne": nil}
+
+// TrashUploads moves the user's uploads to the trash. Folder membership is
+// left in place so a restore puts them back where they were.
+func TrashUploads(ctx context.Context, userID string, ids []bson.ObjectID, now time.Time) (int64, error) {
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		return 0, err
+	}
+	res, err := uploads.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "user_id": userID, "deleted_at": nil},
+		library.TrashUpdate(userID, now))
+	if err != nil {
+		return 0, fmt.Errorf("failed to trash uploads: %w", err)
+	}
+	return res.ModifiedCount, nil
+}
+
+// TrashFolder moves a folder and its subfolders to the trash. Their items
+// stay in the library.
+func TrashFolder(ctx context.Context, userID string, id bson.ObjectID, now time.Time) (bool, error) {
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		return false, err
+	}
+	trashed := false
+	err = DB.WithTransaction(ctx, func(ctx context.Context) error {
+		folder, err := library.FindFolder(ctx, folders, userID, id.Hex())
+		if errors.Is(err, library.ErrFolderNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		filter := library.SubtreeMatch(folder)
+		filter["deleted_at"] = nil
+		if _, err := folders.UpdateMany(ctx, filter, library.TrashUpdate(userID, now)); err != nil {
+			return fmt.Errorf("failed to trash folder: %w", err)
+		}
+		trashed = true
+		return nil
+	})
+	return trashed, err
+}
+
+// TrashFolderWithItems moves a static folder, its subfolders and everything
+// filed in them to the trash, in one transaction, and returns the ids of
+// the trashed items. Items also filed elsewhere go too.
+func TrashFolderWithItems(ctx context.Context, userID string, id bson.ObjectID, now time.Time) ([]bson.ObjectID, error) {
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		return nil, err
+	}
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		return nil, err
+	}
+	var ids []bson.ObjectID
+	err = DB.WithTransaction(ctx, func(ctx context.Context) error {
+		folder, err := library.FindFolder(ctx, folders, userID, id.Hex())
+		if err != nil {
+			return err
+		}
+		subfolders, err := library.Descendants(ctx, folders, folder)
+		if err != nil {
+			return err
+		}
+		static := []string{folder.ID.Hex()}
+		for _, f := range subfolders {
+			if !f.IsSmart() {
+				static = append(static, f.ID.Hex())
+			}
+		}
+		ids, err = matchingIDs(ctx, uploads, bson.M{"user_id": userID, "folders": bson.M{"$in": static}, "deleted_at": nil})
+		if err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			if _, err := TrashUploads(ctx, userID, ids, now); err != nil {
+				return err
+			}
+		}
+		_, err = TrashFolder(ctx, userID, id, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// RestoreUploads takes uploads out of the trash and returns them, so the
+// caller can put them back into the search index.
+func RestoreUploads(ctx context.Context, userID string, ids []bson.ObjectID) ([]model.LykedUploads, error) {
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"_id": bson.M{"$in": ids}, "user_id": userID, "deleted_at": inTrash}
+	cursor, err := uploads.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trashed uploads: %w", err)
+	}
+	restored := []model.LykedUploads{}
+	if err := cursor.All(ctx, &restored); err != nil {
+		return nil, fmt.Errorf("failed to parse trashed uploads: %w", err)
+	}
+	if len(restored) == 0 {
+		return restored, nil
+	}
+
+	if _, err := uploads.UpdateMany(ctx, filter, library.RestoreUpdate()); err != nil {
+		return nil, fmt.Errorf("failed to restore uploads: %w", err)
+	}
+	for i := range restored {
+		restored[i].DeletedAt = nil
+		restored[i].DeletedBy = ""
+	}
+	return restored, nil
+}
+
+// RestoreFolders takes folders out of the trash, with the subfolders that
+// were trashed along with them. Their items never left them, so membership
+// comes back as it was. A folder whose parent is gone moves to the top.
+func RestoreFolders(ctx context.Context, userID string, ids []bson.ObjectID) (int64, error) {
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		return 0, err
+	}
+	cursor, err := folders.Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "user_id": userID, "deleted_at": inTrash})
+	if err != nil {
+		return 0, fmt.Errorf("failed to load trashed folders: %w", err)
+	}
+	var trashed []model.Folder
+	if err := cursor.All(ctx, &trashed); err != nil {
+		return 0, fmt.Errorf("failed to parse trashed folders: %w", err)
+	}
+
+	var restored int64
+	now := time.Now().UTC()
+	for _, f := range trashed {
+		err := DB.WithTransaction(ctx, func(ctx context.Context) error {
+			filter := library.SubtreeMatch(&f)
+			filter["deleted_at"] = f.DeletedAt
+			res, err := folders.UpdateMany(ctx, filter, library.RestoreUpdate())
+			if err != nil {
+				return fmt.Errorf("failed to restore folders: %w", err)
+			}
+			restored += res.ModifiedCount
+			if f.ParentID == "" {
+				return nil
+			}
+			if _, err := library.FindFolder(ctx, folders, userID, f.ParentID); !errors.Is(err, library.ErrFolderNotFound) {
+				return err
+			}
+			return library.DetachFolder(ctx, folders, &f, now)
+		})
+		if err != nil {
+			return restored, err
+		}
+	}
+	return restored, nil
+}
+
+// Empty permanently deletes everything in the user's trash.
+func Empty(ctx context.Context, userID string) (Purged, error) {
+	filter := bson.M{"user_id": userID, "deleted_at": inTrash}
+	return purge(ctx, filter, filter)
+}
+
+// PurgeExpired permanently deletes anything trashed before the cutoff.
+func PurgeExpired(ctx context.Context, cutoff time.Time) (Purged, error) {
+	filter := bson.M{"deleted_at": bson.M{"$ne": nil, "$lt": cutoff}}
+	return purge(ctx, filter, filter)
+}
+
+// purge deletes the matching uploads and folders, and drops references to
+// the folders from the uploads that are kept.
+func purge(ctx context.Context, uploadFilter, folderFilter bson.M) (Purged, error) {
+	var purged Purged
+	uploads, err := DB.GetCollection("uploads")
+	if err != nil {
+		return purged, err
+	}
+	folders, err := DB.GetCollection("folders")
+	if err != nil {
+		return purged, err
+	}
+
+	uploadIDs, err := matchingIDs(ctx, uploads, uploadFilter)
+	if err != nil {
+		return purged, err
+	}
+	for start := 0; start < len(uploadIDs); start += purgeChunk {
+		chunk := uploadIDs[start:min(start+purgeChunk, len(uploadIDs))]
+		res, err := uploads.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": chunk}})
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge uploads: %w", err)
+		}
+		purged.Uploads += int(res.DeletedCount)
+
+		hexIDs := hexes(chunk)
+		if err := archive.RemoveForUploads(ctx, hexIDs); err != nil {
+			return purged, err
+		}
+		if feedback, err := DB.GetCollection("resurface_feedback"); err == nil {
+			if _, err := feedback.DeleteMany(ctx, bson.M{"upload_id": bson.M{"$in": hexIDs}}); err != nil {
+				return purged, fmt.Errorf("failed to delete resurfacing feedback: %w", err)
+			}
+		}
+		for _, name := range []string{"review_cards", "review_log"} {
+			if collection, err := DB.GetCollection(name); err == nil {
+				if _, err := collection.DeleteMany(ctx, bson.M{"upload_id": bson.M{"$in": hexIDs}}); err != nil {
+					return purged, fmt.Errorf("failed to delete review history: %w", err)
+				}
+			}
+		}
+		if err := deleteShareLinks(ctx, model.ShareKindUpload, chunk); err != nil {
+			return purged, err
+		}
+	}
+
+	folderIDs, err := matchingIDs(ctx, folders, folderFilter)
+	if err != nil {
+		return purged, err
+	}
+	for start := 0; start < len(folderIDs); start += purgeChunk {
+		chunk := folderIDs[start:min(start+purgeChunk, len(folderIDs))]
+		// Membership lives on the uploads, so the folders and the references
+		// to them go together.
+		var deleted int64
+		err := DB.WithTransaction(ctx, func(ctx context.Context) error {
+			res, err := folders.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": chunk}})
+			if err != nil {
+				return fmt.Errorf("failed to purge folders: %w", err)
+			}
+			deleted = res.DeletedCount
+			hexIDs := hexes(chunk)
+			positions := bson.M{}
+			for _, id := range hexIDs {
+				positions["positions."+id] = ""
+			}
+			if _, err := uploads.UpdateMany(ctx, bson.M{"folders": bson.M{"$in": hexIDs}},
+				bson.M{"$pull": bson.M{"folders": bson.M{"$in": hexIDs}}, "$unset": positions}); err != nil {
+				return fmt.Errorf("failed to update uploads: %w", err)
+			}
+			if invites, err := DB.GetCollection("folder_invites"); err == nil {
+				if _, err := invites.DeleteMany(ctx, bson.M{"folder_id": bson.M{"$in": chunk}}); err != nil {
+					return fmt.Errorf("failed to delete folder invites: %w", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return purged, err
+		}
+		purged.Folders += int(deleted)
+		if err := deleteShareLinks(ctx, model.ShareKindFolder, chunk); err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+// deleteShareLinks drops the public links to purged uploads or folders.
+func deleteShareLinks(ctx context.Context, kind string, ids []bson.ObjectID) error {
+	links, err := DB.GetCollection("share_links")
+	if err != nil {
+		return nil
+	}
+	if _, err := links.DeleteMany(ctx, bson.M{"kind": kind, "target_id": bson.M{"$in": ids}}); err != nil {
+		return fmt.Errorf("failed to delete share links: %w", err)
+	}
+	return nil
+}
+
+func matchingIDs(ctx context.Context, coll *mongo.Collection, filter bson.M) ([]bson.ObjectID, error) {
+	cursor, err := coll.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s: %w", coll.Name(), err)
+	}
+	var docs []struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", coll.Name(), err)
+	}
+	ids := make([]bson.ObjectID, len(docs))
+	for i, d := range docs {
+		ids[i] = d.ID
+	}
+	return ids, nil
+}
+
+func hexes(ids []bson.ObjectID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.Hex()
+	}
+	return out
+}
+
+// StartPurger purges expired trash now and then every interval, for the
+// lifetime of the process.
+func StartPurger(retention, interval time.Duration) {
+	go func() {
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			purged, err := PurgeExpired(ctx, time.Now().UTC().Add(-retention))
+			cancel()
+			if err != nil {
+				fmt.Printf("Failed to purge trash: %v\n", err)
+			} else if purged.Uploads > 0 || purged.Folders > 0 {
+				fmt.Printf("🗑️ Purged %d uploads and %d folders from the trash\n", purged.Uploads, purged.Folders)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Known platforms a saved link can come from
+const (
+	PlatformTikTok    = "tiktok"
+	PlatformInstagram = "instagram"
+	PlatformYouTube   = "youtube"
+	PlatformPinterest = "pinterest"
+	PlatformWeb       = "web"
+)
+
+var platformHosts = map[string]string{
+	"tiktok.com":    PlatformTikTok,
+	"tiktokv.com":   PlatformTikTok, // share links in TikTok data exports
+	"instagram.com": PlatformInstagram,
+	"youtube.com":   PlatformYouTube,
+	"youtu.be":      PlatformYouTube,
+	"pinterest.com": PlatformPinterest,
+	"pin.it":        PlatformPinterest,
+}
+
+// DetectPlatform maps a saved link to the platform it was saved from.
+// Anything we don't recognise is reported as "web".
+func DetectPlatform(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return PlatformWeb
+	}
+	host := strings.ToLower(u.Hostname())
+	for domain, platform := range platformHosts {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return platform
+		}
+	}
+	return PlatformWeb
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// TagSeparator splits a tag into the levels of its hierarchy: "food/baking"
+// is below "food".
+const TagSeparator = "/"
+
+// NormalizeTag is the form tags are stored and matched in: lower case, with
+// surrounding whitespace trimmed and inner runs of whitespace collapsed to
+// one space, so "Baking " and "baking" are the same tag. Each level is
+// normalized on its own and empty levels are dropped, so "Food / Baking/"
+// is "food/baking".
+func NormalizeTag(tag string) string {
+	var levels []string
+	for _, level := range strings.Split(tag, TagSeparator) {
+		if level = strings.ToLower(strings.Join(strings.Fields(level), " ")); level != "" {
+			levels = append(levels, level)
+		}
+	}
+	return strings.Join(levels, TagSeparator)
+}
+
+// TagWithin reports whether tag is ancestor or one of its descendants.
+func TagWithin(tag, ancestor string) bool {
+	return tag == ancestor || strings.HasPrefix(tag, ancestor+TagSeparator)
+}
+
+// TagPattern is a regular expression matching a normalized tag and its
+// descendants. Anchored at the start, it can use an index on tags.
+func TagPattern(tag string) string {
+	return "^" + regexp.QuoteMeta(tag) + "(" + TagSeparator + "|$)"
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var youtubeID = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// ThumbnailURL returns a still image for a saved link when one can be
+// derived from the link alone, and "" otherwise. Only YouTube serves
+// thumbnails at predictable addresses.
+func ThumbnailURL(link string) string {
+	if DetectPlatform(link) != PlatformYouTube {
+		return ""
+	}
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return ""
+	}
+	id := u.Query().Get("v")
+	if strings.EqualFold(u.Hostname(), "youtu.be") {
+		id = strings.Trim(u.Path, "/")
+	}
+	for _, prefix := range []string{"/shorts/", "/embed/", "/live/"} {
+		if rest, ok := strings.CutPrefix(u.Path, prefix); ok {
+			id = strings.Trim(rest, "/")
+		}
+	}
+	if !youtubeID.MatchString(id) {
+		return ""
+	}
+	return "https://i.ytimg.com/vi/" + id + "/hqdefault.jpg"
+}
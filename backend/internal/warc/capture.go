@@ -0,0 +1,350 @@
+package warc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"lyked-backend/internal/utils"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	DefaultMaxResources = 150
+	DefaultMaxBytes     = 50 << 20 // whole snapshot
+	maxResourceBytes    = 20 << 20
+	captureWorkers      = 4
+	userAgent           = "Mozilla/5.0 (compatible; LykedArchiver/1.0)"
+)
+
+// Capturer fetches a page with the images, stylesheets, scripts, fonts and
+// media it references, and writes every exchange to a WARC file.
+type Capturer struct {
+	Client       *http.Client
+	MaxResources int
+	MaxBytes     int64
+}
+
+// NewCapturer returns a Capturer that refuses loopback and private
+// addresses unless allowPrivate is set, which tests against a local
+// fixture server need.
+func NewCapturer(allowPrivate bool) *Capturer {
+	return &Capturer{
+		Client:       &http.Client{Transport: utils.PublicTransport(allowPrivate), Timeout: 2 * time.Minute},
+		MaxResources: DefaultMaxResources,
+		MaxBytes:     DefaultMaxBytes,
+	}
+}
+
+// CaptureResult summarises a capture.
+type CaptureResult struct {
+	URL       string   // the page after redirects
+	Resources int      // sub-resources captured
+	Failed    []string // sub-resources that could not be fetched
+	Bytes     int64    // payload bytes captured
+}
+
+// Capture writes the page and its sub-resources to w. Only the page
+// itself has to succeed; sub-resources that fail are listed in the result.
+func (c *Capturer) Capture(ctx context.Context, pageURL string, w io.Writer) (*CaptureResult, error) {
+	writer := NewWriter(w)
+	err := writer.WriteInfo(map[string]string{
+		"software": "lyked-backend",
+		"format":   "WARC File Format 1.1",
+		"isPartOf": pageURL,
+	}, "software", "format", "isPartOf")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CaptureResult{}
+	page, err := c.fetch(ctx, writer, pageURL, c.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	result.URL = page.url.String()
+	result.Bytes = int64(len(page.body))
+	if page.status < 200 || page.status >= 300 {
+		return nil, fmt.Errorf("fetching %s returned HTTP %d", pageURL, page.status)
+	}
+
+	var queue []string
+	switch page.mediaType() {
+	case "text/html", "application/xhtml+xml":
+		queue = htmlResources(page.url, page.body)
+	case "text/css":
+		queue = cssResources(page.url, page.body)
+	}
+
+	seen := map[string]bool{result.URL: true, pageURL: true}
+	var mu sync.Mutex
+	for len(queue) > 0 && result.Resources+len(result.Failed) < c.MaxResources {
+		var batch []string
+		for _, link := range queue {
+			if !seen[link] && result.Resources+len(result.Failed)+len(batch) < c.MaxResources {
+				seen[link] = true
+				batch = append(batch, link)
+			}
+		}
+		queue = nil
+
+		jobs := make(chan string)
+		var wg sync.WaitGroup
+		for range captureWorkers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for link := range jobs {
+					mu.Lock()
+					budget := c.MaxBytes - result.Bytes
+					mu.Unlock()
+
+					res, err := c.fetch(ctx, writer, link, min(budget, maxResourceBytes))
+
+					mu.Lock()
+					if err != nil || res.status >= 400 {
+						result.Failed = append(result.Failed, link)
+					} else {
+						result.Resources++
+						result.Bytes += int64(len(res.body))
+						// Stylesheets pull in fonts, images and other stylesheets.
+						if res.mediaType() == "text/css" {
+							queue = append(queue, cssResources(res.url, res.body)...)
+						}
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+		for _, link := range batch {
+			if ctx.Err() != nil || result.Bytes >= c.MaxBytes {
+				break
+			}
+			jobs <- link
+		}
+		close(jobs)
+		wg.Wait()
+		if ctx.Err() != nil || result.Bytes >= c.MaxBytes {
+			break
+		}
+	}
+	return result, ctx.Err()
+}
+
+type fetched struct {
+	url    *url.URL
+	status int
+	header http.Header
+	body   []byte
+}
+
+func (f *fetched) mediaType() string {
+	mediaType, _, _ := mime.ParseMediaType(f.header.Get("Content-Type"))
+	return mediaType
+}
+
+// fetch does a GET without following redirects, so every hop is recorded;
+// redirects are then followed by hand.
+func (c *Capturer) fetch(ctx context.Context, writer *Writer, link string, limit int64) (*fetched, error) {
+	client := *c.Client
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	for hops := 0; ; hops++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+		if err != nil {
+			return nil, err
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return nil, fmt.Errorf("unsupported URL scheme %q", req.URL.Scheme)
+		}
+		req.Header.Set("User-Agent", userAgent)
+		req.Header.Set("Accept", "*/*")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", link, err)
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", link, err)
+		}
+		if int64(len(body)) > limit {
+			return nil, fmt.Errorf("%s is larger than the snapshot limit", link)
+		}
+		if err := record(writer, req, resp, body); err != nil {
+			return nil, err
+		}
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode >= 300 && resp.StatusCode < 400 && location != "" && hops < 10 {
+			next, err := req.URL.Parse(location)
+			if err != nil {
+				return nil, err
+			}
+			link = next.String()
+			continue
+		}
+		return &fetched{url: req.URL, status: resp.StatusCode, header: resp.Header, body: body}, nil
+	}
+}
+
+// record writes the request and response records of one exchange. The
+// body has already been de-chunked and decompressed, so the headers that
+// describe the wire encoding are dropped.
+func record(writer *Writer, req *http.Request, resp *http.Response, body []byte) error {
+	var reqBlock bytes.Buffer
+	fmt.Fprintf(&reqBlock, "GET %s HTTP/1.1\r\nHost: %s\r\n", req.URL.RequestURI(), req.URL.Host)
+	req.Header.Write(&reqBlock)
+	reqBlock.WriteString("\r\n")
+
+	header := resp.Header.Clone()
+	header.Del("Transfer-Encoding")
+	header.Del("Content-Encoding")
+	header.Set("Content-Length", fmt.Sprint(len(body)))
+	var respBlock bytes.Buffer
+	fmt.Fprintf(&respBlock, "HTTP/1.1 %s\r\n", resp.Status)
+	header.Write(&respBlock)
+	respBlock.WriteString("\r\n")
+	respBlock.Write(body)
+
+	target := req.URL.String()
+	responseID, err := writer.WriteRecord(TypeResponse, target, "application/http; msgtype=response", respBlock.Bytes(),
+		Field{"WARC-Payload-Digest", Digest(body)})
+	if err != nil {
+		return err
+	}
+	_, err = writer.WriteRecord(TypeRequest, target, "application/http; msgtype=request", reqBlock.Bytes(),
+		Field{"WARC-Concurrent-To", responseID})
+	return err
+}
+
+// htmlResources lists what a page needs to render: images, stylesheets,
+// scripts, icons, media and the url()s in inline styles.
+func htmlResources(base *url.URL, page []byte) []string {
+	var links []string
+	add := func(ref string) {
+		if link := resolve(base, ref); link != "" {
+			links = append(links, link)
+		}
+	}
+
+	z := html.NewTokenizer(bytes.NewReader(page))
+	inStyle := false
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return links
+		case html.TextToken:
+			if inStyle {
+				links = append(links, cssResources(base, z.Text())...)
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "style" {
+				inStyle = false
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			if token.Data == "style" {
+				inStyle = true
+			}
+			if token.Data == "base" {
+				if href := attr(token, "href"); href != "" {
+					if u, err := base.Parse(href); err == nil {
+						base = u
+					}
+				}
+			}
+			for _, a := range token.Attr {
+				switch {
+				case a.Key == "style":
+					links = append(links, cssResources(base, []byte(a.Val))...)
+				case a.Key == "srcset":
+					for _, ref := range srcset(a.Val) {
+						add(ref)
+					}
+				case isResourceAttr(token, a.Key):
+					add(a.Val)
+				}
+			}
+		}
+	}
+}
+
+// isResourceAttr reports whether the attribute names something the page
+// loads, as opposed to a link the reader may follow.
+func isResourceAttr(token html.Token, key string) bool {
+	switch token.Data {
+	case "img", "script", "video", "audio", "source", "track", "embed", "input", "iframe":
+		return key == "src" || key == "poster"
+	case "link":
+		if key != "href" {
+			return false
+		}
+		for _, rel := range strings.Fields(strings.ToLower(attr(token, "rel"))) {
+			switch rel {
+			case "stylesheet", "icon", "shortcut", "apple-touch-icon", "preload", "manifest":
+				return true
+			}
+		}
+	case "body", "table", "td":
+		return key == "background"
+	}
+	return false
+}
+
+var cssURL = regexp.MustCompile(`url\(\s*(?:"([^"]*)"|'([^']*)'|([^'")\s]+))\s*\)|@import\s+(?:"([^"]*)"|'([^']*)')`)
+
+// cssResources lists the url()s and @imports of a stylesheet.
+func cssResources(base *url.URL, css []byte) []string {
+	var links []string
+	for _, m := range cssURL.FindAllSubmatch(css, -1) {
+		for _, ref := range m[1:] {
+			if len(ref) > 0 {
+				if link := resolve(base, string(ref)); link != "" {
+					links = append(links, link)
+				}
+				break
+			}
+		}
+	}
+	return links
+}
+
+func srcset(value string) []string {
+	var refs []string
+	for _, candidate := range strings.Split(value, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			refs = append(refs, fields[0])
+		}
+	}
+	return refs
+}
+
+// resolve makes ref absolute, dropping fragments and anything that is not
+// http(s), such as data: URIs that are already inline.
+func resolve(base *url.URL, ref string) string {
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	u.Fragment = ""
+	return u.String()
+}
+
+func attr(token html.Token, key string) string {
+	for _, a := range token.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
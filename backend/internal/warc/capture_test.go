@@ -0,0 +1,183 @@
+package warc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"lyked-backend/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fixture serves a page with a stylesheet that pulls in a font, an image
+// behind a redirect, a script, and a missing image.
+func fixture(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!doctype html><html><head>
+<link rel="stylesheet" href="/style.css">
+<meta http-equiv="refresh" content="0; url=https://example.com/">
+<script src="/app.js"></script>
+</head><body onload="steal()">
+<h1>Fresh pasta</h1>
+<img src="/old.png" alt="pasta">
+<img src="/missing.png">
+<a href="/other">more</a>
+</body></html>`))
+	})
+	mux.HandleFunc("/style.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		w.Write([]byte(`@font-face { src: url("/font.woff2"); } h1 { color: red; }`))
+	})
+	mux.HandleFunc("/font.woff2", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "font/woff2")
+		w.Write([]byte("FONT"))
+	})
+	mux.HandleFunc("/old.png", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new.png", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("PNG"))
+	})
+	mux.HandleFunc("/app.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript")
+		w.Write([]byte("steal()"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func capture(t *testing.T, c *Capturer, link string) (*CaptureResult, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	result, err := c.Capture(context.Background(), link, &buf)
+	if err != nil {
+		t.Fatalf("Capture(%s): %v", link, err)
+	}
+	return result, &buf
+}
+
+func TestCapture(t *testing.T) {
+	server := fixture(t)
+	result, buf := capture(t, NewCapturer(true), server.URL+"/page")
+
+	if result.URL != server.URL+"/page" {
+		t.Errorf("URL = %q, want the page", result.URL)
+	}
+	// The stylesheet, font, image (after its redirect) and script.
+	if result.Resources != 4 {
+		t.Errorf("Resources = %d, want 4", result.Resources)
+	}
+	if len(result.Failed) != 1 || result.Failed[0] != server.URL+"/missing.png" {
+		t.Errorf("Failed = %v, want the missing image", result.Failed)
+	}
+
+	archive, err := Load(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archive.Page() != server.URL+"/page" {
+		t.Errorf("Page() = %q, want the page", archive.Page())
+	}
+	for _, path := range []string{"/page", "/style.css", "/font.woff2", "/old.png", "/new.png", "/app.js"} {
+		if _, ok := archive.Responses[server.URL+path]; !ok {
+			t.Errorf("%s was not recorded", path)
+		}
+	}
+	// Redirects are recorded hop by hop and followed on lookup.
+	if resp := archive.Responses[server.URL+"/old.png"]; resp == nil || resp.StatusCode != http.StatusMovedPermanently {
+		t.Errorf("the redirect itself should be recorded, got %+v", resp)
+	}
+	resp, err := archive.Lookup(server.URL + "/old.png")
+	if err != nil || string(resp.Body) != "PNG" {
+		t.Errorf("Lookup through the redirect = %v, %v", resp, err)
+	}
+	if _, err := archive.Lookup(server.URL + "/never"); !errors.Is(err, ErrNotCaptured) {
+		t.Errorf("Lookup of an uncaptured URL: err = %v, want ErrNotCaptured", err)
+	}
+}
+
+func TestCaptureLimits(t *testing.T) {
+	server := fixture(t)
+
+	c := NewCapturer(true)
+	c.MaxResources = 1
+	result, _ := capture(t, c, server.URL+"/page")
+	if got := result.Resources + len(result.Failed); got != 1 {
+		t.Errorf("fetched %d resources, want at most 1", got)
+	}
+
+	c = NewCapturer(true)
+	c.MaxBytes = 10
+	if _, err := c.Capture(context.Background(), server.URL+"/page", &bytes.Buffer{}); err == nil {
+		t.Error("a page over the byte limit should fail")
+	}
+}
+
+func TestCaptureErrors(t *testing.T) {
+	server := fixture(t)
+	tests := []struct {
+		name string
+		link string
+	}{
+		{"not found", server.URL + "/missing.png"},
+		{"unsupported scheme", "ftp://example.com/file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewCapturer(true).Capture(context.Background(), tt.link, &bytes.Buffer{}); err == nil {
+				t.Errorf("Capture(%s) should fail", tt.link)
+			}
+		})
+	}
+}
+
+func TestCaptureRefusesPrivateAddresses(t *testing.T) {
+	server := fixture(t)
+	_, err := NewCapturer(false).Capture(context.Background(), server.URL+"/page", &bytes.Buffer{})
+	if !errors.Is(err, utils.ErrPrivateAddress) {
+		t.Errorf("capturing %s: err = %v, want ErrPrivateAddress", server.URL, err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	server := fixture(t)
+	_, buf := capture(t, NewCapturer(true), server.URL+"/page")
+	archive, err := Load(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := archive.Render(archive.Page())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(page)
+
+	for _, want := range []string{
+		"<h1>Fresh pasta</h1>",
+		`src="data:image/png;base64,UE5H"`,       // the redirected image, inlined
+		`url("data:font/woff2;base64,Rk9OVA==")`, // the font inside the inlined stylesheet
+		`src="about:invalid"`,                    // the image that failed
+		`href="` + server.URL + `/other"`,        // links point back to the live page
+		`target="_blank"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("rendered page is missing %q:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"<script", "steal()", "onload", "http-equiv", server.URL + "/style.css", server.URL + "/old.png"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("rendered page still contains %q:\n%s", unwanted, out)
+		}
+	}
+
+	if _, err := archive.Render(server.URL + "/style.css"); err == nil {
+		t.Error("rendering a stylesheet should fail")
+	}
+}
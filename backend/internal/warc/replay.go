@@ -0,0 +1,310 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ReplayPolicy is the Content-Security-Policy replayed pages are served
+// with: nothing may be loaded from the network and no script may run, so
+// a snapshot cannot phone home or act on the viewer's session.
+const ReplayPolicy = "default-src 'none'; img-src data:; media-src data:; font-src data:; style-src 'unsafe-inline' data:; sandbox allow-popups allow-popups-to-escape-sandbox"
+
+var ErrNotCaptured = errors.New("url was not captured")
+
+// Response is a captured HTTP response.
+type Response struct {
+	URL        string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Archive is a WARC file loaded for replay, its responses indexed by URL.
+type Archive struct {
+	Responses map[string]*Response
+	first     string
+}
+
+// Load reads every response record of a WARC file.
+func Load(r io.Reader) (*Archive, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	archive := &Archive{Responses: map[string]*Response{}}
+	for {
+		rec, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return archive, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if rec.Type() != TypeResponse {
+			continue
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(rec.Block)), nil)
+		if err != nil {
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			continue
+		}
+		target := rec.TargetURI()
+		archive.Responses[target] = &Response{URL: target, StatusCode: resp.StatusCode, Header: resp.Header, Body: body}
+		if archive.first == "" {
+			archive.first = target
+		}
+	}
+}
+
+// Lookup returns the response for a URL, following captured redirects.
+func (a *Archive) Lookup(link string) (*Response, error) {
+	for hops := 0; hops <= 10; hops++ {
+		resp, ok := a.Responses[link]
+		if !ok {
+			return nil, ErrNotCaptured
+		}
+		location := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+			return resp, nil
+		}
+		base, err := url.Parse(link)
+		if err != nil {
+			return nil, err
+		}
+		next, err := base.Parse(location)
+		if err != nil {
+			return nil, err
+		}
+		link = next.String()
+	}
+	return nil, ErrNotCaptured
+}
+
+// resource is Lookup for inlining: error pages do not count.
+func (a *Archive) resource(link string) (*Response, error) {
+	resp, err := a.Lookup(link)
+	if err == nil && resp.StatusCode >= 400 {
+		return nil, ErrNotCaptured
+	}
+	return resp, err
+}
+
+// Page is the first URL captured, the page the snapshot was taken of.
+func (a *Archive) Page() string {
+	return a.first
+}
+
+// Render returns the page as one self-contained HTML document: captured
+// images, stylesheets, fonts and media are inlined as data: URIs, scripts
+// and event handlers are removed, and links point back to the live web.
+func (a *Archive) Render(link string) ([]byte, error) {
+	resp, err := a.Lookup(link)
+	if err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(resp.URL)
+	if err != nil {
+		return nil, err
+	}
+	if mediaType(resp.Header) != "text/html" && mediaType(resp.Header) != "application/xhtml+xml" {
+		return nil, errors.New("snapshot is not an HTML page")
+	}
+
+	var out bytes.Buffer
+	z := html.NewTokenizer(bytes.NewReader(resp.Body))
+	skip := ""
+	inStyle := false
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if errors.Is(z.Err(), io.EOF) {
+				return out.Bytes(), nil
+			}
+			return nil, z.Err()
+		}
+		// Raw is only valid until Token is called.
+		raw := bytes.Clone(z.Raw())
+		token := z.Token()
+		if skip != "" {
+			if tt == html.EndTagToken && token.Data == skip {
+				skip = ""
+			}
+			continue
+		}
+
+		switch tt {
+		case html.TextToken:
+			if inStyle {
+				raw = a.rewriteCSS(base, raw, 0)
+			}
+			out.Write(raw)
+			continue
+		case html.EndTagToken:
+			if token.Data == "style" {
+				inStyle = false
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			switch token.Data {
+			case "style":
+				inStyle = tt == html.StartTagToken
+			case "script", "noscript", "iframe", "object", "embed":
+				if tt == html.StartTagToken {
+					skip = token.Data
+				}
+				continue
+			case "base":
+				if href := attr(token, "href"); href != "" {
+					if u, err := base.Parse(href); err == nil {
+						base = u
+					}
+				}
+				continue
+			case "meta":
+				// A refresh would navigate the replay away from the snapshot.
+				if strings.EqualFold(attr(token, "http-equiv"), "refresh") {
+					continue
+				}
+			case "link":
+				if inlined := a.inlineStylesheet(base, token); inlined != "" {
+					out.WriteString(inlined)
+					continue
+				}
+			}
+			token.Attr = a.rewriteAttrs(base, token)
+		}
+		out.WriteString(token.String())
+	}
+}
+
+func (a *Archive) rewriteAttrs(base *url.URL, token html.Token) []html.Attribute {
+	attrs := make([]html.Attribute, 0, len(token.Attr))
+	for _, at := range token.Attr {
+		key := strings.ToLower(at.Key)
+		switch {
+		case strings.HasPrefix(key, "on"):
+			continue
+		case key == "style":
+			at.Val = string(a.rewriteCSS(base, []byte(at.Val), 0))
+		case key == "srcset":
+			var candidates []string
+			for _, candidate := range strings.Split(at.Val, ",") {
+				fields := strings.Fields(candidate)
+				if len(fields) == 0 {
+					continue
+				}
+				fields[0] = a.dataURI(base, fields[0])
+				candidates = append(candidates, strings.Join(fields, " "))
+			}
+			at.Val = strings.Join(candidates, ", ")
+		case key == "href" && (token.Data == "a" || token.Data == "area"):
+			if u, err := base.Parse(at.Val); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+				at.Val = u.String()
+			}
+		case isResourceAttr(token, key):
+			at.Val = a.dataURI(base, at.Val)
+		}
+		attrs = append(attrs, at)
+	}
+	if token.Data == "a" || token.Data == "area" {
+		attrs = append(attrs, html.Attribute{Key: "target", Val: "_blank"}, html.Attribute{Key: "rel", Val: "noopener noreferrer"})
+	}
+	return attrs
+}
+
+// inlineStylesheet turns <link rel="stylesheet"> into a <style> element
+// holding the captured sheet.
+func (a *Archive) inlineStylesheet(base *url.URL, token html.Token) string {
+	isSheet := false
+	for _, rel := range strings.Fields(strings.ToLower(attr(token, "rel"))) {
+		isSheet = isSheet || rel == "stylesheet"
+	}
+	if !isSheet {
+		return ""
+	}
+	link := resolve(base, attr(token, "href"))
+	resp, err := a.resource(link)
+	if err != nil {
+		return ""
+	}
+	sheetBase, _ := url.Parse(resp.URL)
+	css := a.rewriteCSS(sheetBase, resp.Body, 0)
+	// A stylesheet cannot end the <style> element early.
+	css = bytes.ReplaceAll(css, []byte("</"), []byte(`<\/`))
+	media := ""
+	if m := attr(token, "media"); m != "" {
+		media = ` media="` + html.EscapeString(m) + `"`
+	}
+	return "<style" + media + ">" + string(css) + "</style>"
+}
+
+// rewriteCSS replaces url()s and @imports with data: URIs. Imported
+// sheets are rewritten too, a few levels deep.
+func (a *Archive) rewriteCSS(base *url.URL, css []byte, depth int) []byte {
+	return cssURL.ReplaceAllFunc(css, func(match []byte) []byte {
+		sub := cssURL.FindSubmatch(match)
+		ref := ""
+		for _, s := range sub[1:] {
+			if len(s) > 0 {
+				ref = string(s)
+				break
+			}
+		}
+		link := resolve(base, ref)
+		if link == "" {
+			return match
+		}
+		resp, err := a.resource(link)
+		if err != nil {
+			return []byte(`url("about:invalid")`)
+		}
+		body := resp.Body
+		if mediaType(resp.Header) == "text/css" && depth < 3 {
+			sheetBase, _ := url.Parse(resp.URL)
+			body = a.rewriteCSS(sheetBase, body, depth+1)
+		}
+		uri := encodeDataURI(resp.Header.Get("Content-Type"), body)
+		if bytes.HasPrefix(bytes.TrimSpace(match), []byte("@import")) {
+			return []byte(`@import url("` + uri + `")`)
+		}
+		return []byte(`url("` + uri + `")`)
+	})
+}
+
+// dataURI inlines a captured resource; references that were not captured
+// are blanked so the browser does not reach for the live web.
+func (a *Archive) dataURI(base *url.URL, ref string) string {
+	if strings.HasPrefix(strings.TrimSpace(ref), "data:") {
+		return ref
+	}
+	resp, err := a.resource(resolve(base, ref))
+	if err != nil {
+		return "about:invalid"
+	}
+	return encodeDataURI(resp.Header.Get("Content-Type"), resp.Body)
+}
+
+func encodeDataURI(contentType string, body []byte) string {
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	return "data:" + strings.ReplaceAll(contentType, " ", "") + ";base64," + base64.StdEncoding.EncodeToString(body)
+}
+
+func mediaType(header http.Header) string {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	return mediaType
+}
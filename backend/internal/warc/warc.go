@@ -0,0 +1,166 @@
+// Package warc writes and reads WARC 1.1 files (ISO 28500), the format web
+// archives use to keep HTTP exchanges exactly as they were captured.
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const version = "WARC/1.1"
+
+// Record types used by the capturer.
+const (
+	TypeWarcinfo = "warcinfo"
+	TypeRequest  = "request"
+	TypeResponse = "response"
+)
+
+// Field is one WARC header line. Headers are kept as a list so records
+// are written with their fields in a stable, conventional order.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Writer appends records to a .warc.gz stream, each record gzipped as its
+// own member so readers can seek to any record. It is safe for concurrent use.
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// WriteRecord writes a record and returns its WARC-Record-ID.
+func (w *Writer) WriteRecord(recordType, targetURI, contentType string, block []byte, extra ...Field) (string, error) {
+	id := "<urn:uuid:" + uuid.NewString() + ">"
+	fields := []Field{
+		{"WARC-Type", recordType},
+		{"WARC-Record-ID", id},
+		{"WARC-Date", time.Now().UTC().Format(time.RFC3339)},
+	}
+	if targetURI != "" {
+		fields = append(fields, Field{"WARC-Target-URI", targetURI})
+	}
+	fields = append(fields, extra...)
+	fields = append(fields,
+		Field{"Content-Type", contentType},
+		Field{"WARC-Block-Digest", Digest(block)},
+		Field{"Content-Length", strconv.Itoa(len(block))},
+	)
+
+	var buf bytes.Buffer
+	buf.WriteString(version + "\r\n")
+	for _, f := range fields {
+		fmt.Fprintf(&buf, "%s: %s\r\n", f.Name, f.Value)
+	}
+	buf.WriteString("\r\n")
+	buf.Write(block)
+	buf.WriteString("\r\n\r\n")
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	gz := gzip.NewWriter(w.w)
+	if _, err := gz.Write(buf.Bytes()); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// WriteInfo writes the warcinfo record that opens a file.
+func (w *Writer) WriteInfo(fields map[string]string, order ...string) error {
+	var block bytes.Buffer
+	for _, name := range order {
+		fmt.Fprintf(&block, "%s: %s\r\n", name, fields[name])
+	}
+	_, err := w.WriteRecord(TypeWarcinfo, "", "application/warc-fields", block.Bytes())
+	return err
+}
+
+// Digest is the WARC form of a SHA-1 digest: "sha1:" plus base32.
+func Digest(b []byte) string {
+	sum := sha1.Sum(b)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// Record is a record read back from a WARC file.
+type Record struct {
+	Header textproto.MIMEHeader
+	Block  []byte
+}
+
+func (r *Record) Type() string      { return r.Header.Get("WARC-Type") }
+func (r *Record) TargetURI() string { return r.Header.Get("WARC-Target-URI") }
+
+// Reader reads records in order from a .warc or .warc.gz stream.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader detects gzip from the magic bytes, so both plain and
+// compressed files can be read.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return &Reader{r: bufio.NewReader(gz)}, nil
+	}
+	return &Reader{r: br}, nil
+}
+
+// Next returns the next record, or io.EOF after the last one.
+func (r *Reader) Next() (*Record, error) {
+	var line string
+	for {
+		l, err := r.r.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) && strings.TrimSpace(l) == "" {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+		if line = strings.TrimRight(l, "\r\n"); line != "" {
+			break
+		}
+	}
+	if !strings.HasPrefix(line, "WARC/") {
+		return nil, fmt.Errorf("not a WARC record: %q", line)
+	}
+
+	header, err := textproto.NewReader(r.r).ReadMIMEHeader()
+	if err != nil {
+		return nil, fmt.Errorf("invalid WARC header: %w", err)
+	}
+	length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid WARC Content-Length %q", header.Get("Content-Length"))
+	}
+	block := make([]byte, length)
+	if _, err := io.ReadFull(r.r, block); err != nil {
+		return nil, fmt.Errorf("truncated WARC record: %w", err)
+	}
+	return &Record{Header: header, Block: block}, nil
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	archiveHandlers "lyked-backend/internal/handlers/archive"
+	"lyked-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitProtectedArchiveRoutes(r *gin.Engine) error {
+	protectedArchiveRoutes := r.Group("/archive")
+	protectedArchiveRoutes.Use(middleware.JWTAuthMiddleware())
+	{
+		protectedArchiveRoutes.GET("/usage", archiveHandlers.ArchiveUsageHandler)
+		protectedArchiveRoutes.POST("/:id", archiveHandlers.StartArchiveHandler)
+		protectedArchiveRoutes.GET("/:id", archiveHandlers.GetArchiveHandler)
+		protectedArchiveRoutes.GET("/:id/content", archiveHandlers.StreamArchiveHandler)
+		protectedArchiveRoutes.DELETE("/:id", archiveHandlers.DeleteArchiveHandler)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package routes
+
+import (
+	folderHandlers "lyked-backend/internal/handlers/folders"
+	"lyked-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitProtectedFolderRoutes(r *gin.Engine) error {
+	protectedFolderRoutes := r.Group("/folders")
+	protectedFolderRoutes.Use(middleware.JWTAuthMiddleware())
+	{
+		protectedFolderRoutes.GET("", folderHandlers.ListFoldersHandler)
+		protectedFolderRoutes.POST("", folderHandlers.CreateFolderHandler)
+		protectedFolderRoutes.GET("/tree", folderHandlers.FolderTreeHandler)
+		protectedFolderRoutes.GET("/shared", folderHandlers.SharedWithMeHandler)
+		protectedFolderRoutes.GET("/invites", folderHandlers.MyInvitesHandler)
+		protectedFolderRoutes.POST("/invites/:invite_id/accept", folderHandlers.AcceptInviteHandler)
+		protectedFolderRoutes.POST("/invites/:invite_id/decline", folderHandlers.DeclineInviteHandler)
+		protectedFolderRoutes.GET("/:id", folderHandlers.GetFolderItemsHandler)
+		protectedFolderRoutes.PATCH("/:id", folderHandlers.RenameFolderHandler)
+		protectedFolderRoutes.DELETE("/:id", folderHandlers.DeleteFolderHandler)
+		protectedFolderRoutes.POST("/:id/move", folderHandlers.MoveFolderHandler)
+		protectedFolderRoutes.GET("/:id/breadcrumbs", folderHandlers.BreadcrumbsHandler)
+		protectedFolderRoutes.GET("/:id/items", folderHandlers.GetFolderItemsHandler)
+		protectedFolderRoutes.POST("/:id/items", folderHandlers.AddFolderItemsHandler)
+		protectedFolderRoutes.DELETE("/:id/items", folderHandlers.RemoveFolderItemsHandler)
+		protectedFolderRoutes.POST("/:id/reorder", folderHandlers.ReorderFolderItemHandler)
+		protectedFolderRoutes.PUT("/:id/pin", folderHandlers.PinFolderHandler)
+		protectedFolderRoutes.GET("/:id/members", folderHandlers.ListMembersHandler)
+		protectedFolderRoutes.PATCH("/:id/members/:user_id", folderHandlers.UpdateMemberHandler)
+		protectedFolderRoutes.DELETE("/:id/members/:user_id", folderHandlers.RemoveMemberHandler)
+		protectedFolderRoutes.POST("/:id/invites", folderHandlers.InviteHandler)
+		protectedFolderRoutes.DELETE("/:id/invites/:invite_id", folderHandlers.RevokeInviteHandler)
+
+		protectedFolderRoutes.POST("/smart", folderHandlers.CreateSmartFolderHandler)
+		protectedFolderRoutes.PUT("/smart/:id", folderHandlers.UpdateSmartFolderHandler)
+		protectedFolderRoutes.DELETE("/smart/:id", folderHandlers.DeleteSmartFolderHandler)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	importHandlers "lyked-backend/internal/handlers/imports"
+	"lyked-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitProtectedImportRoutes(r *gin.Engine) error {
+	protectedImportRoutes := r.Group("/imports")
+	protectedImportRoutes.Use(middleware.JWTAuthMiddleware())
+	{
+		protectedImportRoutes.POST("", importHandlers.StartImportHandler)
+		protectedImportRoutes.GET("", importHandlers.ListImportsHandler)
+		protectedImportRoutes.GET("/:id", importHandlers.GetImportHandler)
+	}
+	return nil
+}
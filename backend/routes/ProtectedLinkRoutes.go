@@ -0,0 +1,18 @@
+package routes
+
+import (
+	linkHandlers "lyked-backend/internal/handlers/links"
+	"lyked-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitProtectedLinkRoutes(r *gin.Engine) error {
+	protectedLinkRoutes := r.Group("/links")
+	protectedLinkRoutes.Use(middleware.JWTAuthMiddleware())
+	{
+		protectedLinkRoutes.GET("/broken", linkHandlers.BrokenLinksHandler)
+		protectedLinkRoutes.POST("/:id/check", linkHandlers.CheckLinkHandler)
+	}
+	return nil
+}
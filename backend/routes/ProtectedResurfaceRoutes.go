@@ -0,0 +1,20 @@
+package routes
+
+import (
+	resurfaceHandlers "lyked-backend/internal/handlers/resurface"
+	"lyked-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitProtectedResurfaceRoutes(r *gin.Engine) error {
+	protectedResurfaceRoutes := r.Group("/resurface")
+	protectedResurfaceRoutes.Use(middleware.JWTAuthMiddleware())
+	{
+		protectedResurfaceRoutes.GET("", resurfaceHandlers.FeedHandler)
+		protectedResurfaceRoutes.POST("/:id/snooze", resurfaceHandlers.SnoozeHandler)
+		protectedResurfaceRoutes.POST("/:id/dismiss", resurfaceHandlers.DismissHandler)
+		protectedResurfaceRoutes.DELETE("/:id", resurfaceHandlers.ClearFeedbackHandler)
+	}
+	return nil
+}
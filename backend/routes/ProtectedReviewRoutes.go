@@ -0,0 +1,21 @@
+package routes
+
+import (
+	reviewHandlers "lyked-backend/internal/handlers/review"
+	"lyked-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitProtectedReviewRoutes(r *gin.Engine) error {
+	protectedReviewRoutes := r.Group("/review")
+	protectedReviewRoutes.Use(middleware.JWTAuthMiddleware())
+	{
+		protectedReviewRoutes.GET("/settings", reviewHandlers.GetSettingsHandler)
+		protectedReviewRoutes.PUT("/settings", reviewHandlers.UpdateSettingsHandler)
+		protectedReviewRoutes.GET("/due", reviewHandlers.DueHandler)
+		protectedReviewRoutes.POST("/:id/grade", reviewHandlers.GradeHandler)
+		protectedReviewRoutes.GET("/stats", reviewHandlers.StatsHandler)
+	}
+	return nil
+}
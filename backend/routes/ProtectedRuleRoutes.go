@@ -0,0 +1,23 @@
+package routes
+
+import (
+	ruleHandlers "lyked-backend/internal/handlers/rules"
+	"lyked-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitProtectedRuleRoutes(r *gin.Engine) error {
+	protectedRuleRoutes := r.Group("/rules")
+	protectedRuleRoutes.Use(middleware.JWTAuthMiddleware())
+	{
+		protectedRuleRoutes.GET("", ruleHandlers.ListRulesHandler)
+		protectedRuleRoutes.POST("", ruleHandlers.CreateRuleHandler)
+		protectedRuleRoutes.POST("/test", ruleHandlers.TestRuleHandler)
+		protectedRuleRoutes.POST("/run", ruleHandlers.RunRulesHandler)
+		protectedRuleRoutes.PUT("/:id", ruleHandlers.UpdateRuleHandler)
+		protectedRuleRoutes.DELETE("/:id", ruleHandlers.DeleteRuleHandler)
+		protectedRuleRoutes.POST("/:id/run", ruleHandlers.RunRuleHandler)
+	}
+	return nil
+}
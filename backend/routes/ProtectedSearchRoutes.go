@@ -0,0 +1,17 @@
+package routes
+
+import (
+	searchHandlers "lyked-backend/internal/handlers/search"
+	"lyked-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitProtectedSearchRoutes(r *gin.Engine) error {
+	protectedSearchRoutes := r.Group("/search")
+	protectedSearchRoutes.Use(middleware.JWTAuthMiddleware())
+	{
+		protectedSearchRoutes.GET("", searchHandlers.SearchHandler)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	shareLinkHandlers "lyked-backend/internal/handlers/sharelinks"
+	"lyked-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitProtectedShareLinkRoutes(r *gin.Engine) error {
+	protectedShareLinkRoutes := r.Group("/share-links")
+	protectedShareLinkRoutes.Use(middleware.JWTAuthMiddleware())
+	{
+		protectedShareLinkRoutes.POST("", shareLinkHandlers.CreateShareLinkHandler)
+		protectedShareLinkRoutes.GET("", shareLinkHandlers.ListShareLinksHandler)
+		protectedShareLinkRoutes.DELETE("/:id", shareLinkHandlers.RevokeShareLinkHandler)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	snapshotHandlers "lyked-backend/internal/handlers/snapshots"
+	"lyked-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitProtectedSnapshotRoutes(r *gin.Engine) error {
+	protectedSnapshotRoutes := r.Group("/snapshots")
+	protectedSnapshotRoutes.Use(middleware.JWTAuthMiddleware())
+	{
+		protectedSnapshotRoutes.POST("", snapshotHandlers.CreateSnapshotHandler)
+		protectedSnapshotRoutes.GET("", snapshotHandlers.ListSnapshotsHandler)
+		protectedSnapshotRoutes.GET("/:id", snapshotHandlers.GetSnapshotHandler)
+		protectedSnapshotRoutes.GET("/:id/replay", snapshotHandlers.ReplaySnapshotHandler)
+		protectedSnapshotRoutes.GET("/:id/warc", snapshotHandlers.DownloadSnapshotHandler)
+		protectedSnapshotRoutes.DELETE("/:id", snapshotHandlers.DeleteSnapshotHandler)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	tagHandlers "lyked-backend/internal/handlers/tags"
+	"lyked-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitProtectedTagRoutes(r *gin.Engine) error {
+	protectedTagRoutes := r.Group("/tags")
+	protectedTagRoutes.Use(middleware.JWTAuthMiddleware())
+	{
+		protectedTagRoutes.GET("", tagHandlers.ListTagsHandler)
+		protectedTagRoutes.GET("/tree", tagHandlers.TagTreeHandler)
+		protectedTagRoutes.POST("/rename", tagHandlers.RenameTagHandler)
+		protectedTagRoutes.POST("/merge", tagHandlers.MergeTagsHandler)
+		protectedTagRoutes.POST("/delete", tagHandlers.DeleteTagsHandler)
+		protectedTagRoutes.PUT("/meta", tagHandlers.SetTagMetaHandler)
+		protectedTagRoutes.GET("/aliases", tagHandlers.ListAliasesHandler)
+		protectedTagRoutes.PUT("/aliases", tagHandlers.SetAliasHandler)
+		protectedTagRoutes.POST("/aliases/delete", tagHandlers.DeleteAliasesHandler)
+		protectedTagRoutes.POST("/suggest", tagHandlers.SuggestTagsHandler)
+		protectedTagRoutes.POST("/suggest/feedback", tagHandlers.TagSuggestionFeedbackHandler)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	trashHandlers "lyked-backend/internal/handlers/trash"
+	"lyked-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitProtectedTrashRoutes(r *gin.Engine) error {
+	protectedTrashRoutes := r.Group("/trash")
+	protectedTrashRoutes.Use(middleware.JWTAuthMiddleware())
+	{
+		protectedTrashRoutes.GET("", trashHandlers.ListTrashHandler)
+		protectedTrashRoutes.POST("/restore", trashHandlers.RestoreTrashHandler)
+		protectedTrashRoutes.DELETE("", trashHandlers.EmptyTrashHandler)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	noteHandlers "lyked-backend/internal/handlers/notes"
 	uploadHandlers "lyked-backend/internal/handlers/upload"
 	"lyked-backend/middleware"
 
@@ -14,6 +15,17 @@ func InitProtectedUploadRoutes(r *gin.Engine) error {
 		protectedUploadRoutes.POST("/upload", uploadHandlers.UploadHandler)
 		protectedUploadRoutes.DELETE("/delete", uploadHandlers.DeleteUploadHandler)
 		protectedUploadRoutes.GET("/all", uploadHandlers.GetAllUploadsHandler)
+		protectedUploadRoutes.POST("/batch", uploadHandlers.BatchUploadsHandler)
+		protectedUploadRoutes.GET("/export", uploadHandlers.ExportUploadsHandler)
+		protectedUploadRoutes.PATCH("/:id", uploadHandlers.UpdateUploadHandler)
+
+		protectedUploadRoutes.GET("/:id/notes", noteHandlers.ListNotesHandler)
+		protectedUploadRoutes.POST("/:id/notes", noteHandlers.CreateNoteHandler)
+		protectedUploadRoutes.PATCH("/:id/notes/:noteId", noteHandlers.UpdateNoteHandler)
+		protectedUploadRoutes.DELETE("/:id/notes/:noteId", noteHandlers.DeleteNoteHandler)
+		protectedUploadRoutes.POST("/:id/annotations", noteHandlers.CreateAnnotationHandler)
+		protectedUploadRoutes.PATCH("/:id/annotations/:annotationId", noteHandlers.UpdateAnnotationHandler)
+		protectedUploadRoutes.DELETE("/:id/annotations/:annotationId", noteHandlers.DeleteAnnotationHandler)
 	}
 	return nil
 }
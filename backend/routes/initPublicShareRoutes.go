@@ -0,0 +1,16 @@
+package routes
+
+import (
+	shareLinkHandlers "lyked-backend/internal/handlers/sharelinks"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InitPublicShareRoutes serves share links without authentication.
+func InitPublicShareRoutes(r *gin.Engine) error {
+	publicShareRoutes := r.Group("/public/share")
+	{
+		publicShareRoutes.GET("/:token", shareLinkHandlers.PublicShareHandler)
+	}
+	return nil
+}